@@ -0,0 +1,113 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package aringo
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+var ErrMissingApplication = errors.New("MISSING_APPLICATION")
+
+// WithApplications sets the Stasis applications served over the websocket, overwriting the app parameter of its URL
+// Each event carries the name of its application inside Event.Application
+func WithApplications(apps ...string) Option {
+	return func(ari *ARInGO) {
+		ari.wsURL = setQueryParam(ari.wsURL, "app", strings.Join(apps, ","))
+	}
+}
+
+// WithSubscribeAll subscribes the applications to all the events of Asterisk, not only the ones of their resources
+func WithSubscribeAll() Option {
+	return func(ari *ARInGO) {
+		ari.wsURL = setQueryParam(ari.wsURL, "subscribeAll", "true")
+	}
+}
+
+// setQueryParam overwrites one parameter inside the query of rawURL
+func setQueryParam(rawURL, key, value string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	query := u.Query()
+	query.Set(key, value)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// Applications returns the Stasis applications served over the websocket
+func (ari *ARInGO) Applications() (apps []string) {
+	u, err := url.Parse(ari.wsURL)
+	if err != nil {
+		return
+	}
+	for _, app := range strings.Split(u.Query().Get("app"), ",") {
+		if app != "" {
+			apps = append(apps, app)
+		}
+	}
+	return
+}
+
+// ApplicationFilter matches the events of the given applications
+// Events not tied to an application (ie: Reconnected) are matched as well
+func ApplicationFilter(apps ...string) EventFilter {
+	return func(ev *Event) bool {
+		if ev.Application == "" {
+			return true
+		}
+		for _, app := range apps {
+			if ev.Application == app {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// WithApplicationErrorHandler registers a function receiving the applications whose HandleApplication handler
+// stopped since events were lost (ErrSubscriptionOverflow), the application has to resync and handle them again
+func WithApplicationErrorHandler(f func(app string, err error)) Option {
+	return func(ari *ARInGO) {
+		ari.appErrHandler = f
+	}
+}
+
+// HandleApplication calls handler for each event of the application, in order, out of a dedicated goroutine
+// so handlers of different applications do not block each other
+// The handler is not called anymore once the subscription overflows, reported via WithApplicationErrorHandler
+// Close the returned subscription to stop
+func (ari *ARInGO) HandleApplication(app string, handler func(*Event)) (sub *Subscription) {
+	sub = ari.Subscribe(ApplicationFilter(app))
+	go func() {
+		for ev := range sub.events {
+			handler(ev)
+		}
+		if err := sub.Err(); err != ErrSubscriptionClosed {
+			ari.log().Error("ARI application handler stopped", "application", app, "error", err)
+			if ari.appErrHandler != nil {
+				ari.appErrHandler(app, err)
+			}
+		}
+	}()
+	return
+}
+
+// defaultApp returns app, or the first application of the connection if empty
+func (ari *ARInGO) defaultApp(app string) (string, error) {
+	if app != "" {
+		return app, nil
+	}
+	apps := ari.Applications()
+	if len(apps) == 0 {
+		return "", ErrMissingApplication
+	}
+	return apps[0], nil
+}
@@ -0,0 +1,119 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package aringo
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+func TestApplicationsURL(t *testing.T) {
+	queries := make(chan string, 1)
+	srv := httptest.NewServer(websocket.Handler(func(c *websocket.Conn) {
+		queries <- c.Request().URL.RawQuery
+		c.Read(make([]byte, 1))
+	}))
+	defer srv.Close()
+	stopChan := make(chan struct{})
+	defer close(stopChan)
+	ari, err := NewARInGO("ws"+strings.TrimPrefix(srv.URL, "http")+"/ari/events?api_key=cgrates:CGRateS.org&app=old",
+		srv.URL, "", "", "", nil, make(chan error, 1), stopChan, 1, 0, 0, fibDuration,
+		WithApplications("ivr", "queue"), WithSubscribeAll())
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := "api_key=cgrates%3ACGRateS.org&app=ivr%2Cqueue&subscribeAll=true"
+	if rcv := <-queries; rcv != exp {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp, rcv)
+	}
+	if apps := ari.Applications(); !reflect.DeepEqual(apps, []string{"ivr", "queue"}) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", []string{"ivr", "queue"}, apps)
+	}
+	if ari.restURL != srv.URL+"/ari" {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", srv.URL+"/ari", ari.restURL)
+	}
+}
+
+func TestHandleApplication(t *testing.T) {
+	ari := new(ARInGO)
+	var mux sync.Mutex
+	received := make(map[string][]string)
+	var wg sync.WaitGroup
+	wg.Add(5)
+	handler := func(ev *Event) {
+		mux.Lock()
+		received[ev.Application] = append(received[ev.Application], ev.Type)
+		mux.Unlock()
+		wg.Done()
+	}
+	ivrSub := ari.HandleApplication("ivr", handler)
+	queueSub := ari.HandleApplication("queue", handler)
+	for _, frame := range []string{
+		`{"type":"StasisStart","application":"ivr"}`,
+		`{"type":"StasisStart","application":"queue"}`,
+		`{"type":"StasisStart","application":"other"}`,
+		`{"type":"StasisEnd","application":"ivr"}`,
+		`{"type":"Reconnected"}`, // connection events go to all the applications
+	} {
+		ari.handleFrame([]byte(frame))
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Handlers not called")
+	}
+	exp := map[string][]string{
+		"ivr":   {"StasisStart", "StasisEnd"},
+		"queue": {"StasisStart"},
+		"":      {"Reconnected", "Reconnected"},
+	}
+	mux.Lock()
+	defer mux.Unlock()
+	if !reflect.DeepEqual(exp, received) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp, received)
+	}
+	ivrSub.Close()
+	queueSub.Close()
+	if len(ari.subs) != 0 {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", 0, len(ari.subs))
+	}
+}
+
+func TestHandleApplicationOverflow(t *testing.T) {
+	appErrs := make(chan string, 1)
+	ari := NewReplayARInGO(nil, WithApplicationErrorHandler(func(app string, err error) {
+		if err == ErrSubscriptionOverflow {
+			appErrs <- app
+		}
+	}))
+	release := make(chan struct{})
+	ari.HandleApplication("ivr", func(*Event) { <-release }) // stuck handler
+	for i := 0; i < subscriptionBuffer+2; i++ {
+		ari.handleFrame([]byte(`{"type":"ChannelDtmfReceived","application":"ivr"}`))
+	}
+	close(release)
+	select {
+	case app := <-appErrs:
+		if app != "ivr" {
+			t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", "ivr", app)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Overflow not reported")
+	}
+}
@@ -9,39 +9,73 @@ package aringo
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
+	"strings"
+	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/net/websocket"
 )
 
+const (
+	contentTypeJSON = "application/json"
+	contentTypeForm = "application/x-www-form-urlencoded"
+)
+
 const (
 	HTTP_POST   = "POST"
 	HTTP_GET    = "GET"
 	HTTP_DELETE = "DELETE"
+	HTTP_PUT    = "PUT"
 )
 
 var (
 	ErrZeroConnectAttempts = errors.New("ZERO_CONNECT_ATTEMPTS")
+	ErrNotAnObject         = errors.New("NOT_AN_OBJECT")
+
+	errNotConnected = errors.New("NOT_CONNECTED")
 )
 
 func NewErrUnexpectedReplyCode(statusCode int) error {
-	return fmt.Errorf("UNEXPECTED_REPLY_CODE: %d", statusCode)
+	return &UnexpectedReplyCodeError{StatusCode: statusCode}
+}
+
+// UnexpectedReplyCodeError is returned by Call when Asterisk replies with an error status code
+type UnexpectedReplyCodeError struct {
+	StatusCode int
+	Message    string // explanation sent by Asterisk in the reply body, if any
+}
+
+func (err *UnexpectedReplyCodeError) Error() string {
+	return fmt.Sprintf("UNEXPECTED_REPLY_CODE: %d", err.StatusCode)
+}
+
+// IsReplyCode checks if err was caused by Asterisk replying with statusCode
+func IsReplyCode(err error, statusCode int) bool {
+	var rcErr *UnexpectedReplyCodeError
+	return errors.As(err, &rcErr) && rcErr.StatusCode == statusCode
 }
 
 func NewARInGO(wsUrl, wsOrigin, username, password, userAgent string, evChannel chan map[string]interface{},
 	errChannel chan error, stopChan <-chan struct{}, connectAttempts, reconnects int,
-	maxReconnectInterval time.Duration, delayFunc func(time.Duration, time.Duration) func() time.Duration) (ari *ARInGO, err error) {
+	maxReconnectInterval time.Duration, delayFunc func(time.Duration, time.Duration) func() time.Duration,
+	opts ...Option) (ari *ARInGO, err error) {
 	if connectAttempts == 0 {
 		return nil, ErrZeroConnectAttempts
 	}
 	ari = &ARInGO{
 		httpClient:           new(http.Client),
 		wsURL:                wsUrl,
+		restURL:              restURLFromWS(wsUrl),
 		wsOrigin:             wsOrigin,
 		username:             username,
 		password:             password,
@@ -53,22 +87,56 @@ func NewARInGO(wsUrl, wsOrigin, username, password, userAgent string, evChannel
 		errChannel:           errChannel,
 		wsListenerExit:       stopChan,
 	}
+	for _, opt := range opts {
+		opt(ari)
+	}
 	if err = ari.connect(); err != nil {
+		ari.log().Warn("ARI connect failed", "url", redactURL(ari.wsURL), "attempt", 1, "error", err)
 		delay := ari.delayFunc(time.Second, 0)
 		for i := 0; connectAttempts == -1 || i < connectAttempts-1; i++ { // -1 for infinite attempts
 			time.Sleep(delay()) // Increased delay to randomize network load
 			if err = ari.connect(); err == nil {
 				return
 			}
+			ari.log().Warn("ARI connect failed", "url", redactURL(ari.wsURL), "attempt", i+2, "error", err)
 		}
+		ari.log().Error("ARI connect giving up", "url", redactURL(ari.wsURL), "error", err)
 	}
 	return
 }
 
+// Option customizes the optional behaviour of an ARInGO connection
+type Option func(*ARInGO)
+
+// WithDecodeErrorHandler registers a function receiving the frames which could not be decoded as events
+// Such frames are skipped without affecting the connection
+func WithDecodeErrorHandler(f func(*DecodeError)) Option {
+	return func(ari *ARInGO) {
+		ari.decodeErrHandler = f
+	}
+}
+
+// WithRESTURL overwrites the base URL of the REST resources (ie: http://127.0.0.1:8088/ari)
+// By default it is derived out of the websocket URL
+func WithRESTURL(restURL string) Option {
+	return func(ari *ARInGO) {
+		ari.restURL = strings.TrimSuffix(restURL, "/")
+	}
+}
+
+// WithResync enables resynchronization of channels, bridges and playbacks after a reconnect
+// The resources which disappeared while disconnected are announced via synthetic end events
+func WithResync() Option {
+	return func(ari *ARInGO) {
+		ari.tracker = newResourceTracker()
+	}
+}
+
 // ARInGO represents one ARI connection/application
 type ARInGO struct {
 	httpClient           *http.Client
 	wsURL                string
+	restURL              string // base URL for the REST resources, without trailing slash
 	wsOrigin             string
 	username             string
 	password             string
@@ -80,96 +148,377 @@ type ARInGO struct {
 	evChannel            chan map[string]interface{}                             // Events coming from Asterisk are posted here
 	errChannel           chan error                                              // Errors are posted here
 	wsListenerExit       <-chan struct{}                                         // Signal dispatcher to stop listening
+	decodeErrHandler     func(*DecodeError)                                      // Receives the frames which could not be decoded
+	appErrHandler        func(string, error)                                     // Receives the applications whose handler stopped
+	tracker              *resourceTracker                                        // Resources known to the application, used on resync
+	subsMux              sync.RWMutex                                            // protects subs and lastSubID
+	subs                 map[uint64]*Subscription                                // Subscriptions receiving the matching events
+	lastSubID            uint64                                                  // last id assigned to a subscription
+	idGenerator          IDGenerator                                             // ids for the resources created by the helpers, UUIDs if nil
+	retryPolicy          *RetryPolicy                                            // default retry policy of the REST calls
+	metrics              Metrics                                                 // collects the runtime measurements if not nil
+	tracer               trace.Tracer                                            // traces the REST calls and channel events if not nil
+	chanSpansMux         sync.RWMutex                                            // protects chanSpans
+	chanSpans            map[string]*channelSpan                                 // span context of the traced channels
+	chanSpansPruned      time.Time                                               // last removal of the stale chanSpans
+	logger               *slog.Logger                                            // logs nothing if nil
+	wireLogging          bool                                                    // log REST exchanges and websocket frames at debug level
+	recorder             *Recorder                                               // records the event stream if not nil
+	wsMux                sync.Mutex                                              // protects ws against concurrent writes and reconnects
+	wsREST               *wsRESTTransport                                        // sends the REST requests over the websocket if not nil
+	inbound              bool                                                    // websocket opened by Asterisk towards a Server
+	hold                 *eventHold                                              // queues the events while the connection is set up, if not nil
 }
 
-// wsDispatcher listens for JSON rawMessages and stores them into the evChannel
+// wsEventListener listens for raw frames and dispatches them as events
+// Only transport errors are considered connection failures, frames which cannot be decoded are skipped
 func (ari *ARInGO) wsEventListener() {
 	for {
 		select {
 		case <-ari.wsListenerExit:
 			ari.disconnect()
+			ari.log().Info("ARI websocket closed", "url", redactURL(ari.wsURL))
 			return
 		default:
 		}
-		var ev map[string]interface{}
-		if err := websocket.JSON.Receive(ari.ws, &ev); err != nil {
+		var raw []byte
+		if err := websocket.Message.Receive(ari.ws, &raw); err != nil {
 			ari.disconnect()
 			select {
 			case <-ari.wsListenerExit:
+				ari.log().Info("ARI websocket closed", "url", redactURL(ari.wsURL))
 				return // if the chanel was closed already do not try to reconnect
 			default:
 			}
-			if errConn := ari.connect(); errConn != nil { // give up on success since another goroutine will pick up events
+			ari.log().Warn("ARI websocket connection lost", "url", redactURL(ari.wsURL), "error", err)
+			if ari.inbound { // reconnecting is up to Asterisk
+				return
+			}
+			if errConn := ari.reconnect(); errConn != nil { // give up on success since another goroutine will pick up events
+				ari.log().Warn("ARI reconnect failed", "attempt", 1, "error", errConn)
 				delay := ari.delayFunc(time.Second, ari.maxReconnectInterval)
 				for i := 0; i < ari.reconnects-1; i++ { // attempt reconnect
 					time.Sleep(delay())
-					if errConn := ari.connect(); errConn == nil { // give up on success since another goroutine will pick up events
+					if errConn = ari.reconnect(); errConn == nil { // give up on success since another goroutine will pick up events
 						return
 					}
+					ari.log().Warn("ARI reconnect failed", "attempt", i+2, "error", errConn)
 				}
 				// reconnect did not succeed, pass the original error and give up
+				ari.log().Error("ARI reconnect giving up", "url", redactURL(ari.wsURL), "error", err)
 				ari.errChannel <- err
 			}
 			return
 		}
-		ari.evChannel <- ev
+		ari.handleFrame(raw)
+	}
+}
+
+// handleFrame decodes one raw frame and posts it into the evChannel
+// The replies to the REST requests sent over the websocket are neither logged nor recorded as frames,
+// the request does it with the credentials redacted
+func (ari *ARInGO) handleFrame(raw []byte) {
+	ev, err := decodeEvent(raw)
+	if err == nil && ev.Type == EventTypeRESTResponse { // reply to a request, not an event
+		if ari.wsREST != nil {
+			ari.wsREST.deliver(ev)
+		}
+		return
+	}
+	if ari.wireLogging {
+		ari.logWire("ARI frame received", "frame", redactJSON(raw))
+	}
+	if ari.recorder != nil {
+		ari.recorder.recordFrame(raw)
+	}
+	if err != nil {
+		ari.reportDecodeError(err.(*DecodeError))
+		return
+	}
+	if ari.hold != nil && ari.hold.add(ev, raw) {
+		return
+	}
+	ari.handleEvent(ev, raw)
+}
+
+// handleEvent posts one decoded event into the subscriptions and the evChannel
+func (ari *ARInGO) handleEvent(ev *Event, raw []byte) {
+	var fields map[string]interface{}
+	if ari.evChannel != nil { // the map is only built for the legacy event channel
+		var err error
+		if fields, err = ev.Fields(); err != nil {
+			ari.reportDecodeError(&DecodeError{Raw: raw, Err: err})
+			return
+		}
+	}
+	if ari.metrics != nil {
+		ari.metrics.EventReceived(ev.Type)
+		ari.metrics.EventQueueDepth(len(ari.evChannel))
+	}
+	if ari.tracker != nil {
+		ari.tracker.track(ev)
+	}
+	if ari.tracer != nil {
+		ari.traceEvent(ev)
+	}
+	ari.dispatch(ev)
+	if ari.evChannel != nil {
+		ari.evChannel <- fields
+	}
+}
+
+// eventHold queues the events received while the connection is set up (ie: subscribing out of Server onConnect)
+// The replies of the REST requests sent over the websocket are not held so the setup can use them
+type eventHold struct {
+	mux      sync.Mutex
+	events   []*Event
+	raws     [][]byte
+	released bool
+}
+
+// add queues the event, returns false once the hold was released
+func (h *eventHold) add(ev *Event, raw []byte) bool {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	if h.released {
+		return false
+	}
+	h.events = append(h.events, ev)
+	h.raws = append(h.raws, raw)
+	return true
+}
+
+// release handles the queued events, in order, and lets the next ones through
+func (h *eventHold) release(ari *ARInGO) {
+	for {
+		h.mux.Lock()
+		events, raws := h.events, h.raws
+		h.events, h.raws = nil, nil
+		if len(events) == 0 { // nothing queued meanwhile
+			h.released = true
+			h.mux.Unlock()
+			return
+		}
+		h.mux.Unlock()
+		for i, ev := range events {
+			ari.handleEvent(ev, raws[i])
+		}
+	}
+}
+
+func (ari *ARInGO) reportDecodeError(err *DecodeError) {
+	ari.log().Warn("ARI frame dropped", "error", err.Err, "frame", redactJSON(err.Raw))
+	if ari.metrics != nil {
+		ari.metrics.DecodeFailed()
+	}
+	if ari.decodeErrHandler != nil {
+		ari.decodeErrHandler(err)
 	}
 }
 
 // connect connects to Asterisk Websocket and starts listener
 func (ari *ARInGO) connect() (err error) {
-	if ari.ws, err = websocket.Dial(ari.wsURL, "", ari.wsOrigin); err != nil {
+	var ws *websocket.Conn
+	if ws, err = websocket.Dial(ari.wsURL, "", ari.wsOrigin); err != nil {
 		return
 	}
+	ari.setWS(ws)
+	if ari.metrics != nil {
+		ari.metrics.ConnectionUp()
+	}
+	ari.log().Info("ARI connected", "url", redactURL(ari.wsURL))
 	// Connected, start listener
 	go ari.wsEventListener()
 	return
 }
 
+// reconnect connects back to Asterisk Websocket and starts the listener, the events are held until the reconnect is announced
+func (ari *ARInGO) reconnect() (err error) {
+	if ari.metrics != nil {
+		ari.metrics.ReconnectAttempted()
+	}
+	var ws *websocket.Conn
+	if ws, err = websocket.Dial(ari.wsURL, "", ari.wsOrigin); err != nil {
+		return
+	}
+	ari.setWS(ws)
+	if ari.metrics != nil {
+		ari.metrics.Reconnected()
+		ari.metrics.ConnectionUp()
+	}
+	ari.log().Info("ARI reconnected", "url", redactURL(ari.wsURL))
+	ari.hold = new(eventHold) // the resync reflects the gap only, its REST requests may need the listener for their replies
+	go ari.wsEventListener()
+	go func() {
+		ari.onReconnect()
+		ari.hold.release(ari)
+	}()
+	return
+}
+
 func (ari *ARInGO) disconnect() error {
+	if ari.metrics != nil {
+		ari.metrics.ConnectionDown()
+	}
+	if ari.wsREST != nil {
+		ari.wsREST.reset()
+	}
 	return ari.ws.Close()
 }
 
+// setWS replaces the websocket used by the listener and the writers
+func (ari *ARInGO) setWS(ws *websocket.Conn) {
+	ari.wsMux.Lock()
+	ari.ws = ws
+	ari.wsMux.Unlock()
+}
+
+// sendFrame writes one JSON frame towards Asterisk, safe for concurrent use
+func (ari *ARInGO) sendFrame(v interface{}) (err error) {
+	ari.wsMux.Lock()
+	defer ari.wsMux.Unlock()
+	if ari.ws == nil {
+		return errNotConnected
+	}
+	return websocket.JSON.Send(ari.ws, v)
+}
+
 // Call represents one REST call to Asterisk using httpClient call
 // If there is a reply from Asterisk it should be in form map[string]interface{}
+// The reply is only returned for GET requests
 func (ari *ARInGO) Call(method, reqURL string, data url.Values) (reply []byte, err error) {
-	var reqBody io.Reader
+	if reply, err = ari.CallContext(context.Background(), method, reqURL, data); method != HTTP_GET {
+		reply = nil
+	}
+	return
+}
+
+// CallJSON executes one REST call sending body marshaled as JSON, the params go in the query string
+// Like CallContext, the reply is returned independent of method
+func (ari *ARInGO) CallJSON(ctx context.Context, method, reqURL string, params url.Values, body interface{},
+	opts ...CallOption) (reply []byte, err error) {
+	return ari.CallContext(ctx, method, reqURL, params, append(opts, WithJSONBody(body))...)
+}
+
+// request executes one REST request returning the reply body independent of method
+// With jsonBody the data is sent in the query string, idempotent marks the requests safe to be sent twice
+func (ari *ARInGO) request(ctx context.Context, method, reqURL string, data url.Values, jsonBody []byte,
+	idempotent bool) (reply []byte, err error) {
 	switch method {
-	case HTTP_GET: // Add data inside url
-		u, _ := url.ParseRequestURI(reqURL)
-		u.RawQuery = data.Encode()
-		reqURL = u.String()
-	case HTTP_POST, HTTP_DELETE:
-		reqBody = bytes.NewBufferString(data.Encode())
+	case HTTP_GET, HTTP_POST, HTTP_DELETE, HTTP_PUT:
 	default:
 		err = fmt.Errorf("Unrecognized method: %s", method)
 		return
 	}
+	var u *url.URL
+	if u, err = url.Parse(reqURL); err != nil {
+		return
+	}
+	if (method == HTTP_GET || jsonBody != nil) && len(data) != 0 { // Add data inside url
+		query := u.Query()
+		for key, vals := range data {
+			query[key] = append(query[key], vals...)
+		}
+		u.RawQuery = query.Encode()
+		data = nil
+	}
+	traced := ari.wireLogging || ari.recorder != nil // redacting is only paid when the exchange is logged or recorded
+	var redactedURL, reqBody string
+	if traced {
+		redactedURL = redactURL(u.String())
+		if reqBody = redactJSON(jsonBody); jsonBody == nil {
+			reqBody = redactValues(data).Encode()
+		}
+		ari.logWire("ARI request", "method", method, "url", redactedURL, "body", reqBody)
+	}
+	var statusCode int
+	start := time.Now()
+	statusCode, reply, err = ari.roundTrip(ctx, method, u, data, jsonBody, idempotent)
+	if ari.metrics != nil {
+		ari.metrics.RESTCall(method, resourceTemplate(ari.restURL, u), statusCode, time.Since(start))
+	}
+	if statusCode == 0 { // no reply
+		return
+	}
+	if ari.tracer != nil {
+		trace.SpanFromContext(ctx).SetAttributes(attribute.Int("http.response.status_code", statusCode))
+	}
+	var replyBody string
+	if traced && statusCode != 204 { // No content status code
+		replyBody = redactJSON(reply)
+	}
+	if statusCode == 204 {
+		ari.logWire("ARI reply", "method", method, "url", redactedURL, "status", statusCode)
+	} else {
+		ari.logWire("ARI reply", "method", method, "url", redactedURL, "status", statusCode, "body", replyBody)
+	}
+	if ari.recorder != nil {
+		ari.recorder.recordREST(method, redactedURL, reqBody, statusCode, replyBody)
+	}
+	if statusCode != 200 && statusCode != 204 {
+		if err != nil {
+			reply = nil
+		}
+		return nil, replyCodeError(statusCode, reply)
+	}
+	return
+}
+
+// replyCodeError builds the error of an unexpected status code, with the message found in the reply
+func replyCodeError(statusCode int, reply []byte) error {
+	rcErr := &UnexpectedReplyCodeError{StatusCode: statusCode}
+	var errReply struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(reply, &errReply) == nil {
+		rcErr.Message = errReply.Message
+	}
+	return rcErr
+}
+
+// roundTrip sends the request over the websocket if enabled and supported by Asterisk, over HTTP otherwise
+// statusCode is 0 if no reply was received
+func (ari *ARInGO) roundTrip(ctx context.Context, method string, u *url.URL, data url.Values,
+	jsonBody []byte, idempotent bool) (statusCode int, reply []byte, err error) {
+	if ari.wsREST != nil {
+		if statusCode, reply, err = ari.wsRoundTrip(ctx, method, u, data, jsonBody, idempotent); err != errRESTOverWSUnavailable {
+			return
+		}
+	}
+	return ari.httpRoundTrip(ctx, method, u, data, jsonBody)
+}
+
+// httpRoundTrip sends the request using the httpClient
+func (ari *ARInGO) httpRoundTrip(ctx context.Context, method string, u *url.URL, data url.Values,
+	jsonBody []byte) (statusCode int, reply []byte, err error) {
+	var reqBody io.Reader
+	switch {
+	case jsonBody != nil:
+		reqBody = bytes.NewReader(jsonBody)
+	case method != HTTP_GET:
+		reqBody = bytes.NewBufferString(data.Encode())
+	}
 	var req *http.Request
-	if req, err = http.NewRequest(method, reqURL, reqBody); err != nil {
+	if req, err = http.NewRequestWithContext(ctx, method, u.String(), reqBody); err != nil {
 		return
 	}
+	switch {
+	case jsonBody != nil:
+		req.Header.Set("Content-Type", contentTypeJSON)
+	case len(data) != 0 && method != HTTP_GET:
+		req.Header.Set("Content-Type", contentTypeForm)
+	}
 	req.Header.Set("User-Agent", ari.userAgent)
 	req.SetBasicAuth(ari.username, ari.password)
 	var resp *http.Response
 	if resp, err = ari.httpClient.Do(req); err != nil {
 		return
 	}
-	if resp.StatusCode == 204 { // No content status code
-		return
-	}
-	if resp.StatusCode != 200 {
-		err = NewErrUnexpectedReplyCode(resp.StatusCode)
+	defer resp.Body.Close()
+	statusCode = resp.StatusCode
+	if statusCode == 204 {
 		return
 	}
-	var respBody []byte
-	respBody, err = io.ReadAll(resp.Body)
-	resp.Body.Close()
-	if err != nil ||
-		method != HTTP_GET {
-		return
-	}
-	reply = respBody
+	reply, err = io.ReadAll(resp.Body)
 	return
-
 }
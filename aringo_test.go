@@ -8,6 +8,7 @@ Provides Asterisk ARI connector from Go programming language.
 package aringo
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -104,6 +105,7 @@ func TestAringoNewARInGO(t *testing.T) {
 	expected := &ARInGO{
 		httpClient:     http.DefaultClient,
 		wsURL:          wsUrl,
+		restURL:        srv.URL,
 		wsOrigin:       wsOrigin,
 		username:       "",
 		password:       "",
@@ -301,34 +303,32 @@ func TestAringowsEventListenerReconnect(t *testing.T) {
 		t.Fatalf("\nExpected: <%+v>, \nReceived: <%+v>", 0, len(ari.errChannel))
 	}
 
-	if len(ari.evChannel) != 0 {
-		t.Fatalf("\nExpected: <%+v>, \nReceived: <%+v>", 0, len(ari.evChannel))
+	select {
+	case ev := <-ari.evChannel:
+		if ev["type"] != EventTypeReconnected {
+			t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", EventTypeReconnected, ev["type"])
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Reconnected event not received")
 	}
+	close(stopChan)
 }
 
 func TestAringowsEventListenerInvalidJSONReturn(t *testing.T) {
 	stopChan := make(chan struct{})
-	var srv *httptest.Server
-
+	decodeErrs := make(chan *DecodeError, 1)
 	ari := &ARInGO{
-		httpClient:     new(http.Client),
-		username:       "",
-		password:       "",
-		userAgent:      "",
-		reconnects:     100,
-		delayFunc:      fibDuration,
-		evChannel:      make(chan map[string]interface{}),
-		errChannel:     make(chan error, 1),
-		wsListenerExit: stopChan,
-	}
-	srv = httptest.NewServer(websocket.Handler(func(c *websocket.Conn) {
-		urll := ari.wsURL
-		ari.wsURL = "invalidURL"
+		httpClient:       new(http.Client),
+		reconnects:       100,
+		delayFunc:        fibDuration,
+		evChannel:        make(chan map[string]interface{}, 1),
+		errChannel:       make(chan error, 1),
+		wsListenerExit:   stopChan,
+		decodeErrHandler: func(err *DecodeError) { decodeErrs <- err },
+	}
+	srv := httptest.NewServer(websocket.Handler(func(c *websocket.Conn) {
 		c.Write([]byte("invalid"))
-		time.Sleep(20 * time.Millisecond)
-		ari.wsURL = urll
-		c.Close()
-
+		io.Copy(io.Discard, c) // keep the connection until the client closes it
 	}))
 	defer srv.Close()
 
@@ -341,17 +341,33 @@ func TestAringowsEventListenerInvalidJSONReturn(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	listenerDone := make(chan struct{})
+	go func() {
+		ari.wsEventListener()
+		close(listenerDone)
+	}()
 
-	ari.wsEventListener()
+	select {
+	case decErr := <-decodeErrs:
+		if string(decErr.Raw) != "invalid" {
+			t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", "invalid", string(decErr.Raw))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("decode error not reported")
+	}
+	close(stopChan)
+	ari.ws.Close()
+	select {
+	case <-listenerDone:
+	case <-time.After(time.Second):
+		t.Fatal("listener not stopped")
+	}
 	if len(ari.errChannel) != 0 {
-		t.Fatalf("\nExpected: <%+v>, \nReceived: <%+v>", 0, len(ari.errChannel))
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", 0, len(ari.errChannel))
 	}
-
 	if len(ari.evChannel) != 0 {
-		t.Fatalf("\nExpected: <%+v>, \nReceived: <%+v>", 0, len(ari.evChannel))
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", 0, len(ari.evChannel))
 	}
-
-	close(stopChan)
 }
 
 func TestAringowsEventListenerFailReconnect(t *testing.T) {
@@ -369,12 +385,14 @@ func TestAringowsEventListenerFailReconnect(t *testing.T) {
 		errChannel:     make(chan error, 1),
 		wsListenerExit: stopChan,
 	}
+	var decodeErrs []*DecodeError
+	ari.decodeErrHandler = func(err *DecodeError) {
+		decodeErrs = append(decodeErrs, err)
+	}
 	srv = httptest.NewServer(websocket.Handler(func(c *websocket.Conn) {
-		urll := ari.wsURL
 		ari.wsURL = "invalidURL"
 		c.Write([]byte("invalid"))
 		time.Sleep(20 * time.Millisecond)
-		ari.wsURL = urll
 		c.Close()
 
 	}))
@@ -400,16 +418,81 @@ func TestAringowsEventListenerFailReconnect(t *testing.T) {
 		t.Fatalf("\nExpected: <%+v>, \nReceived: <%+v>", 0, len(ari.evChannel))
 	}
 
-	exp := "invalid character 'i' looking for beginning of value"
+	// the malformed frame is reported separately, the connection only fails once closed by the server
 	rcv := <-ari.errChannel
+	if rcv != io.EOF {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", io.EOF, rcv)
+	}
 
-	if exp != rcv.Error() {
-		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp, rcv)
+	if len(decodeErrs) != 1 {
+		t.Fatalf("\nExpected: <%+v>, \nReceived: <%+v>", 1, len(decodeErrs))
+	}
+	exp := "invalid character 'i' looking for beginning of value"
+	if decodeErrs[0].Err.Error() != exp {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp, decodeErrs[0].Err)
+	}
+	if string(decodeErrs[0].Raw) != "invalid" {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", "invalid", string(decodeErrs[0].Raw))
 	}
 
 	close(stopChan)
 }
 
+func TestAringowsEventListenerSkipMalformed(t *testing.T) {
+	stopChan := make(chan struct{})
+	srv := httptest.NewServer(websocket.Handler(func(c *websocket.Conn) {
+		c.Write([]byte("{\"type\":"))
+		c.Write([]byte("[\"not\",\"an\",\"event\"]"))
+		c.Write([]byte("{\"type\":\"NewerAsteriskEvent\",\"unknown\":{\"key\":1}}"))
+		time.Sleep(10 * time.Millisecond)
+		close(stopChan)
+		c.Close()
+	}))
+	defer srv.Close()
+
+	n := strings.LastIndexByte(srv.URL, ':')
+	ari := &ARInGO{
+		httpClient:     new(http.Client),
+		wsURL:          "ws" + strings.TrimPrefix(srv.URL, "http") + "/",
+		wsOrigin:       srv.URL[:n] + "/",
+		reconnects:     -1,
+		delayFunc:      fibDuration,
+		evChannel:      make(chan map[string]interface{}, 1),
+		errChannel:     make(chan error, 1),
+		wsListenerExit: stopChan,
+	}
+	var decodeErrs []*DecodeError
+	ari.decodeErrHandler = func(err *DecodeError) {
+		decodeErrs = append(decodeErrs, err)
+	}
+
+	var err error
+	if ari.ws, err = websocket.Dial(ari.wsURL, "", ari.wsOrigin); err != nil {
+		t.Fatal(err)
+	}
+
+	ari.wsEventListener()
+	if len(ari.errChannel) != 0 {
+		t.Fatalf("\nExpected: <%+v>, \nReceived: <%+v>", 0, len(ari.errChannel))
+	}
+	if len(decodeErrs) != 2 {
+		t.Fatalf("\nExpected: <%+v>, \nReceived: <%+v>", 2, len(decodeErrs))
+	}
+	if decodeErrs[1].Err != ErrNotAnObject {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", ErrNotAnObject, decodeErrs[1].Err)
+	}
+	if len(ari.evChannel) != 1 {
+		t.Fatalf("\nExpected: <%+v>, \nReceived: <%+v>", 1, len(ari.evChannel))
+	}
+	exp := map[string]interface{}{
+		"type":    "NewerAsteriskEvent",
+		"unknown": map[string]interface{}{"key": 1.},
+	}
+	if rcv := <-ari.evChannel; !reflect.DeepEqual(exp, rcv) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp, rcv)
+	}
+}
+
 func TestAringoCallUnrecognizedMethod(t *testing.T) {
 	ari := &ARInGO{
 		delayFunc: fibDuration,
@@ -508,6 +591,9 @@ func TestAringoCallNoGET(t *testing.T) {
 		if !reflect.DeepEqual(expdata, rcv) {
 			t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", expdata, rcv)
 		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/x-www-form-urlencoded" {
+			t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", "application/x-www-form-urlencoded", ct)
+		}
 		rw.Write([]byte("OK"))
 	}))
 	defer srv.Close()
@@ -523,6 +609,38 @@ func TestAringoCallNoGET(t *testing.T) {
 	}
 }
 
+func TestAringoCallJSON(t *testing.T) {
+	var received []string
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = append(received, r.Method+" "+r.URL.RequestURI()+" "+r.Header.Get("Content-Type")+" "+string(body))
+		rw.Write([]byte(`{"id":"ch1"}`))
+	}))
+	defer srv.Close()
+	ari := NewRESTARInGO(srv.URL, "", "", "aringo")
+	body := map[string]interface{}{"variables": map[string]string{"CALLERID(num)": "1001"}}
+	reply, err := ari.CallJSON(context.Background(), HTTP_POST, srv.URL+"/channels?app=test",
+		url.Values{"endpoint": {"PJSIP/1001"}}, body)
+	if err != nil {
+		t.Fatal(err)
+	} else if string(reply) != `{"id":"ch1"}` {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", `{"id":"ch1"}`, string(reply))
+	}
+	if _, err = ari.CallJSON(context.Background(), HTTP_DELETE, srv.URL+"/channels/ch1", nil, nil); err != nil {
+		t.Error(err)
+	}
+	if _, err = ari.CallJSON(context.Background(), HTTP_POST, srv.URL+"/channels", nil, make(chan int)); err == nil {
+		t.Error("Expected marshal error")
+	}
+	exp := []string{
+		`POST /channels?app=test&endpoint=PJSIP%2F1001 application/json {"variables":{"CALLERID(num)":"1001"}}`,
+		"DELETE /channels/ch1  ",
+	}
+	if !reflect.DeepEqual(exp, received) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp, received)
+	}
+}
+
 func TestAringoCallDoErr(t *testing.T) {
 	stopChan := make(chan struct{})
 
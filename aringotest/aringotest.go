@@ -0,0 +1,30 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+// Package aringotest provides helpers for testing the code built on top of ARInGO
+package aringotest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/cgrates/aringo"
+)
+
+// Inject feeds the frames into the event stream, as if received from Asterisk
+// ari is expected out of aringo.NewReplayARInGO, the frames are dispatched before returning
+func Inject(ari *aringo.ARInGO, frames ...string) error {
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+	for _, frame := range frames {
+		if err := enc.Encode(aringo.RecordEntry{Kind: aringo.RecordKindFrame, Frame: json.RawMessage(frame)}); err != nil {
+			return err
+		}
+	}
+	return ari.Replay(context.Background(), buf, 0)
+}
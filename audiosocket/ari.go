@@ -0,0 +1,99 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package audiosocket
+
+import (
+	"context"
+
+	"github.com/cgrates/aringo"
+)
+
+// Session is one AudioSocket session together with the ARI channel it belongs to
+type Session struct {
+	*Conn
+	ChannelID string
+	BridgeID  string // bridge joining the session with the attached channel, Attach only
+}
+
+// Originate creates a channel towards the AudioSocket server, p.Endpoint is overwritten
+// Returns once Asterisk opened the session, the channel is hung up if it does not within ctx
+func Originate(ctx context.Context, ari *aringo.ARInGO, srv *Server, p aringo.OriginateParams) (*Session, error) {
+	return connect(ctx, ari, srv, func(uuid string) (channelID string, err error) {
+		p.Endpoint = endpoint(srv, uuid)
+		var ch *aringo.Channel
+		if ch, err = ari.Originate(p); err != nil {
+			return
+		}
+		return ch.ID, nil
+	})
+}
+
+// Attach connects an existing channel, inside Stasis, to the AudioSocket server
+// An AudioSocket externalMedia channel is created into app and bridged with it, destroying the bridge is up to the caller
+// The channel is left as it is on errors
+func Attach(ctx context.Context, ari *aringo.ARInGO, srv *Server, app, channelID string) (s *Session, err error) {
+	if s, err = ExternalMedia(ctx, ari, srv, app); err != nil {
+		return
+	}
+	var br *aringo.Bridge
+	if br, err = ari.CreateBridge(aringo.BridgeParams{Type: "mixing"}); err != nil {
+		s.Close()
+		ari.Hangup(s.ChannelID, "")
+		return nil, err
+	}
+	if err = ari.AddChannel(br.ID, channelID, s.ChannelID); err != nil {
+		ari.DestroyBridge(br.ID)
+		s.Close()
+		ari.Hangup(s.ChannelID, "")
+		return nil, err
+	}
+	s.BridgeID = br.ID
+	return
+}
+
+// ExternalMedia creates an externalMedia channel with AudioSocket encapsulation, to be bridged with the call
+func ExternalMedia(ctx context.Context, ari *aringo.ARInGO, srv *Server, app string) (*Session, error) {
+	return connect(ctx, ari, srv, func(uuid string) (channelID string, err error) {
+		var ch *aringo.Channel
+		if ch, err = ari.ExternalMedia(aringo.ExternalMediaParams{
+			App:           app,
+			ExternalHost:  srv.advertiseAddr(),
+			Encapsulation: "audiosocket",
+			Transport:     "tcp",
+			Format:        "slin",
+			Data:          uuid,
+		}); err != nil {
+			return
+		}
+		return ch.ID, nil
+	})
+}
+
+// endpoint returns the dial string of the AudioSocket channel technology
+func endpoint(srv *Server, uuid string) string {
+	return "AudioSocket/" + srv.advertiseAddr() + "/" + uuid
+}
+
+// connect generates the session UUID, instructs Asterisk to create the channel and waits for the session
+// The channel is hung up if the session is not opened
+func connect(ctx context.Context, ari *aringo.ARInGO, srv *Server,
+	instruct func(uuid string) (channelID string, err error)) (s *Session, err error) {
+	uuid := aringo.NewUUID() // the protocol carries binary UUIDs, independent of the ARI id generator
+	pending := srv.Expect(uuid)
+	var channelID string
+	if channelID, err = instruct(uuid); err != nil {
+		pending.Cancel()
+		return
+	}
+	var conn *Conn
+	if conn, err = pending.Wait(ctx); err != nil {
+		ari.Hangup(channelID, "")
+		return
+	}
+	return &Session{Conn: conn, ChannelID: channelID}, nil
+}
@@ -0,0 +1,120 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package audiosocket
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cgrates/aringo"
+)
+
+// newFakeAsterisk answers the ARI requests and opens the AudioSocket sessions they ask for
+// The channels added into bridges are posted on bridged
+func newFakeAsterisk(t *testing.T, srv *Server) (ari *aringo.ARInGO, hangups, bridged chan string) {
+	hangups = make(chan string, 1)
+	bridged = make(chan string, 1)
+	dial := func(addr, uuid string) {
+		if addr != srv.advertiseAddr() {
+			t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", srv.advertiseAddr(), addr)
+		}
+		go func() {
+			if c, err := Dial(addr, uuid); err == nil {
+				t.Cleanup(func() { c.Close() })
+			}
+		}()
+	}
+	ast := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		switch {
+		case r.Method == http.MethodDelete:
+			hangups <- strings.TrimPrefix(r.URL.Path, "/ari/channels/")
+			rw.WriteHeader(http.StatusNoContent)
+			return
+		case r.URL.Path == "/ari/channels/externalMedia":
+			if query.Get("encapsulation") != "audiosocket" || query.Get("transport") != "tcp" || query.Get("format") != "slin" {
+				t.Errorf("Unexpected query: %s", r.URL.RawQuery)
+			}
+			dial(query.Get("external_host"), query.Get("data"))
+		case r.URL.Path == "/ari/channels": // originate
+			parts := strings.Split(query.Get("endpoint"), "/")
+			if len(parts) != 3 || parts[0] != "AudioSocket" {
+				t.Errorf("Unexpected endpoint: %s", query.Get("endpoint"))
+			}
+			dial(parts[1], parts[2])
+		case strings.HasSuffix(r.URL.Path, "/addChannel"):
+			bridged <- r.URL.Path + " " + query.Get("channel")
+			rw.WriteHeader(http.StatusNoContent)
+			return
+		case strings.HasPrefix(r.URL.Path, "/ari/bridges/"):
+			rw.Write([]byte(`{"id":"` + strings.TrimPrefix(r.URL.Path, "/ari/bridges/") + `"}`))
+			return
+		}
+		rw.Write([]byte(`{"id":"` + query.Get("channelId") + `"}`))
+	}))
+	t.Cleanup(ast.Close)
+	return aringo.NewRESTARInGO(ast.URL+"/ari", "", "", "", aringo.WithIDGenerator(func() string { return "ch1" })), hangups, bridged
+}
+
+func TestARIHelpers(t *testing.T) {
+	srv, err := Listen("127.0.0.1:0", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	ari, _, bridged := newFakeAsterisk(t, srv)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	s, err := Originate(ctx, ari, srv, aringo.OriginateParams{App: "bot"})
+	if err != nil {
+		t.Fatal(err)
+	} else if s.ChannelID != "ch1" || len(s.ID()) != 36 {
+		t.Errorf("Unexpected session: %+v", s)
+	}
+	if s, err = ExternalMedia(ctx, ari, srv, "bot"); err != nil {
+		t.Fatal(err)
+	} else if s.ChannelID != "ch1" {
+		t.Errorf("Unexpected session: %+v", s)
+	}
+	if s, err = Attach(ctx, ari, srv, "bot", "call1"); err != nil {
+		t.Fatal(err)
+	} else if s.ChannelID != "ch1" || s.BridgeID != "ch1" {
+		t.Errorf("Unexpected session: %+v", s)
+	}
+	if rcv := <-bridged; rcv != "/ari/bridges/ch1/addChannel call1,ch1" {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", "/ari/bridges/ch1/addChannel call1,ch1", rcv)
+	}
+}
+
+func TestOriginateNoSession(t *testing.T) {
+	srv, err := Listen("127.0.0.1:0", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	srv.SetAdvertiseAddr("127.0.0.1:1") // unreachable, the session is never opened
+	ari, hangups, _ := newFakeAsterisk(t, srv)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err = Originate(ctx, ari, srv, aringo.OriginateParams{App: "bot"}); err != context.DeadlineExceeded {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", context.DeadlineExceeded, err)
+	}
+	select {
+	case chID := <-hangups:
+		if chID != "ch1" {
+			t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", "ch1", chID)
+		}
+	default:
+		t.Error("Channel not hung up")
+	}
+}
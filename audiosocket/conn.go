@@ -0,0 +1,98 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package audiosocket
+
+import (
+	"bufio"
+	"net"
+	"sync"
+)
+
+const audioFrameSize = 320 // bytes inside 20ms of audio
+
+// Conn is one AudioSocket session
+type Conn struct {
+	conn     net.Conn
+	r        *bufio.Reader
+	id       string
+	writeMux sync.Mutex
+}
+
+func newConn(conn net.Conn, id string) *Conn {
+	return &Conn{conn: conn, r: bufio.NewReader(conn), id: id}
+}
+
+// Dial opens a session towards an AudioSocket server, as Asterisk does
+func Dial(addr, uuid string) (c *Conn, err error) {
+	var msg Message
+	if msg, err = UUIDMessage(uuid); err != nil {
+		return
+	}
+	var conn net.Conn
+	if conn, err = net.Dial("tcp", addr); err != nil {
+		return
+	}
+	c = newConn(conn, uuid)
+	if err = c.WriteMessage(msg); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return
+}
+
+// ID returns the UUID of the session
+func (c *Conn) ID() string {
+	return c.id
+}
+
+// RemoteAddr returns the address of the other side
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// ReadMessage reads the next message of the session, not safe for concurrent use
+func (c *Conn) ReadMessage() (Message, error) {
+	return ReadMessage(c.r)
+}
+
+// WriteMessage sends one message, safe for concurrent use
+func (c *Conn) WriteMessage(msg Message) (err error) {
+	var buf []byte
+	if buf, err = msg.Marshal(); err != nil {
+		return
+	}
+	c.writeMux.Lock()
+	defer c.writeMux.Unlock()
+	_, err = c.conn.Write(buf)
+	return
+}
+
+// WriteAudio sends the signed linear 16 bit little endian PCM in frames of 20ms, without pacing
+func (c *Conn) WriteAudio(pcm []byte) (err error) {
+	for len(pcm) != 0 {
+		n := audioFrameSize
+		if n > len(pcm) {
+			n = len(pcm)
+		}
+		if err = c.WriteMessage(AudioMessage(pcm[:n])); err != nil {
+			return
+		}
+		pcm = pcm[n:]
+	}
+	return
+}
+
+// Hangup asks the other side to terminate the session
+func (c *Conn) Hangup() error {
+	return c.WriteMessage(HangupMessage())
+}
+
+// Close closes the TCP connection
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
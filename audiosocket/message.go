@@ -0,0 +1,123 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+// Package audiosocket implements the Asterisk AudioSocket protocol: audio exchanged over TCP as simple TLV frames
+package audiosocket
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Kind is the type of one AudioSocket message
+type Kind byte
+
+const (
+	KindHangup Kind = 0x00 // call hung up, no payload
+	KindUUID   Kind = 0x01 // first message of a session, 16 bytes UUID
+	KindDTMF   Kind = 0x03 // one DTMF digit as ASCII
+	KindAudio  Kind = 0x10 // signed linear 16 bit little endian PCM, 8kHz mono
+	KindError  Kind = 0xff // error, one byte error code
+
+	headerSize     = 3
+	maxPayloadSize = 0xffff
+)
+
+// Error codes carried by the KindError messages
+const (
+	ErrorCodeNone   = 0x00
+	ErrorCodeHangup = 0x01 // call hung up
+	ErrorCodeFrame  = 0x02 // frame forwarding failed
+	ErrorCodeMemory = 0x04 // memory allocation failed
+)
+
+var ErrInvalidUUID = errors.New("INVALID_UUID")
+
+// Message is one AudioSocket frame
+type Message struct {
+	Kind    Kind
+	Payload []byte
+}
+
+// ReadMessage reads one message out of r
+func ReadMessage(r io.Reader) (msg Message, err error) {
+	var header [headerSize]byte
+	if _, err = io.ReadFull(r, header[:]); err != nil {
+		return
+	}
+	msg.Kind = Kind(header[0])
+	msg.Payload = make([]byte, binary.BigEndian.Uint16(header[1:]))
+	if _, err = io.ReadFull(r, msg.Payload); err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return
+}
+
+// Marshal returns the message in wire format
+func (msg Message) Marshal() ([]byte, error) {
+	if len(msg.Payload) > maxPayloadSize {
+		return nil, fmt.Errorf("payload of %d bytes too big", len(msg.Payload))
+	}
+	buf := make([]byte, headerSize+len(msg.Payload))
+	buf[0] = byte(msg.Kind)
+	binary.BigEndian.PutUint16(buf[1:], uint16(len(msg.Payload)))
+	copy(buf[headerSize:], msg.Payload)
+	return buf, nil
+}
+
+// UUID returns the session id carried by a KindUUID message in its textual form
+func (msg Message) UUID() (string, error) {
+	if msg.Kind != KindUUID || len(msg.Payload) != 16 {
+		return "", ErrInvalidUUID
+	}
+	h := hex.EncodeToString(msg.Payload)
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:], nil
+}
+
+// DTMF returns the digit carried by a KindDTMF message
+func (msg Message) DTMF() string {
+	if msg.Kind != KindDTMF || len(msg.Payload) == 0 {
+		return ""
+	}
+	return string(msg.Payload[:1])
+}
+
+// ErrorCode returns the code carried by a KindError message
+func (msg Message) ErrorCode() byte {
+	if msg.Kind != KindError || len(msg.Payload) == 0 {
+		return ErrorCodeNone
+	}
+	return msg.Payload[0]
+}
+
+// UUIDMessage returns the message opening a session
+func UUIDMessage(uuid string) (msg Message, err error) {
+	msg.Kind = KindUUID
+	if msg.Payload, err = hex.DecodeString(strings.ReplaceAll(uuid, "-", "")); err != nil || len(msg.Payload) != 16 {
+		return msg, ErrInvalidUUID
+	}
+	return
+}
+
+// AudioMessage returns the message carrying the PCM audio
+func AudioMessage(pcm []byte) Message {
+	return Message{Kind: KindAudio, Payload: pcm}
+}
+
+// DTMFMessage returns the message carrying one DTMF digit
+func DTMFMessage(digit byte) Message {
+	return Message{Kind: KindDTMF, Payload: []byte{digit}}
+}
+
+// HangupMessage returns the message terminating the session
+func HangupMessage() Message {
+	return Message{Kind: KindHangup}
+}
@@ -0,0 +1,75 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package audiosocket
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestMessageMarshal(t *testing.T) {
+	msg, err := UUIDMessage("40325ec2-5efd-4bd3-805f-53576e581d13")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := new(bytes.Buffer)
+	for _, m := range []Message{msg, DTMFMessage('5'), AudioMessage([]byte{1, 2, 3, 4}), HangupMessage(),
+		{Kind: KindError, Payload: []byte{ErrorCodeFrame}}} {
+		raw, err := m.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf.Write(raw)
+	}
+	exp := []byte{0x01, 0, 16, 0x40, 0x32, 0x5e, 0xc2, 0x5e, 0xfd, 0x4b, 0xd3, 0x80, 0x5f, 0x53, 0x57, 0x6e, 0x58, 0x1d, 0x13,
+		0x03, 0, 1, '5',
+		0x10, 0, 4, 1, 2, 3, 4,
+		0x00, 0, 0,
+		0xff, 0, 1, 0x02}
+	if !bytes.Equal(exp, buf.Bytes()) {
+		t.Errorf("\nExpected: <%x>, \nReceived: <%x>", exp, buf.Bytes())
+	}
+
+	if rcv, err := ReadMessage(buf); err != nil {
+		t.Fatal(err)
+	} else if uuid, err := rcv.UUID(); err != nil || uuid != "40325ec2-5efd-4bd3-805f-53576e581d13" {
+		t.Errorf("Unexpected UUID: %s, error: %v", uuid, err)
+	}
+	if rcv, err := ReadMessage(buf); err != nil || rcv.DTMF() != "5" {
+		t.Errorf("Unexpected DTMF: %+v, error: %v", rcv, err)
+	}
+	if rcv, err := ReadMessage(buf); err != nil || !reflect.DeepEqual(rcv, AudioMessage([]byte{1, 2, 3, 4})) {
+		t.Errorf("Unexpected audio: %+v, error: %v", rcv, err)
+	}
+	if rcv, err := ReadMessage(buf); err != nil || rcv.Kind != KindHangup || len(rcv.Payload) != 0 {
+		t.Errorf("Unexpected hangup: %+v, error: %v", rcv, err)
+	}
+	if rcv, err := ReadMessage(buf); err != nil || rcv.ErrorCode() != ErrorCodeFrame {
+		t.Errorf("Unexpected error message: %+v, error: %v", rcv, err)
+	}
+	if _, err := ReadMessage(buf); err != io.EOF {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", io.EOF, err)
+	}
+	if _, err := ReadMessage(bytes.NewReader([]byte{0x10, 0, 4, 1})); err != io.ErrUnexpectedEOF {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", io.ErrUnexpectedEOF, err)
+	}
+}
+
+func TestMessageInvalid(t *testing.T) {
+	if _, err := UUIDMessage("not-an-uuid"); err != ErrInvalidUUID {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", ErrInvalidUUID, err)
+	}
+	if _, err := (Message{Kind: KindUUID, Payload: []byte{1}}).UUID(); err != ErrInvalidUUID {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", ErrInvalidUUID, err)
+	}
+	if _, err := AudioMessage(make([]byte, maxPayloadSize+1)).Marshal(); err == nil {
+		t.Error("Expected error for oversized payload")
+	}
+}
@@ -0,0 +1,168 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package audiosocket
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+const uuidTimeout = 5 * time.Second // time allowed to the other side to identify the session
+
+var ErrServerClosed = errors.New("SERVER_CLOSED")
+
+// Server accepts the AudioSocket sessions opened by Asterisk
+// Sessions expected via Expect are delivered to their waiter, the others to the handler
+type Server struct {
+	listener   net.Listener
+	advertise  string
+	handler    func(*Conn)
+	waitersMux sync.Mutex
+	waiters    map[string]*Pending
+	done       chan struct{}
+	closeOnce  sync.Once
+}
+
+// Listen starts a server on the TCP address, handler receives the sessions nobody waits for (closed if nil)
+func Listen(addr string, handler func(*Conn)) (srv *Server, err error) {
+	var l net.Listener
+	if l, err = net.Listen("tcp", addr); err != nil {
+		return
+	}
+	return NewServer(l, handler), nil
+}
+
+// NewServer starts serving the sessions accepted by l
+func NewServer(l net.Listener, handler func(*Conn)) (srv *Server) {
+	srv = &Server{
+		listener: l,
+		handler:  handler,
+		waiters:  make(map[string]*Pending),
+		done:     make(chan struct{}),
+	}
+	go srv.serve()
+	return
+}
+
+func (srv *Server) serve() {
+	for {
+		conn, err := srv.listener.Accept()
+		if err != nil {
+			var nErr net.Error
+			if errors.As(err, &nErr) && nErr.Timeout() {
+				continue
+			}
+			return
+		}
+		go srv.open(conn)
+	}
+}
+
+// open identifies the session and passes it further
+func (srv *Server) open(conn net.Conn) {
+	conn.SetReadDeadline(time.Now().Add(uuidTimeout))
+	msg, err := ReadMessage(conn)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	var id string
+	if id, err = msg.UUID(); err != nil {
+		conn.Close()
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+	c := newConn(conn, id)
+	srv.waitersMux.Lock()
+	p, has := srv.waiters[id]
+	delete(srv.waiters, id)
+	srv.waitersMux.Unlock()
+	switch {
+	case has:
+		p.conns <- c
+	case srv.handler != nil:
+		srv.handler(c)
+	default:
+		c.Close()
+	}
+}
+
+// Addr returns the address the server listens on
+func (srv *Server) Addr() net.Addr {
+	return srv.listener.Addr()
+}
+
+// SetAdvertiseAddr sets the host:port Asterisk connects to when different from the listening address
+// (ie: listening on all interfaces or behind NAT)
+func (srv *Server) SetAdvertiseAddr(addr string) {
+	srv.advertise = addr
+}
+
+// advertiseAddr returns the address handed to Asterisk
+func (srv *Server) advertiseAddr() string {
+	if srv.advertise != "" {
+		return srv.advertise
+	}
+	return srv.listener.Addr().String()
+}
+
+// Expect registers interest in the session with the given UUID
+// Expect before instructing Asterisk to connect, so the session cannot arrive unnoticed
+func (srv *Server) Expect(uuid string) (p *Pending) {
+	p = &Pending{srv: srv, uuid: uuid, conns: make(chan *Conn, 1)}
+	srv.waitersMux.Lock()
+	srv.waiters[uuid] = p
+	srv.waitersMux.Unlock()
+	return
+}
+
+// Close stops accepting sessions, the accepted ones are not affected
+func (srv *Server) Close() (err error) {
+	srv.closeOnce.Do(func() {
+		close(srv.done)
+		err = srv.listener.Close()
+	})
+	return
+}
+
+// Pending is one session expected by the application
+type Pending struct {
+	srv   *Server
+	uuid  string
+	conns chan *Conn
+}
+
+// Wait returns the session once opened, the context error or ErrServerClosed
+func (p *Pending) Wait(ctx context.Context) (*Conn, error) {
+	select {
+	case c := <-p.conns:
+		return c, nil
+	case <-ctx.Done():
+		p.Cancel()
+		select {
+		case c := <-p.conns: // opened meanwhile
+			c.Close()
+		default:
+		}
+		return nil, ctx.Err()
+	case <-p.srv.done:
+		return nil, ErrServerClosed
+	}
+}
+
+// Cancel stops expecting the session, if it is opened later it goes to the handler
+func (p *Pending) Cancel() {
+	p.srv.waitersMux.Lock()
+	if p.srv.waiters[p.uuid] == p {
+		delete(p.srv.waiters, p.uuid)
+	}
+	p.srv.waitersMux.Unlock()
+}
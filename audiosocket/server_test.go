@@ -0,0 +1,102 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package audiosocket
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+const testUUID = "40325ec2-5efd-4bd3-805f-53576e581d13"
+
+func TestServer(t *testing.T) {
+	handled := make(chan *Conn, 1)
+	srv, err := Listen("127.0.0.1:0", func(c *Conn) { handled <- c })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	pending := srv.Expect(testUUID)
+	client, err := Dial(srv.Addr().String(), testUUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	conn, err := pending.Wait(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if conn.ID() != testUUID {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", testUUID, conn.ID())
+	}
+
+	audio := bytes.Repeat([]byte{1, 2}, audioFrameSize/2+10) // one frame and a half
+	if err = client.WriteAudio(audio); err != nil {
+		t.Fatal(err)
+	}
+	client.WriteMessage(DTMFMessage('#'))
+	var received []byte
+	for i := 0; i < 2; i++ {
+		msg, err := conn.ReadMessage()
+		if err != nil || msg.Kind != KindAudio {
+			t.Fatalf("Unexpected message: %+v, error: %v", msg, err)
+		}
+		received = append(received, msg.Payload...)
+	}
+	if !bytes.Equal(audio, received) {
+		t.Errorf("\nExpected: <%x>, \nReceived: <%x>", audio, received)
+	}
+	if msg, err := conn.ReadMessage(); err != nil || msg.DTMF() != "#" {
+		t.Errorf("Unexpected DTMF: %+v, error: %v", msg, err)
+	}
+	conn.Hangup()
+	if msg, err := client.ReadMessage(); err != nil || msg.Kind != KindHangup {
+		t.Errorf("Unexpected hangup: %+v, error: %v", msg, err)
+	}
+
+	unexpected, err := Dial(srv.Addr().String(), "b5aa1a64-2e32-4b9f-8e3c-1c5a5e2b33a1") // nobody waits for it
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unexpected.Close()
+	select {
+	case c := <-handled:
+		if c.ID() != unexpected.ID() {
+			t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", unexpected.ID(), c.ID())
+		}
+		c.Close()
+	case <-time.After(time.Second):
+		t.Fatal("Session not handled")
+	}
+}
+
+func TestServerWaitTimeout(t *testing.T) {
+	srv, err := Listen("127.0.0.1:0", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err = srv.Expect(testUUID).Wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", context.DeadlineExceeded, err)
+	}
+	if len(srv.waiters) != 0 {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", 0, len(srv.waiters))
+	}
+	pending := srv.Expect(testUUID)
+	srv.Close()
+	if _, err = pending.Wait(context.Background()); err != ErrServerClosed {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", ErrServerClosed, err)
+	}
+}
@@ -0,0 +1,212 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package aringo
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	talkDetectSet    = "TALK_DETECT(set)"
+	talkDetectRemove = "TALK_DETECT(remove)"
+	bridgeDTMFEvents = "dtmf_events"
+)
+
+// EnableTalkDetection raises ChannelTalkingStarted and ChannelTalkingFinished for the channel
+// silence is the time without voice ending the talking, talkingThreshold the energy level considered voice, 0 for the Asterisk defaults
+func (ari *ARInGO) EnableTalkDetection(channelID string, silence time.Duration, talkingThreshold int) error {
+	var value string
+	if silence != 0 || talkingThreshold != 0 {
+		value = strconv.FormatInt(silence.Milliseconds(), 10)
+		if talkingThreshold != 0 {
+			value += "," + strconv.Itoa(talkingThreshold)
+		}
+	}
+	return ari.SetChannelVar(channelID, talkDetectSet, value)
+}
+
+// DisableTalkDetection stops the talking events of the channel
+func (ari *ARInGO) DisableTalkDetection(channelID string) error {
+	return ari.SetChannelVar(channelID, talkDetectRemove, "")
+}
+
+// EnableBridgeTalkDetection enables the talk detection on the channels currently inside the bridge
+// Use BridgeEventsParams.TalkDetection to cover also the channels joining later
+func (ari *ARInGO) EnableBridgeTalkDetection(bridgeID string) (err error) {
+	var br *Bridge
+	if br, err = ari.GetBridge(bridgeID); err != nil {
+		return
+	}
+	for _, channelID := range br.Channels {
+		if err = ari.EnableTalkDetection(channelID, 0, 0); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// BridgeEvent is one change of a bridge or of the channels inside it
+type BridgeEvent struct {
+	Type      string // ChannelEnteredBridge, ChannelLeftBridge, ChannelTalkingStarted, ChannelTalkingFinished, ChannelDtmfReceived or BridgeDestroyed
+	ChannelID string
+	Digit     string        // ChannelDtmfReceived only
+	Duration  time.Duration // of the talking or of the digit, ChannelTalkingFinished and ChannelDtmfReceived only
+}
+
+// BridgeEventsParams are the parameters of SubscribeBridge
+type BridgeEventsParams struct {
+	TalkDetection bool // enables the talk detection on the channels of the bridge, including the ones joining later
+}
+
+// BridgeEventStream receives the typed events of one bridge, created with SubscribeBridge
+// DTMF of the bridged channels is only reported by the bridges created with dtmf_events, see BridgeParams.DTMFEvents
+type BridgeEventStream struct {
+	ari      *ARInGO
+	BridgeID string
+	p        BridgeEventsParams
+
+	mux     sync.RWMutex
+	members map[string]bool // channels which were in the bridge, false once they left
+
+	sub    *Subscription
+	events chan *BridgeEvent
+	done   chan struct{}
+}
+
+// SubscribeBridge streams the events of the bridge and of the channels inside it until the bridge is destroyed,
+// the stream is closed or ctx is done
+func (ari *ARInGO) SubscribeBridge(ctx context.Context, bridgeID string, p BridgeEventsParams) (bs *BridgeEventStream, err error) {
+	bs = &BridgeEventStream{
+		ari:      ari,
+		BridgeID: bridgeID,
+		p:        p,
+		members:  make(map[string]bool),
+		events:   make(chan *BridgeEvent, subscriptionBuffer),
+		done:     make(chan struct{}),
+	}
+	bs.sub = ari.Subscribe(bs.filter) // before listing the channels so no event is missed
+	var br *Bridge
+	if br, err = ari.GetBridge(bridgeID); err != nil {
+		bs.sub.Close()
+		return nil, err
+	}
+	var channelIDs []string
+	bs.mux.Lock()
+	for _, channelID := range br.Channels {
+		if _, known := bs.members[channelID]; !known { // events are more recent than the reply
+			bs.members[channelID] = true
+			channelIDs = append(channelIDs, channelID)
+		}
+	}
+	bs.mux.Unlock()
+	if p.TalkDetection {
+		for _, channelID := range channelIDs {
+			if err = ari.EnableTalkDetection(channelID, 0, 0); err != nil {
+				bs.sub.Close()
+				return nil, err
+			}
+		}
+	}
+	go bs.run(ctx)
+	return
+}
+
+// Events returns the channel where the bridge events are posted, closed once the stream ends
+// Events are dropped if not consumed in time
+func (bs *BridgeEventStream) Events() <-chan *BridgeEvent {
+	return bs.events
+}
+
+// Done is closed once the stream ends
+func (bs *BridgeEventStream) Done() <-chan struct{} {
+	return bs.done
+}
+
+// Close ends the stream
+func (bs *BridgeEventStream) Close() {
+	bs.sub.Close()
+}
+
+// filter matches the events of the bridge and the ones of its channels
+// The membership is tracked here since the filters see the events in order, before they are posted
+func (bs *BridgeEventStream) filter(ev *Event) bool {
+	switch ev.Type {
+	case "ChannelEnteredBridge", "ChannelLeftBridge":
+		if ev.BridgeID() != bs.BridgeID {
+			return false
+		}
+		bs.mux.Lock()
+		bs.members[ev.ChannelID()] = ev.Type == "ChannelEnteredBridge"
+		bs.mux.Unlock()
+		return true
+	case "BridgeDestroyed":
+		return ev.BridgeID() == bs.BridgeID
+	case "ChannelTalkingStarted", "ChannelTalkingFinished", "ChannelDtmfReceived":
+		bs.mux.RLock()
+		defer bs.mux.RUnlock()
+		return bs.members[ev.ChannelID()]
+	}
+	return false
+}
+
+// run converts the events until the bridge is destroyed or the stream is closed
+func (bs *BridgeEventStream) run(ctx context.Context) {
+	defer close(bs.done)
+	defer close(bs.events)
+	defer bs.sub.Close()
+	for {
+		select {
+		case ev, ok := <-bs.sub.Events():
+			if !ok {
+				return
+			}
+			bev := bs.convert(ev)
+			if bev == nil {
+				continue
+			}
+			if bev.Type == "ChannelEnteredBridge" && bs.p.TalkDetection {
+				if err := bs.ari.EnableTalkDetection(bev.ChannelID, 0, 0); err != nil {
+					bs.ari.log().Warn("ARI talk detection failed", "channel", bev.ChannelID, "error", err)
+				}
+			}
+			select {
+			case bs.events <- bev:
+			default:
+			}
+			if bev.Type == "BridgeDestroyed" {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// convert returns the typed form of one event, nil if it cannot be decoded
+func (bs *BridgeEventStream) convert(ev *Event) (bev *BridgeEvent) {
+	bev = &BridgeEvent{Type: ev.Type, ChannelID: ev.ChannelID()}
+	switch ev.Type {
+	case "ChannelTalkingFinished":
+		var talking ChannelTalking
+		if ev.Decode(&talking) != nil {
+			return nil
+		}
+		bev.Duration = time.Duration(talking.Duration) * time.Millisecond
+	case "ChannelDtmfReceived":
+		var dtmf ChannelDtmfReceived
+		if ev.Decode(&dtmf) != nil {
+			return nil
+		}
+		bev.Digit = dtmf.Digit
+		bev.Duration = time.Duration(dtmf.DurationMs) * time.Millisecond
+	}
+	return
+}
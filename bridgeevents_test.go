@@ -0,0 +1,160 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package aringo
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newBridgeFake serves the bridge br1 holding ch1, logging the requests
+func newBridgeFake(t *testing.T) (fake *fakeARI, ari *ARInGO) {
+	fake, ari = newFakeARI(t)
+	fake.handleREST("/ari/", func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method == HTTP_GET && r.URL.Path == "/ari/bridges/br1" {
+			rw.Write([]byte(`{"id":"br1","bridge_type":"mixing","channels":["ch1"]}`))
+			return
+		}
+		if r.Method == HTTP_GET {
+			rw.WriteHeader(http.StatusNotFound)
+			rw.Write([]byte(`{"message":"Bridge not found"}`))
+			return
+		}
+		rw.WriteHeader(http.StatusNoContent)
+	})
+	return
+}
+
+// collectBridgeEvents reads the stream until closed
+func collectBridgeEvents(t *testing.T, bs *BridgeEventStream) (evs []BridgeEvent) {
+	t.Helper()
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case ev, ok := <-bs.Events():
+			if !ok {
+				return
+			}
+			evs = append(evs, *ev)
+		case <-timeout:
+			t.Fatal("stream not closed")
+		}
+	}
+}
+
+func TestTalkDetection(t *testing.T) {
+	fake, ari := newBridgeFake(t)
+	if err := ari.EnableTalkDetection("ch1", 0, 0); err != nil {
+		t.Error(err)
+	}
+	if err := ari.EnableTalkDetection("ch1", 2500*time.Millisecond, 256); err != nil {
+		t.Error(err)
+	}
+	if err := ari.EnableTalkDetection("ch1", time.Second, 0); err != nil {
+		t.Error(err)
+	}
+	if err := ari.DisableTalkDetection("ch1"); err != nil {
+		t.Error(err)
+	}
+	if err := ari.EnableBridgeTalkDetection("br1"); err != nil {
+		t.Error(err)
+	}
+	fake.checkRequests(t, []string{
+		"POST /ari/channels/ch1/variable?value=&variable=TALK_DETECT%28set%29",
+		"POST /ari/channels/ch1/variable?value=2500%2C256&variable=TALK_DETECT%28set%29",
+		"POST /ari/channels/ch1/variable?value=1000&variable=TALK_DETECT%28set%29",
+		"POST /ari/channels/ch1/variable?value=&variable=TALK_DETECT%28remove%29",
+		"GET /ari/bridges/br1",
+		"POST /ari/channels/ch1/variable?value=&variable=TALK_DETECT%28set%29",
+	})
+}
+
+func TestBridgeParamsType(t *testing.T) {
+	for _, tc := range []struct {
+		p   BridgeParams
+		exp string
+	}{
+		{BridgeParams{}, ""},
+		{BridgeParams{Type: "mixing"}, "mixing"},
+		{BridgeParams{DTMFEvents: true}, "dtmf_events"},
+		{BridgeParams{Type: "mixing", DTMFEvents: true}, "mixing,dtmf_events"},
+		{BridgeParams{Type: "mixing,dtmf_events", DTMFEvents: true}, "mixing,dtmf_events"},
+	} {
+		if rcv := tc.p.bridgeType(); rcv != tc.exp {
+			t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", tc.exp, rcv)
+		}
+	}
+}
+
+func TestSubscribeBridge(t *testing.T) {
+	fake, ari := newBridgeFake(t)
+	bs, err := ari.SubscribeBridge(context.Background(), "br1", BridgeEventsParams{TalkDetection: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, frame := range []string{
+		`{"type":"ChannelTalkingStarted","channel":{"id":"ch9"}}`,
+		`{"type":"ChannelEnteredBridge","bridge":{"id":"br1"},"channel":{"id":"ch2"}}`,
+		`{"type":"ChannelTalkingStarted","channel":{"id":"ch2"}}`,
+		`{"type":"ChannelTalkingFinished","channel":{"id":"ch2"},"duration":1500}`,
+		`{"type":"ChannelDtmfReceived","channel":{"id":"ch1"},"digit":"5","duration_ms":120}`,
+		`{"type":"ChannelLeftBridge","bridge":{"id":"br1"},"channel":{"id":"ch2"}}`,
+		`{"type":"ChannelTalkingStarted","channel":{"id":"ch2"}}`,
+		`{"type":"ChannelEnteredBridge","bridge":{"id":"br2"},"channel":{"id":"ch3"}}`,
+		`{"type":"BridgeDestroyed","bridge":{"id":"br1"}}`,
+	} {
+		fake.send(frame)
+	}
+	exp := []BridgeEvent{
+		{Type: "ChannelEnteredBridge", ChannelID: "ch2"},
+		{Type: "ChannelTalkingStarted", ChannelID: "ch2"},
+		{Type: "ChannelTalkingFinished", ChannelID: "ch2", Duration: 1500 * time.Millisecond},
+		{Type: "ChannelDtmfReceived", ChannelID: "ch1", Digit: "5", Duration: 120 * time.Millisecond},
+		{Type: "ChannelLeftBridge", ChannelID: "ch2"},
+		{Type: "BridgeDestroyed"},
+	}
+	if rcv := collectBridgeEvents(t, bs); !reflect.DeepEqual(exp, rcv) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp, rcv)
+	}
+	fake.checkRequests(t, []string{
+		"GET /ari/bridges/br1",
+		"POST /ari/channels/ch1/variable?value=&variable=TALK_DETECT%28set%29",
+		"POST /ari/channels/ch2/variable?value=&variable=TALK_DETECT%28set%29",
+	})
+	<-bs.Done()
+}
+
+func TestSubscribeBridgeEnd(t *testing.T) {
+	fake, ari := newBridgeFake(t)
+	if _, err := ari.SubscribeBridge(context.Background(), "missing", BridgeEventsParams{}); !IsReplyCode(err, http.StatusNotFound) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	bs, err := ari.SubscribeBridge(ctx, "br1", BridgeEventsParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if rcv := collectBridgeEvents(t, bs); len(rcv) != 0 {
+		t.Errorf("Unexpected events: %+v", rcv)
+	}
+	bs, err = ari.SubscribeBridge(context.Background(), "br1", BridgeEventsParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs.Close()
+	collectBridgeEvents(t, bs)
+	fake.checkRequests(t, []string{ // no changes
+		"GET /ari/bridges/missing",
+		"GET /ari/bridges/br1",
+		"GET /ari/bridges/br1",
+	})
+}
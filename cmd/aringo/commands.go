@@ -0,0 +1,286 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cgrates/aringo"
+)
+
+type runFunc = func(ctx context.Context, env *cmdEnv, args []string) error
+
+// exactArgs returns errUsage unless there are n positional arguments
+func exactArgs(args []string, n int) error {
+	if len(args) != n {
+		return errUsage
+	}
+	return nil
+}
+
+func eventsTail(fs *flag.FlagSet) runFunc {
+	var apps, types listFlag
+	fs.Var(&apps, "app", "Stasis application to receive the events for, repeatable (required)")
+	fs.Var(&types, "type", "only print the events of this type, repeatable")
+	count := fs.Int("count", 0, "exit after printing this number of events")
+	subscribeAll := fs.Bool("subscribe-all", false, "receive all the events of Asterisk, not only the ones of the application resources")
+	reconnects := fs.Int("reconnects", 5, "reconnect attempts when the websocket is lost")
+	return func(ctx context.Context, env *cmdEnv, args []string) (err error) {
+		if len(apps) == 0 || len(args) != 0 {
+			return errUsage
+		}
+		errChan := make(chan error, 1)
+		stopChan := make(chan struct{})
+		defer close(stopChan)
+		opts := []aringo.Option{aringo.WithRESTURL(env.cfg.URL), aringo.WithApplications(apps...)}
+		if *subscribeAll {
+			opts = append(opts, aringo.WithSubscribeAll())
+		}
+		var ari *aringo.ARInGO
+		if ari, err = aringo.NewARInGO(env.cfg.wsURL(), env.cfg.URL, env.cfg.Username, env.cfg.Password, userAgent,
+			nil, errChan, stopChan, 1, *reconnects, 30*time.Second, doublingDelay, opts...); err != nil {
+			return
+		}
+		filter := func(*aringo.Event) bool { return true }
+		if len(types) != 0 {
+			filter = aringo.TypeFilter(types...)
+		}
+		sub := ari.Subscribe(filter)
+		defer sub.Close()
+		for printed := 0; *count == 0 || printed < *count; printed++ {
+			select {
+			case <-ctx.Done():
+				return
+			case err = <-errChan:
+				return
+			case ev, ok := <-sub.Events():
+				if !ok {
+					return sub.Err()
+				}
+				if env.cfg.Format == formatJSON {
+					fmt.Fprintln(env.out, string(ev.Raw))
+					continue
+				}
+				fmt.Fprintf(env.out, "%-29s %-26s %-16s %s\n", ev.Timestamp, ev.Type, ev.Application, eventSubject(ev))
+			}
+		}
+		return
+	}
+}
+
+// eventSubject returns the resources an event refers to, for the table output
+func eventSubject(ev *aringo.Event) string {
+	var subject []string
+	for prefix, id := range map[string]string{
+		"channel:":  ev.ChannelID(),
+		"bridge:":   ev.BridgeID(),
+		"playback:": ev.PlaybackID(),
+	} {
+		if id != "" {
+			subject = append(subject, prefix+id)
+		}
+	}
+	sort.Strings(subject)
+	return orDash(strings.Join(subject, " "))
+}
+
+// doublingDelay doubles the reconnect delay up to max
+func doublingDelay(min, max time.Duration) func() time.Duration {
+	delay := min / 2
+	return func() time.Duration {
+		if delay *= 2; max != 0 && delay > max {
+			delay = max
+		}
+		return delay
+	}
+}
+
+func channelsTable(chs ...*aringo.Channel) (tbl table) {
+	tbl.header = []string{"ID", "NAME", "STATE", "CALLER", "DIALPLAN", "CREATED"}
+	for _, ch := range chs {
+		tbl.rows = append(tbl.rows, []string{ch.ID, ch.Name, ch.State,
+			orDash(strings.TrimSpace(ch.Caller.Name + " " + ch.Caller.Number)),
+			dialplanLocation(ch.Dialplan),
+			orDash(ch.CreationTime)})
+	}
+	return
+}
+
+// dialplanLocation formats the dialplan location as context,exten,priority
+func dialplanLocation(dp aringo.DialplanCEP) string {
+	if dp.Context == "" {
+		return "-"
+	}
+	return dp.Context + "," + dp.Exten + "," + strconv.Itoa(dp.Priority)
+}
+
+func channelsList(fs *flag.FlagSet) runFunc {
+	return func(ctx context.Context, env *cmdEnv, args []string) (err error) {
+		if err = exactArgs(args, 0); err != nil {
+			return
+		}
+		var chs []*aringo.Channel
+		if chs, err = env.ari.ListChannels(); err != nil {
+			return
+		}
+		return printResult(env.out, env.cfg.Format, chs, channelsTable(chs...))
+	}
+}
+
+func channelsGet(fs *flag.FlagSet) runFunc {
+	return func(ctx context.Context, env *cmdEnv, args []string) (err error) {
+		if err = exactArgs(args, 1); err != nil {
+			return
+		}
+		var ch *aringo.Channel
+		if ch, err = env.ari.GetChannel(args[0]); err != nil {
+			return
+		}
+		return printResult(env.out, env.cfg.Format, ch, channelsTable(ch))
+	}
+}
+
+func channelsHangup(fs *flag.FlagSet) runFunc {
+	reason := fs.String("reason", "", "hangup reason: normal, busy, congestion, no_answer, ...")
+	return func(ctx context.Context, env *cmdEnv, args []string) (err error) {
+		if err = exactArgs(args, 1); err != nil {
+			return
+		}
+		return env.ari.Hangup(args[0], *reason)
+	}
+}
+
+func bridgesList(fs *flag.FlagSet) runFunc {
+	return func(ctx context.Context, env *cmdEnv, args []string) (err error) {
+		if err = exactArgs(args, 0); err != nil {
+			return
+		}
+		var brs []*aringo.Bridge
+		if brs, err = env.ari.ListBridges(); err != nil {
+			return
+		}
+		tbl := table{header: []string{"ID", "TYPE", "CLASS", "NAME", "CHANNELS"}}
+		for _, br := range brs {
+			tbl.rows = append(tbl.rows, []string{br.ID, br.BridgeType, orDash(br.BridgeClass), orDash(br.Name),
+				orDash(strings.Join(br.Channels, ","))})
+		}
+		return printResult(env.out, env.cfg.Format, brs, tbl)
+	}
+}
+
+func bridgesDestroy(fs *flag.FlagSet) runFunc {
+	return func(ctx context.Context, env *cmdEnv, args []string) (err error) {
+		if err = exactArgs(args, 1); err != nil {
+			return
+		}
+		return env.ari.DestroyBridge(args[0])
+	}
+}
+
+func originate(fs *flag.FlagSet) runFunc {
+	var p aringo.OriginateParams
+	fs.StringVar(&p.App, "app", "", "Stasis application to place the channel into")
+	fs.StringVar(&p.AppArgs, "app-args", "", "arguments passed to the Stasis application")
+	fs.StringVar(&p.Extension, "extension", "", "dialplan extension to connect the channel to")
+	fs.StringVar(&p.Context, "context", "", "dialplan context")
+	fs.IntVar(&p.Priority, "priority", 0, "dialplan priority")
+	fs.StringVar(&p.Label, "label", "", "dialplan label")
+	fs.StringVar(&p.CallerID, "caller-id", "", "caller id of the new channel")
+	fs.IntVar(&p.Timeout, "timeout", 0, "seconds to wait for answer")
+	fs.StringVar(&p.ChannelID, "channel-id", "", "id of the new channel, generated if empty")
+	return func(ctx context.Context, env *cmdEnv, args []string) (err error) {
+		if err = exactArgs(args, 1); err != nil {
+			return
+		}
+		if p.App == "" && p.Extension == "" {
+			return errors.New("either -app or -extension is required")
+		}
+		p.Endpoint = args[0]
+		var ch *aringo.Channel
+		if ch, err = env.ari.Originate(p); err != nil {
+			return
+		}
+		return printResult(env.out, env.cfg.Format, ch, channelsTable(ch))
+	}
+}
+
+func play(fs *flag.FlagSet) runFunc {
+	channelID := fs.String("channel", "", "channel to play the media on")
+	bridgeID := fs.String("bridge", "", "bridge to play the media on")
+	return func(ctx context.Context, env *cmdEnv, args []string) (err error) {
+		if len(args) == 0 || (*channelID == "") == (*bridgeID == "") {
+			return errUsage
+		}
+		var pb *aringo.Playback
+		if *channelID != "" {
+			pb, err = env.ari.Play(*channelID, args...)
+		} else {
+			pb, err = env.ari.PlayOnBridge(*bridgeID, args...)
+		}
+		if err != nil {
+			return
+		}
+		return printResult(env.out, env.cfg.Format, pb, table{
+			header: []string{"ID", "MEDIA", "TARGET", "STATE"},
+			rows:   [][]string{{pb.ID, pb.MediaURI, pb.TargetURI, pb.State}},
+		})
+	}
+}
+
+func asteriskInfo(fs *flag.FlagSet) runFunc {
+	var only listFlag
+	fs.Var(&only, "only", "only show these sections: build, system, config, status")
+	return func(ctx context.Context, env *cmdEnv, args []string) (err error) {
+		if err = exactArgs(args, 0); err != nil {
+			return
+		}
+		var info *aringo.AsteriskInfo
+		if info, err = env.ari.AsteriskInfo(only...); err != nil {
+			return
+		}
+		tbl := table{header: []string{"FIELD", "VALUE"}}
+		add := func(field, value string) {
+			tbl.rows = append(tbl.rows, []string{field, orDash(value)})
+		}
+		if info.System != nil {
+			add("version", info.System.Version)
+			add("entity_id", info.System.EntityID)
+		}
+		if info.Config != nil {
+			add("name", info.Config.Name)
+			add("default_language", info.Config.DefaultLanguage)
+		}
+		if info.Status != nil {
+			add("startup_time", info.Status.StartupTime)
+			add("last_reload_time", info.Status.LastReloadTime)
+		}
+		if info.Build != nil {
+			add("build_os", info.Build.OS)
+			add("build_kernel", info.Build.Kernel)
+			add("build_machine", info.Build.Machine)
+			add("build_date", info.Build.Date)
+		}
+		return printResult(env.out, env.cfg.Format, info, tbl)
+	}
+}
+
+func modulesReload(fs *flag.FlagSet) runFunc {
+	return func(ctx context.Context, env *cmdEnv, args []string) (err error) {
+		if err = exactArgs(args, 1); err != nil {
+			return
+		}
+		return env.ari.ReloadModule(args[0])
+	}
+}
@@ -0,0 +1,133 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// runCLI runs the command line against the server, returning the output
+func runCLI(t *testing.T, srv *httptest.Server, args ...string) (string, error) {
+	cfgPath := filepath.Join(t.TempDir(), "aringo.json") // keep the user config out
+	if err := os.WriteFile(cfgPath, []byte(`{}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{
+		"ARINGO_URL":      srv.URL + "/ari",
+		"ARINGO_USERNAME": "cgrates",
+		"ARINGO_PASSWORD": "CGRateS.org",
+		"ARINGO_CONFIG":   cfgPath,
+	}
+	out := new(bytes.Buffer)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := run(ctx, args, func(key string) string { return env[key] }, out, io.Discard)
+	return out.String(), err
+}
+
+func newTestServer(t *testing.T) (srv *httptest.Server, received *[]string) {
+	received = new([]string)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ari/", func(rw http.ResponseWriter, r *http.Request) {
+		*received = append(*received, r.Method+" "+r.URL.RequestURI())
+		switch r.URL.Path {
+		case "/ari/channels":
+			rw.Write([]byte(`[{"id":"ch1","name":"PJSIP/1001-01","state":"Up","caller":{"name":"Alice","number":"1001"},
+				"dialplan":{"context":"default","exten":"100","priority":1}}]`))
+		case "/ari/channels/ch2":
+			rw.Write([]byte(`{"id":"ch2","name":"PJSIP/1002-02","state":"Down"}`))
+		default:
+			rw.WriteHeader(http.StatusNoContent)
+		}
+	})
+	mux.Handle("/ari/events", websocket.Handler(func(c *websocket.Conn) {
+		for _, frame := range []string{
+			`{"type":"StasisStart","application":"test","timestamp":"2026-10-19T10:00:00.000+0000","channel":{"id":"ch1"}}`,
+			`{"type":"ChannelDtmfReceived","application":"test","timestamp":"2026-10-19T10:00:01.000+0000","digit":"1","channel":{"id":"ch1"}}`,
+			`{"type":"ChannelDtmfReceived","application":"test","timestamp":"2026-10-19T10:00:02.000+0000","digit":"2","channel":{"id":"ch1"}}`,
+		} {
+			time.Sleep(10 * time.Millisecond) // let the subscription in
+			c.Write([]byte(frame))
+		}
+		c.Read(make([]byte, 1))
+	}))
+	srv = httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return
+}
+
+func TestCLIChannels(t *testing.T) {
+	srv, received := newTestServer(t)
+	out, err := runCLI(t, srv, "channels", "list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := "ID   NAME           STATE  CALLER      DIALPLAN       CREATED\n" +
+		"ch1  PJSIP/1001-01  Up     Alice 1001  default,100,1  -\n"
+	if out != exp {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp, out)
+	}
+	if out, err = runCLI(t, srv, "channels", "get", "-format", "json", "ch2"); err != nil {
+		t.Fatal(err)
+	} else if !strings.Contains(out, `"id": "ch2"`) || !strings.Contains(out, `"state": "Down"`) {
+		t.Errorf("Unexpected output: %s", out)
+	}
+	if _, err = runCLI(t, srv, "channels", "hangup", "-reason", "busy", "ch2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = runCLI(t, srv, "modules", "reload", "res_pjsip.so"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = runCLI(t, srv, "channels", "hangup"); err != errUsage {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", errUsage, err)
+	}
+	if _, err = runCLI(t, srv, "channels", "dance"); err != errUsage {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", errUsage, err)
+	}
+	expReceived := []string{
+		"GET /ari/channels",
+		"GET /ari/channels/ch2",
+		"DELETE /ari/channels/ch2?reason=busy",
+		"PUT /ari/asterisk/modules/res_pjsip.so",
+	}
+	if strings.Join(*received, "\n") != strings.Join(expReceived, "\n") {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", expReceived, *received)
+	}
+}
+
+func TestCLIEventsTail(t *testing.T) {
+	srv, _ := newTestServer(t)
+	out, err := runCLI(t, srv, "events", "tail", "-app", "test", "-type", "ChannelDtmfReceived", "-count", "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := "2026-10-19T10:00:01.000+0000  ChannelDtmfReceived        test             channel:ch1\n" +
+		"2026-10-19T10:00:02.000+0000  ChannelDtmfReceived        test             channel:ch1\n"
+	if out != exp {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp, out)
+	}
+	if out, err = runCLI(t, srv, "events", "tail", "-app", "test", "-format", "json", "-count", "1"); err != nil {
+		t.Fatal(err)
+	} else if !strings.HasPrefix(out, `{"type":"StasisStart"`) {
+		t.Errorf("Unexpected output: %s", out)
+	}
+	if _, err = runCLI(t, srv, "events", "tail"); err != errUsage {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", errUsage, err)
+	}
+}
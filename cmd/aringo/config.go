@@ -0,0 +1,129 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+)
+
+// config holds the connection settings and the output format
+// Resolved out of defaults, config file, environment and flags, in this order of precedence
+type config struct {
+	URL      string `json:"url"` // base URL of the REST resources (ie: http://127.0.0.1:8088/ari)
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Format   string `json:"format"` // table or json
+}
+
+// connFlags are the connection flags shared by all the commands
+type connFlags struct {
+	configPath string
+	cfg        config
+}
+
+// register adds the connection flags to a command flag set
+func (cf *connFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&cf.configPath, "config", "", "path of the JSON config file (env ARINGO_CONFIG, default ~/.config/aringo/config.json)")
+	fs.StringVar(&cf.cfg.URL, "url", "", "ARI REST base URL (env ARINGO_URL, default http://127.0.0.1:8088/ari)")
+	fs.StringVar(&cf.cfg.Username, "username", "", "ARI username (env ARINGO_USERNAME)")
+	fs.StringVar(&cf.cfg.Password, "password", "", "ARI password (env ARINGO_PASSWORD)")
+	fs.StringVar(&cf.cfg.Format, "format", "", "output format: table or json (env ARINGO_FORMAT)")
+}
+
+// resolve merges the settings out of all sources, getenv is os.Getenv outside tests
+func (cf *connFlags) resolve(getenv func(string) string) (cfg config, err error) {
+	cfg = config{URL: "http://127.0.0.1:8088/ari", Format: formatTable}
+	path := cf.configPath
+	if path == "" {
+		path = getenv("ARINGO_CONFIG")
+	}
+	if err = loadConfigFile(&cfg, path); err != nil {
+		return
+	}
+	cfg.merge(config{
+		URL:      getenv("ARINGO_URL"),
+		Username: getenv("ARINGO_USERNAME"),
+		Password: getenv("ARINGO_PASSWORD"),
+		Format:   getenv("ARINGO_FORMAT"),
+	})
+	cfg.merge(cf.cfg)
+	if cfg.Format != formatTable && cfg.Format != formatJSON {
+		return cfg, fmt.Errorf("unsupported format: %q", cfg.Format)
+	}
+	cfg.URL = strings.TrimSuffix(cfg.URL, "/")
+	return
+}
+
+// merge overwrites the settings which are populated in other
+func (cfg *config) merge(other config) {
+	for dst, src := range map[*string]string{
+		&cfg.URL:      other.URL,
+		&cfg.Username: other.Username,
+		&cfg.Password: other.Password,
+		&cfg.Format:   other.Format,
+	} {
+		if src != "" {
+			*dst = src
+		}
+	}
+}
+
+// loadConfigFile merges the settings out of the config file
+// Without explicit path the default one is used if it exists
+func loadConfigFile(cfg *config, path string) (err error) {
+	explicit := path != ""
+	if !explicit {
+		var dir string
+		if dir, err = os.UserConfigDir(); err != nil {
+			return nil
+		}
+		path = filepath.Join(dir, "aringo", "config.json")
+	}
+	var content []byte
+	if content, err = os.ReadFile(path); err != nil {
+		if !explicit && errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return
+	}
+	var fileCfg config
+	if err = json.Unmarshal(content, &fileCfg); err != nil {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	cfg.merge(fileCfg)
+	return
+}
+
+// wsURL returns the websocket URL of the events
+// ARI accepts the credentials inside the api_key parameter of the websocket
+func (cfg *config) wsURL() string {
+	wsURL := cfg.URL
+	switch {
+	case strings.HasPrefix(wsURL, "https://"):
+		wsURL = "wss://" + strings.TrimPrefix(wsURL, "https://")
+	case strings.HasPrefix(wsURL, "http://"):
+		wsURL = "ws://" + strings.TrimPrefix(wsURL, "http://")
+	}
+	wsURL += "/events"
+	if cfg.Username != "" {
+		wsURL += "?" + url.Values{"api_key": {cfg.Username + ":" + cfg.Password}}.Encode()
+	}
+	return wsURL
+}
@@ -0,0 +1,63 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigResolve(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "aringo.json")
+	if err := os.WriteFile(cfgPath, []byte(`{"url":"http://10.0.0.1:8088/ari/","username":"file","password":"filepass"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{
+		"ARINGO_CONFIG":   cfgPath,
+		"ARINGO_USERNAME": "env",
+		"ARINGO_FORMAT":   "json",
+	}
+	var cf connFlags
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cf.register(fs)
+	if err := fs.Parse([]string{"-username", "flag"}); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := cf.resolve(func(key string) string { return env[key] })
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := config{URL: "http://10.0.0.1:8088/ari", Username: "flag", Password: "filepass", Format: formatJSON}
+	if cfg != exp {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp, cfg)
+	}
+
+	env = map[string]string{"ARINGO_FORMAT": "yaml", "ARINGO_CONFIG": filepath.Join(t.TempDir(), "missing.json")}
+	if _, err = new(connFlags).resolve(func(key string) string { return env[key] }); err == nil {
+		t.Error("Expected error for missing explicit config file")
+	}
+	delete(env, "ARINGO_CONFIG")
+	if _, err = new(connFlags).resolve(func(key string) string { return env[key] }); err == nil {
+		t.Error("Expected error for unsupported format")
+	}
+}
+
+func TestConfigWSURL(t *testing.T) {
+	cfg := config{URL: "https://pbx.example.com/ari", Username: "cgrates", Password: "CGRateS.org"}
+	exp := "wss://pbx.example.com/ari/events?api_key=cgrates%3ACGRateS.org"
+	if rcv := cfg.wsURL(); rcv != exp {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp, rcv)
+	}
+	cfg = config{URL: "http://127.0.0.1:8088/ari"}
+	exp = "ws://127.0.0.1:8088/ari/events"
+	if rcv := cfg.wsURL(); rcv != exp {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp, rcv)
+	}
+}
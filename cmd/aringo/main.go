@@ -0,0 +1,141 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+// Command aringo runs ad-hoc ARI operations and tails the ARI events
+//
+// Usage:
+//
+//	aringo <command> [<subcommand>] [flags] [args]
+//
+// The connection settings are read out of the flags, the ARINGO_* environment variables
+// or a JSON config file ({"url": ..., "username": ..., "password": ..., "format": ...}), in this order of precedence
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/cgrates/aringo"
+)
+
+const userAgent = "aringo-cli"
+
+var errUsage = errors.New("usage")
+
+// cmdEnv is what the commands run with
+type cmdEnv struct {
+	cfg config
+	ari *aringo.ARInGO // REST only connection
+	out io.Writer
+}
+
+// command is one CLI operation, setup registers its flags and returns the function running it
+type command struct {
+	name  string // ie: channels list
+	args  string // positional arguments, for the usage
+	descr string
+	setup func(fs *flag.FlagSet) runFunc
+}
+
+var commands = []*command{
+	{name: "events tail", descr: "print the events received by the applications", setup: eventsTail},
+	{name: "channels list", descr: "list the active channels", setup: channelsList},
+	{name: "channels get", args: "<channelId>", descr: "show one channel", setup: channelsGet},
+	{name: "channels hangup", args: "<channelId>", descr: "hang up one channel", setup: channelsHangup},
+	{name: "bridges list", descr: "list the active bridges", setup: bridgesList},
+	{name: "bridges destroy", args: "<bridgeId>", descr: "destroy one bridge", setup: bridgesDestroy},
+	{name: "originate", args: "<endpoint>", descr: "create a new channel", setup: originate},
+	{name: "play", args: "<media>...", descr: "play media on a channel or bridge", setup: play},
+	{name: "asterisk info", descr: "show the Asterisk system information", setup: asteriskInfo},
+	{name: "modules reload", args: "<module>", descr: "reload one Asterisk module", setup: modulesReload},
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := run(ctx, os.Args[1:], os.Getenv, os.Stdout, os.Stderr); err != nil {
+		if err != errUsage {
+			fmt.Fprintln(os.Stderr, "aringo:", err)
+		}
+		stop()
+		os.Exit(1)
+	}
+}
+
+// run executes the command line args
+func run(ctx context.Context, args []string, getenv func(string) string, stdout, stderr io.Writer) (err error) {
+	cmd, cmdArgs := findCommand(args)
+	if cmd == nil {
+		printUsage(stderr)
+		return errUsage
+	}
+	fs := flag.NewFlagSet("aringo "+cmd.name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: aringo %s [flags] %s\n\n%s\n\nFlags:\n", cmd.name, cmd.args, cmd.descr)
+		fs.PrintDefaults()
+	}
+	var cf connFlags
+	cf.register(fs)
+	runCmd := cmd.setup(fs)
+	if err = fs.Parse(cmdArgs); err == flag.ErrHelp {
+		return nil
+	} else if err != nil {
+		return errUsage
+	}
+	env := &cmdEnv{out: stdout}
+	if env.cfg, err = cf.resolve(getenv); err != nil {
+		return
+	}
+	env.ari = aringo.NewRESTARInGO(env.cfg.URL, env.cfg.Username, env.cfg.Password, userAgent)
+	if err = runCmd(ctx, env, fs.Args()); err == errUsage {
+		fs.Usage()
+	}
+	return
+}
+
+// findCommand matches the longest command name at the beginning of args
+func findCommand(args []string) (*command, []string) {
+	for _, n := range []int{2, 1} {
+		if len(args) < n {
+			continue
+		}
+		name := strings.Join(args[:n], " ")
+		for _, cmd := range commands {
+			if cmd.name == name {
+				return cmd, args[n:]
+			}
+		}
+	}
+	return nil, nil
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: aringo <command> [flags] [args]\n\nCommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-36s %s\n", strings.TrimSpace(cmd.name+" "+cmd.args), cmd.descr)
+	}
+	fmt.Fprintln(w, "\nRun aringo <command> -h for the flags of each command")
+}
+
+// listFlag collects the values of a flag which can be repeated or comma separated
+type listFlag []string
+
+func (lf *listFlag) String() string {
+	return strings.Join(*lf, ",")
+}
+
+func (lf *listFlag) Set(val string) error {
+	*lf = append(*lf, strings.Split(val, ",")...)
+	return nil
+}
@@ -0,0 +1,45 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// table is the tabular form of a command result
+type table struct {
+	header []string
+	rows   [][]string
+}
+
+// printResult writes v as indented JSON or its table form, depending on format
+func printResult(w io.Writer, format string, v interface{}, tbl table) (err error) {
+	if format == formatJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(tbl.header, "\t"))
+	for _, row := range tbl.rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// orDash keeps the empty table cells visible
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
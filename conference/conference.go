@@ -0,0 +1,482 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+// Package conference runs conference rooms on top of ARI mixing bridges
+package conference
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cgrates/aringo"
+)
+
+const eventsBuffer = 64 // conference events queued before dropping
+
+// participant roles
+const (
+	RoleParticipant = "participant" // talks and listens
+	RoleModerator   = "moderator"   // participant controlling the conference start and end
+	RoleListener    = "listener"    // listens only, muted for the whole conference
+)
+
+// conference event types
+const (
+	ParticipantJoined  = "ParticipantJoined"
+	ParticipantLeft    = "ParticipantLeft"
+	ParticipantMuted   = "ParticipantMuted"
+	ParticipantUnmuted = "ParticipantUnmuted"
+	TalkingStarted     = "TalkingStarted"
+	TalkingFinished    = "TalkingFinished"
+	ConferenceEnded    = "ConferenceEnded"
+)
+
+const (
+	mixingBridgeType    = "mixing"
+	muteDirectionInward = "in" // mutes what the participant says, not what it hears
+)
+
+var (
+	ErrNotParticipant = errors.New("NOT_PARTICIPANT")
+	ErrListener       = errors.New("LISTENER_CANNOT_TALK")
+	ErrEnded          = errors.New("CONFERENCE_ENDED")
+)
+
+// Config are the settings of one conference
+type Config struct {
+	BridgeID         string // generated if empty
+	Name             string
+	JoinSound        string // media played to the conference when a participant joins, ie: sound:confbridge-join
+	LeaveSound       string // media played to the conference when a participant leaves
+	WaitForModerator bool   // participants hear music on hold until a moderator joins
+	EndWithModerator bool   // the conference ends once the last moderator leaves
+}
+
+// Participant is the state of one channel inside the conference
+type Participant struct {
+	ChannelID string
+	Role      string
+	Muted     bool
+	Talking   bool
+	JoinedAt  time.Time
+}
+
+// Event reports a change of the conference, Participant is a snapshot taken at the time of the change
+type Event struct {
+	Type        string
+	Participant Participant
+	Duration    time.Duration // TalkingFinished only
+}
+
+// Conference is one conference room, created with New
+type Conference struct {
+	ari      *aringo.ARInGO
+	cfg      Config
+	BridgeID string
+
+	mux          sync.RWMutex
+	participants map[string]*Participant
+	onHold       bool // waiting for a moderator
+
+	sub       *aringo.Subscription
+	events    chan *Event
+	closed    chan struct{} // closed by Close
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// New creates the mixing bridge of the conference
+func New(ari *aringo.ARInGO, cfg Config) (conf *Conference, err error) {
+	if cfg.BridgeID == "" {
+		cfg.BridgeID = ari.NewID()
+	}
+	conf = &Conference{
+		ari:          ari,
+		cfg:          cfg,
+		BridgeID:     cfg.BridgeID,
+		participants: make(map[string]*Participant),
+		events:       make(chan *Event, eventsBuffer),
+		closed:       make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+	conf.sub = conf.subscribe() // before creating the bridge so no event is missed
+	if _, err = ari.CreateBridge(aringo.BridgeParams{BridgeID: cfg.BridgeID, Type: mixingBridgeType, Name: cfg.Name,
+		DTMFEvents: true}); err != nil {
+		conf.sub.Close()
+		return nil, err
+	}
+	go conf.run()
+	return
+}
+
+// subscribe follows the events of the bridge and the talking of the participants
+func (conf *Conference) subscribe() *aringo.Subscription {
+	return conf.ari.Subscribe(func(ev *aringo.Event) bool {
+		return ev.BridgeID() == conf.BridgeID ||
+			(ev.Type == "ChannelTalkingStarted" || ev.Type == "ChannelTalkingFinished") && conf.has(ev.ChannelID())
+	})
+}
+
+// Events returns the channel where the conference changes are posted, closed once the conference ends
+// Events are dropped if not consumed in time
+func (conf *Conference) Events() <-chan *Event {
+	return conf.events
+}
+
+// Done is closed once the conference ends
+func (conf *Conference) Done() <-chan struct{} {
+	return conf.done
+}
+
+// Join adds the channel into the conference with the given role
+// Listeners are muted, the others get talk detection enabled
+func (conf *Conference) Join(channelID, role string) (err error) {
+	select {
+	case <-conf.done:
+		return ErrEnded
+	default:
+	}
+	if role == "" {
+		role = RoleParticipant
+	}
+	conf.mux.Lock()
+	conf.participants[channelID] = &Participant{ChannelID: channelID, Role: role, Muted: role == RoleListener}
+	conf.mux.Unlock()
+	defer func() {
+		if err != nil {
+			conf.mux.Lock()
+			delete(conf.participants, channelID)
+			conf.mux.Unlock()
+		}
+	}()
+	if role == RoleListener {
+		err = conf.ari.Mute(channelID, muteDirectionInward)
+	} else {
+		err = conf.ari.EnableTalkDetection(channelID, 0, 0)
+	}
+	if err != nil {
+		return
+	}
+	return conf.ari.AddChannel(conf.BridgeID, channelID)
+}
+
+// Leave removes the channel out of the conference, leaving it in the Stasis application
+// The mute and the talk detection applied by the conference are removed out of the channel
+func (conf *Conference) Leave(channelID string) (err error) {
+	conf.mux.RLock()
+	part, has := conf.participants[channelID]
+	var role string
+	var muted bool
+	if has {
+		role, muted = part.Role, part.Muted
+	}
+	conf.mux.RUnlock()
+	if !has {
+		return ErrNotParticipant
+	}
+	if err = conf.ari.RemoveChannel(conf.BridgeID, channelID); err != nil {
+		return
+	}
+	if muted {
+		if err = conf.ari.Unmute(channelID, muteDirectionInward); err != nil {
+			return
+		}
+	}
+	if role != RoleListener {
+		err = conf.ari.DisableTalkDetection(channelID)
+	}
+	return
+}
+
+// Kick hangs up the participant
+func (conf *Conference) Kick(channelID string) error {
+	if !conf.has(channelID) {
+		return ErrNotParticipant
+	}
+	return conf.ari.Hangup(channelID, "")
+}
+
+// Mute stops the participant from being heard in the conference
+func (conf *Conference) Mute(channelID string) error {
+	return conf.setMuted(channelID, true)
+}
+
+// Unmute lets the participant be heard again, returns ErrListener for listeners
+func (conf *Conference) Unmute(channelID string) error {
+	return conf.setMuted(channelID, false)
+}
+
+// setMuted changes the mute state of the participant
+// The lock is not held during the REST calls since the event filter (conf.has) needs it, the dispatch of all events would stall
+func (conf *Conference) setMuted(channelID string, muted bool) (err error) {
+	conf.mux.RLock()
+	part, has := conf.participants[channelID]
+	var role string
+	var isMuted bool
+	if has {
+		role, isMuted = part.Role, part.Muted
+	}
+	conf.mux.RUnlock()
+	switch {
+	case !has:
+		return ErrNotParticipant
+	case role == RoleListener && !muted:
+		return ErrListener
+	case isMuted == muted:
+		return
+	}
+	evType := ParticipantMuted
+	if muted {
+		err = conf.ari.Mute(channelID, muteDirectionInward)
+	} else {
+		evType = ParticipantUnmuted
+		err = conf.ari.Unmute(channelID, muteDirectionInward)
+	}
+	if err != nil {
+		return
+	}
+	conf.mux.Lock()
+	defer conf.mux.Unlock()
+	part.Muted = muted
+	conf.emit(&Event{Type: evType, Participant: *part})
+	return
+}
+
+// Participants returns a snapshot of the participants, in the order they joined
+func (conf *Conference) Participants() (parts []Participant) {
+	conf.mux.RLock()
+	defer conf.mux.RUnlock()
+	parts = make([]Participant, 0, len(conf.participants))
+	for _, part := range conf.participants {
+		parts = append(parts, *part)
+	}
+	sort.SliceStable(parts, func(i, j int) bool {
+		if parts[i].JoinedAt.Equal(parts[j].JoinedAt) {
+			return parts[i].ChannelID < parts[j].ChannelID
+		}
+		return parts[i].JoinedAt.Before(parts[j].JoinedAt)
+	})
+	return
+}
+
+// Record starts recording the conference audio
+func (conf *Conference) Record(p aringo.RecordParams) (*aringo.LiveRecording, error) {
+	return conf.ari.RecordBridge(conf.BridgeID, p)
+}
+
+// Close ends the conference by destroying its bridge, the participants are left in the Stasis application
+func (conf *Conference) Close() (err error) {
+	conf.closeOnce.Do(func() {
+		select {
+		case <-conf.done: // ended already
+			return
+		default:
+		}
+		err = conf.ari.DestroyBridge(conf.BridgeID)
+		close(conf.closed)
+	})
+	return
+}
+
+// has checks if the channel joined the conference
+func (conf *Conference) has(channelID string) bool {
+	conf.mux.RLock()
+	defer conf.mux.RUnlock()
+	_, has := conf.participants[channelID]
+	return has
+}
+
+// emit posts one event without blocking
+func (conf *Conference) emit(ev *Event) {
+	select {
+	case conf.events <- ev:
+	default:
+	}
+}
+
+// run handles the ARI events until the conference ends
+func (conf *Conference) run() {
+	defer close(conf.done)
+	defer close(conf.events)
+	for ended := false; !ended; {
+		select {
+		case ev, ok := <-conf.sub.Events():
+			if !ok { // overflown, the changes lost meanwhile are found out of the bridge
+				conf.sub = conf.subscribe()
+				ended = !conf.resync()
+				continue
+			}
+			ended = !conf.handle(ev)
+		case <-conf.closed:
+			ended = true
+		}
+	}
+	conf.sub.Close()
+	conf.emit(&Event{Type: ConferenceEnded})
+}
+
+// resync applies the membership changes lost while the subscription was overflown, returns false once the conference ended
+func (conf *Conference) resync() bool {
+	br, err := conf.ari.GetBridge(conf.BridgeID)
+	if aringo.IsReplyCode(err, http.StatusNotFound) { // destroyed meanwhile
+		return false
+	}
+	if err != nil { // membership kept as known
+		return true
+	}
+	inBridge := make(map[string]bool, len(br.Channels))
+	for _, channelID := range br.Channels {
+		inBridge[channelID] = true
+	}
+	var gone, added []string
+	conf.mux.RLock()
+	for channelID := range conf.participants {
+		if !inBridge[channelID] {
+			gone = append(gone, channelID)
+		}
+	}
+	for _, channelID := range br.Channels {
+		if _, has := conf.participants[channelID]; !has {
+			added = append(added, channelID)
+		}
+	}
+	conf.mux.RUnlock()
+	for _, channelID := range gone {
+		if !conf.left(channelID) {
+			return false
+		}
+	}
+	for _, channelID := range added {
+		conf.joined(channelID)
+	}
+	return true
+}
+
+// handle processes one ARI event, returns false once the conference ended
+func (conf *Conference) handle(ev *aringo.Event) bool {
+	switch ev.Type {
+	case "ChannelEnteredBridge":
+		var entered aringo.BridgeChannelEvent
+		if ev.Decode(&entered) == nil {
+			conf.joined(entered.Channel.ID)
+		}
+	case "ChannelLeftBridge":
+		var left aringo.BridgeChannelEvent
+		if ev.Decode(&left) == nil {
+			return conf.left(left.Channel.ID)
+		}
+	case "ChannelTalkingStarted", "ChannelTalkingFinished":
+		var talking aringo.ChannelTalking
+		if ev.Decode(&talking) == nil {
+			conf.talking(talking.Channel.ID, ev.Type == "ChannelTalkingStarted", talking.Duration)
+		}
+	case "BridgeDestroyed":
+		return false
+	}
+	return true
+}
+
+// joined processes a channel entering the bridge, also the ones added without Join
+// The event is posted after the announcements so the REST calls are done once it is received
+func (conf *Conference) joined(channelID string) {
+	conf.mux.Lock()
+	part, has := conf.participants[channelID]
+	if !has {
+		part = &Participant{ChannelID: channelID, Role: RoleParticipant}
+		conf.participants[channelID] = part
+	}
+	part.JoinedAt = time.Now()
+	ev := &Event{Type: ParticipantJoined, Participant: *part}
+	hold := conf.holdChange()
+	conf.mux.Unlock()
+	conf.applyHold(hold)
+	if conf.cfg.JoinSound != "" {
+		conf.ari.PlayOnBridge(conf.BridgeID, conf.cfg.JoinSound)
+	}
+	conf.emit(ev)
+}
+
+// left processes a channel leaving the bridge, returns false if the conference ended with it
+func (conf *Conference) left(channelID string) bool {
+	conf.mux.Lock()
+	part, has := conf.participants[channelID]
+	if !has {
+		conf.mux.Unlock()
+		return true
+	}
+	delete(conf.participants, channelID)
+	ev := &Event{Type: ParticipantLeft, Participant: *part}
+	ended := conf.cfg.EndWithModerator && part.Role == RoleModerator && !conf.hasModerator()
+	hold := conf.holdChange()
+	remaining := len(conf.participants)
+	conf.mux.Unlock()
+	defer conf.emit(ev)
+	if ended {
+		conf.ari.DestroyBridge(conf.BridgeID)
+		return false
+	}
+	conf.applyHold(hold)
+	if conf.cfg.LeaveSound != "" && remaining != 0 {
+		conf.ari.PlayOnBridge(conf.BridgeID, conf.cfg.LeaveSound)
+	}
+	return true
+}
+
+func (conf *Conference) talking(channelID string, started bool, durationMs int) {
+	conf.mux.Lock()
+	defer conf.mux.Unlock()
+	part, has := conf.participants[channelID]
+	if !has {
+		return
+	}
+	part.Talking = started
+	ev := &Event{Type: TalkingStarted, Participant: *part}
+	if !started {
+		ev.Type = TalkingFinished
+		ev.Duration = time.Duration(durationMs) * time.Millisecond
+	}
+	conf.emit(ev)
+}
+
+// hasModerator checks for moderators among the participants, called with the lock held
+func (conf *Conference) hasModerator() bool {
+	for _, part := range conf.participants {
+		if part.Role == RoleModerator {
+			return true
+		}
+	}
+	return false
+}
+
+// holdChange updates the waiting for moderator state, called with the lock held
+// Returns 1 if the music on hold should start, -1 if it should stop and 0 for no change
+func (conf *Conference) holdChange() int {
+	if !conf.cfg.WaitForModerator {
+		return 0
+	}
+	onHold := len(conf.participants) != 0 && !conf.hasModerator()
+	if onHold == conf.onHold {
+		return 0
+	}
+	conf.onHold = onHold
+	if onHold {
+		return 1
+	}
+	return -1
+}
+
+func (conf *Conference) applyHold(change int) {
+	switch change {
+	case 1:
+		conf.ari.StartBridgeMOH(conf.BridgeID, "")
+	case -1:
+		conf.ari.StopBridgeMOH(conf.BridgeID)
+	}
+}
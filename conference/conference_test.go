@@ -0,0 +1,306 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package conference
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cgrates/aringo"
+	"github.com/cgrates/aringo/aringotest"
+)
+
+// fakeBridge answers the REST calls of the conference, logging them
+type fakeBridge struct {
+	ari       *aringo.ARInGO
+	mux       sync.Mutex
+	reqs      []string
+	channels  []string // inside the bridge
+	destroyed bool
+}
+
+func newFakeBridge(t *testing.T) (fb *fakeBridge) {
+	fb = new(fakeBridge)
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		req := r.Method + " " + r.URL.Path
+		if r.URL.RawQuery != "" {
+			req += "?" + r.URL.RawQuery
+		}
+		fb.mux.Lock()
+		fb.reqs = append(fb.reqs, req)
+		channels, destroyed := fb.channels, fb.destroyed
+		fb.mux.Unlock()
+		switch r.URL.Path {
+		case "/ari/bridges/conf1":
+			if r.Method == http.MethodGet && destroyed {
+				rw.WriteHeader(http.StatusNotFound)
+				rw.Write([]byte(`{"message":"Bridge not found"}`))
+				return
+			}
+			if r.Method == http.MethodGet {
+				json.NewEncoder(rw).Encode(aringo.Bridge{ID: "conf1", BridgeType: "mixing", Channels: channels})
+				return
+			}
+			rw.Write([]byte(`{"id":"conf1","bridge_type":"mixing"}`))
+		case "/ari/bridges/conf1/record":
+			rw.Write([]byte(`{"name":"conf1","state":"queued"}`))
+		default:
+			if strings.HasPrefix(r.URL.Path, "/ari/bridges/conf1/play/") {
+				rw.Write([]byte(`{"id":"` + path.Base(r.URL.Path) + `","state":"playing"}`))
+				return
+			}
+			rw.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	var lastID int
+	fb.ari = aringo.NewReplayARInGO(nil, aringo.WithRESTURL(srv.URL+"/ari"),
+		aringo.WithIDGenerator(func() string {
+			lastID++
+			return "pb" + strconv.Itoa(lastID)
+		}))
+	return
+}
+
+func (fb *fakeBridge) requests() []string {
+	fb.mux.Lock()
+	defer fb.mux.Unlock()
+	return append([]string(nil), fb.reqs...)
+}
+
+func entered(channelID string) string {
+	return `{"type":"ChannelEnteredBridge","bridge":{"id":"conf1"},"channel":{"id":"` + channelID + `"}}`
+}
+
+func left(channelID string) string {
+	return `{"type":"ChannelLeftBridge","bridge":{"id":"conf1"},"channel":{"id":"` + channelID + `"}}`
+}
+
+// nextEvents reads the next events of the conference
+func nextEvents(t *testing.T, conf *Conference, n int) (evs []Event) {
+	t.Helper()
+	for len(evs) < n {
+		select {
+		case ev, ok := <-conf.Events():
+			if !ok {
+				t.Fatalf("events closed after: %+v", evs)
+			}
+			ev.Participant.JoinedAt = time.Time{}
+			evs = append(evs, *ev)
+		case <-time.After(time.Second):
+			t.Fatalf("events missing after: %+v", evs)
+		}
+	}
+	return
+}
+
+func waitEnded(t *testing.T, conf *Conference) {
+	t.Helper()
+	select {
+	case <-conf.Done():
+	case <-time.After(time.Second):
+		t.Fatal("conference not ended")
+	}
+}
+
+func TestConference(t *testing.T) {
+	fb := newFakeBridge(t)
+	conf, err := New(fb.ari, Config{BridgeID: "conf1", Name: "sales", JoinSound: "sound:beep", EndWithModerator: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = conf.Join("ch1", RoleModerator); err != nil {
+		t.Fatal(err)
+	}
+	if err = conf.Join("ch2", RoleListener); err != nil {
+		t.Fatal(err)
+	}
+	aringotest.Inject(fb.ari, entered("ch1"), entered("ch2"))
+	exp := []Event{
+		{Type: ParticipantJoined, Participant: Participant{ChannelID: "ch1", Role: RoleModerator}},
+		{Type: ParticipantJoined, Participant: Participant{ChannelID: "ch2", Role: RoleListener, Muted: true}},
+	}
+	if rcv := nextEvents(t, conf, 2); !reflect.DeepEqual(exp, rcv) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp, rcv)
+	}
+	if parts := conf.Participants(); len(parts) != 2 || parts[0].ChannelID != "ch1" || parts[1].ChannelID != "ch2" {
+		t.Errorf("Unexpected participants: %+v", parts)
+	}
+	if err = conf.Unmute("ch2"); err != ErrListener {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", ErrListener, err)
+	}
+	if err = conf.Mute("ch3"); err != ErrNotParticipant {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", ErrNotParticipant, err)
+	}
+	if err = conf.Mute("ch1"); err != nil {
+		t.Error(err)
+	}
+	if err = conf.Mute("ch1"); err != nil { // muted already
+		t.Error(err)
+	}
+	if err = conf.Unmute("ch1"); err != nil {
+		t.Error(err)
+	}
+	if _, err = conf.Record(aringo.RecordParams{Name: "conf1", Format: "wav"}); err != nil {
+		t.Error(err)
+	}
+	aringotest.Inject(fb.ari, `{"type":"ChannelTalkingStarted","channel":{"id":"ch1"}}`,
+		`{"type":"ChannelTalkingFinished","duration":1500,"channel":{"id":"ch1"}}`,
+		`{"type":"ChannelTalkingStarted","channel":{"id":"other"}}`)
+	exp = []Event{
+		{Type: ParticipantMuted, Participant: Participant{ChannelID: "ch1", Role: RoleModerator, Muted: true}},
+		{Type: ParticipantUnmuted, Participant: Participant{ChannelID: "ch1", Role: RoleModerator}},
+		{Type: TalkingStarted, Participant: Participant{ChannelID: "ch1", Role: RoleModerator, Talking: true}},
+		{Type: TalkingFinished, Participant: Participant{ChannelID: "ch1", Role: RoleModerator}, Duration: 1500 * time.Millisecond},
+	}
+	if rcv := nextEvents(t, conf, 4); !reflect.DeepEqual(exp, rcv) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp, rcv)
+	}
+	if err = conf.Leave("ch2"); err != nil {
+		t.Error(err)
+	}
+	if err = conf.Leave("ch1"); err != nil {
+		t.Error(err)
+	}
+	aringotest.Inject(fb.ari, left("ch2"), left("ch1"))
+	exp = []Event{
+		{Type: ParticipantLeft, Participant: Participant{ChannelID: "ch2", Role: RoleListener, Muted: true}},
+		{Type: ParticipantLeft, Participant: Participant{ChannelID: "ch1", Role: RoleModerator}},
+		{Type: ConferenceEnded},
+	}
+	if rcv := nextEvents(t, conf, 3); !reflect.DeepEqual(exp, rcv) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp, rcv)
+	}
+	waitEnded(t, conf)
+	if err = conf.Join("ch3", ""); err != ErrEnded {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", ErrEnded, err)
+	}
+	if err = conf.Close(); err != nil {
+		t.Error(err)
+	}
+	expReqs := []string{
+		"POST /ari/bridges/conf1?name=sales&type=mixing%2Cdtmf_events",
+		"POST /ari/channels/ch1/variable?value=&variable=TALK_DETECT%28set%29",
+		"POST /ari/bridges/conf1/addChannel?channel=ch1",
+		"POST /ari/channels/ch2/mute?direction=in",
+		"POST /ari/bridges/conf1/addChannel?channel=ch2",
+		"POST /ari/bridges/conf1/play/pb1?media=sound%3Abeep",
+		"POST /ari/bridges/conf1/play/pb2?media=sound%3Abeep",
+		"POST /ari/channels/ch1/mute?direction=in",
+		"DELETE /ari/channels/ch1/mute?direction=in",
+		"POST /ari/bridges/conf1/record?format=wav&name=conf1",
+		"POST /ari/bridges/conf1/removeChannel?channel=ch2",
+		"DELETE /ari/channels/ch2/mute?direction=in",
+		"POST /ari/bridges/conf1/removeChannel?channel=ch1",
+		"POST /ari/channels/ch1/variable?value=&variable=TALK_DETECT%28remove%29",
+		"DELETE /ari/bridges/conf1",
+	}
+	if rcv := fb.requests(); !reflect.DeepEqual(expReqs, rcv) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", expReqs, rcv)
+	}
+}
+
+func TestConferenceWaitForModerator(t *testing.T) {
+	fb := newFakeBridge(t)
+	conf, err := New(fb.ari, Config{BridgeID: "conf1", LeaveSound: "sound:leave", WaitForModerator: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	aringotest.Inject(fb.ari, entered("ch1")) // added to the bridge outside of Join
+	nextEvents(t, conf, 1)
+	if err = conf.Join("ch2", RoleModerator); err != nil {
+		t.Fatal(err)
+	}
+	aringotest.Inject(fb.ari, entered("ch2"))
+	nextEvents(t, conf, 1)
+	if err = conf.Kick("ch2"); err != nil {
+		t.Error(err)
+	}
+	aringotest.Inject(fb.ari, left("ch2"))
+	exp := []Event{{Type: ParticipantLeft, Participant: Participant{ChannelID: "ch2", Role: RoleModerator}}}
+	if rcv := nextEvents(t, conf, 1); !reflect.DeepEqual(exp, rcv) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp, rcv)
+	}
+	if err = conf.Close(); err != nil {
+		t.Error(err)
+	}
+	waitEnded(t, conf)
+	expReqs := []string{
+		"POST /ari/bridges/conf1?type=mixing%2Cdtmf_events",
+		"POST /ari/bridges/conf1/moh",
+		"POST /ari/channels/ch2/variable?value=&variable=TALK_DETECT%28set%29",
+		"POST /ari/bridges/conf1/addChannel?channel=ch2",
+		"DELETE /ari/bridges/conf1/moh",
+		"DELETE /ari/channels/ch2",
+		"POST /ari/bridges/conf1/moh",
+		"POST /ari/bridges/conf1/play/pb1?media=sound%3Aleave",
+		"DELETE /ari/bridges/conf1",
+	}
+	if rcv := fb.requests(); !reflect.DeepEqual(expReqs, rcv) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", expReqs, rcv)
+	}
+}
+
+func TestConferenceResync(t *testing.T) {
+	fb := newFakeBridge(t)
+	conf, err := New(fb.ari, Config{BridgeID: "conf1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	aringotest.Inject(fb.ari, entered("ch1"), entered("ch2"))
+	nextEvents(t, conf, 2)
+	// the subscription ends while ch1 leaves and ch3 enters, the changes are found out of the bridge
+	fb.mux.Lock()
+	fb.channels = []string{"ch2", "ch3"}
+	fb.mux.Unlock()
+	conf.sub.Close()
+	exp := []Event{
+		{Type: ParticipantLeft, Participant: Participant{ChannelID: "ch1", Role: RoleParticipant}},
+		{Type: ParticipantJoined, Participant: Participant{ChannelID: "ch3", Role: RoleParticipant}},
+	}
+	if rcv := nextEvents(t, conf, 2); !reflect.DeepEqual(exp, rcv) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp, rcv)
+	}
+	if parts := conf.Participants(); len(parts) != 2 || parts[0].ChannelID != "ch2" || parts[1].ChannelID != "ch3" {
+		t.Errorf("Unexpected participants: %+v", parts)
+	}
+	// the bridge destroyed while the subscription ends again, the conference ends
+	fb.mux.Lock()
+	fb.destroyed = true
+	fb.mux.Unlock()
+	conf.sub.Close()
+	waitEnded(t, conf)
+}
+
+func TestConferenceBridgeDestroyed(t *testing.T) {
+	fb := newFakeBridge(t)
+	conf, err := New(fb.ari, Config{BridgeID: "conf1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	aringotest.Inject(fb.ari, `{"type":"BridgeDestroyed","bridge":{"id":"conf1"}}`)
+	exp := []Event{{Type: ConferenceEnded}}
+	if rcv := nextEvents(t, conf, 1); !reflect.DeepEqual(exp, rcv) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp, rcv)
+	}
+	waitEnded(t, conf)
+	if err = conf.Close(); err != nil {
+		t.Error(err)
+	}
+	if err = conf.Kick("ch1"); err != ErrNotParticipant {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", ErrNotParticipant, err)
+	}
+}
@@ -0,0 +1,335 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package aringo
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// dial statuses, as reported inside the Dial events
+const (
+	DialStatusRinging     = "RINGING"
+	DialStatusProgress    = "PROGRESS"
+	DialStatusAnswer      = "ANSWER"
+	DialStatusBusy        = "BUSY"
+	DialStatusNoAnswer    = "NOANSWER"
+	DialStatusCongestion  = "CONGESTION"
+	DialStatusChanUnavail = "CHANUNAVAIL"
+	DialStatusCancel      = "CANCEL"
+)
+
+const dialProgressBuffer = 64 // progress updates queued before dropping
+
+var ErrNoEndpoints = errors.New("NO_ENDPOINTS")
+
+// DialEvent is posted by Asterisk on each change of a dial
+type DialEvent struct {
+	Caller     *Channel `json:"caller,omitempty"`
+	Peer       Channel  `json:"peer"`
+	DialStatus string   `json:"dialstatus"` // empty when the dial starts
+	DialString string   `json:"dialstring"`
+	Forward    string   `json:"forward"`
+}
+
+// DialParams are the parameters of Dial
+type DialParams struct {
+	Endpoints  []string         // dialed in parallel, the first answering wins and the others are hung up
+	Inbound    string           // channel bridged with the answered leg, optional
+	App        string           // Stasis application of the outbound channels, the first one of the connection if empty
+	Formats    string           // formats of the outbound channels, the ones of Inbound if empty
+	Timeout    time.Duration    // ringing time before giving up with NOANSWER, no limit if 0
+	EarlyMedia bool             // bridges Inbound with the first leg reporting progress, before answering it
+	Variables  ChannelVariables // set on each outbound channel, ie: SIP headers with AddSIPHeader
+}
+
+// DialProgress is one status change of a dialed leg
+type DialProgress struct {
+	Endpoint  string
+	ChannelID string
+	Status    string
+}
+
+// DialResult is the outcome of a dial
+type DialResult struct {
+	Status    string // DialStatusAnswer or the status of the last leg failing
+	Endpoint  string // of the answered leg
+	ChannelID string // of the answered leg
+	BridgeID  string // bridge of Inbound and the answered leg
+}
+
+// dialLeg is one dialed endpoint
+type dialLeg struct {
+	endpoint  string
+	channelID string
+	status    string
+	ended     bool
+}
+
+// DialSession is a dial in progress, created with Dial
+type DialSession struct {
+	ari      *ARInGO
+	p        DialParams
+	legs     []*dialLeg
+	legIDs   map[string]*dialLeg // read by the subscription filter, not changed after Dial
+	sub      *Subscription
+	cancel   context.CancelFunc
+	progress chan *DialProgress
+	done     chan struct{}
+
+	bridgeID  string   // created for the early media or on answer
+	earlyLeg  *dialLeg // bridged for the early media
+	lastFinal string   // status of the last leg failing
+	res       *DialResult
+	err       error
+}
+
+// Dial creates and dials the outbound legs towards the endpoints
+// Follow the dial through Progress and collect its outcome with Wait
+func (ari *ARInGO) Dial(ctx context.Context, p DialParams) (ds *DialSession, err error) {
+	if len(p.Endpoints) == 0 {
+		return nil, ErrNoEndpoints
+	}
+	if p.App, err = ari.defaultApp(p.App); err != nil {
+		return nil, err
+	}
+	ds = &DialSession{
+		ari:      ari,
+		p:        p,
+		legIDs:   make(map[string]*dialLeg),
+		progress: make(chan *DialProgress, dialProgressBuffer),
+		done:     make(chan struct{}),
+	}
+	for _, endpoint := range p.Endpoints {
+		leg := &dialLeg{endpoint: endpoint, channelID: ari.NewID()}
+		ds.legs = append(ds.legs, leg)
+		ds.legIDs[leg.channelID] = leg
+	}
+	ds.sub = ari.Subscribe(ds.filter) // before dialing so no event is missed
+	if p.Timeout != 0 {
+		ctx, ds.cancel = context.WithTimeout(ctx, p.Timeout)
+	} else {
+		ctx, ds.cancel = context.WithCancel(ctx)
+	}
+	for _, leg := range ds.legs {
+		if errLeg := ds.startLeg(leg); errLeg != nil {
+			ari.log().Warn("ARI dial failed", "endpoint", leg.endpoint, "error", errLeg)
+			ds.legFailed(leg, DialStatusChanUnavail)
+		}
+	}
+	go ds.run(ctx)
+	return
+}
+
+// Progress returns the channel where the status changes of the legs are posted, closed once the dial ends
+// Updates are dropped if not consumed in time
+func (ds *DialSession) Progress() <-chan *DialProgress {
+	return ds.progress
+}
+
+// Done is closed once the dial ends
+func (ds *DialSession) Done() <-chan struct{} {
+	return ds.done
+}
+
+// Wait blocks until the dial ends and returns its outcome
+// The error reports failures to bridge the answered leg or the lost events, the result is populated also then
+func (ds *DialSession) Wait() (*DialResult, error) {
+	<-ds.done
+	return ds.res, ds.err
+}
+
+// Cancel hangs up the legs not answered yet, ending the dial with CANCEL
+func (ds *DialSession) Cancel() {
+	ds.cancel()
+}
+
+// filter matches the events of the legs and the hangup of the inbound channel
+func (ds *DialSession) filter(ev *Event) bool {
+	switch ev.Type {
+	case "Dial":
+		return ds.legIDs[ev.objectID("peer")] != nil
+	case "StasisEnd", "ChannelDestroyed":
+		channelID := ev.ChannelID()
+		return ds.legIDs[channelID] != nil || (ds.p.Inbound != "" && channelID == ds.p.Inbound)
+	}
+	return false
+}
+
+// startLeg creates and dials one leg
+func (ds *DialSession) startLeg(leg *dialLeg) (err error) {
+	if _, err = ds.ari.CreateChannel(OriginateParams{
+		Endpoint:   leg.endpoint,
+		App:        ds.p.App,
+		ChannelID:  leg.channelID,
+		Originator: ds.p.Inbound,
+		Formats:    ds.p.Formats,
+		Variables:  ds.p.Variables,
+	}); err != nil {
+		return
+	}
+	if err = ds.ari.DialChannel(leg.channelID, ds.p.Inbound, 0); err != nil {
+		ds.ari.Hangup(leg.channelID, "")
+	}
+	return
+}
+
+// post publishes the status of one leg without blocking
+func (ds *DialSession) post(leg *dialLeg) {
+	select {
+	case ds.progress <- &DialProgress{Endpoint: leg.endpoint, ChannelID: leg.channelID, Status: leg.status}:
+	default:
+	}
+}
+
+// run follows the dial events until one leg answers, all fail or the dial is cancelled
+func (ds *DialSession) run(ctx context.Context) {
+	defer ds.cancel()
+	defer ds.sub.Close()
+	for !ds.allFailed() {
+		select {
+		case ev, ok := <-ds.sub.Events():
+			if !ok { // events lost, the outcome cannot be followed anymore
+				ds.hangupLegs(nil, DialStatusCancel)
+				ds.err = ds.sub.Err()
+				ds.fail(DialStatusCancel)
+				return
+			}
+			if ds.handle(ev) {
+				return
+			}
+		case <-ctx.Done():
+			status := DialStatusCancel
+			if ctx.Err() == context.DeadlineExceeded {
+				status = DialStatusNoAnswer
+			}
+			ds.hangupLegs(nil, status)
+			ds.fail(status)
+			return
+		}
+	}
+	ds.fail(ds.lastFinal)
+}
+
+// handle processes one event, returns true once the dial ended
+func (ds *DialSession) handle(ev *Event) bool {
+	if ev.Type != "Dial" {
+		channelID := ev.ChannelID()
+		if channelID == ds.p.Inbound { // nobody to connect anymore
+			ds.hangupLegs(nil, DialStatusCancel)
+			ds.fail(DialStatusCancel)
+			return true
+		}
+		if leg := ds.legIDs[channelID]; !leg.ended {
+			ds.legFailed(leg, DialStatusChanUnavail) // hung up without final status
+		}
+		return false
+	}
+	var dial DialEvent
+	if ev.Decode(&dial) != nil || dial.DialStatus == "" {
+		return false
+	}
+	leg := ds.legIDs[dial.Peer.ID]
+	if leg.ended {
+		return false
+	}
+	switch dial.DialStatus {
+	case DialStatusRinging:
+		leg.status = dial.DialStatus
+		ds.post(leg)
+	case DialStatusProgress:
+		leg.status = dial.DialStatus
+		ds.post(leg)
+		if ds.p.EarlyMedia && ds.p.Inbound != "" && ds.earlyLeg == nil {
+			if err := ds.bridge(leg); err != nil {
+				ds.ari.log().Warn("ARI early media bridging failed", "channel", leg.channelID, "error", err)
+			} else {
+				ds.earlyLeg = leg
+			}
+		}
+	case DialStatusAnswer:
+		leg.status = dial.DialStatus
+		ds.post(leg)
+		ds.answered(leg)
+		return true
+	default:
+		ds.legFailed(leg, dial.DialStatus)
+	}
+	return false
+}
+
+// legFailed marks the leg as ended with the final status
+func (ds *DialSession) legFailed(leg *dialLeg, status string) {
+	leg.status = status
+	leg.ended = true
+	ds.lastFinal = status
+	ds.post(leg)
+	if ds.earlyLeg == leg {
+		ds.earlyLeg = nil
+	}
+}
+
+func (ds *DialSession) allFailed() bool {
+	for _, leg := range ds.legs {
+		if !leg.ended {
+			return false
+		}
+	}
+	return true
+}
+
+// hangupLegs hangs up the legs still dialing, except the one given
+func (ds *DialSession) hangupLegs(except *dialLeg, status string) {
+	for _, leg := range ds.legs {
+		if leg != except && !leg.ended {
+			ds.ari.Hangup(leg.channelID, "")
+			ds.legFailed(leg, status)
+		}
+	}
+}
+
+// bridge connects the leg with the inbound channel, creating the bridge on first use
+func (ds *DialSession) bridge(leg *dialLeg) (err error) {
+	if ds.bridgeID == "" {
+		var br *Bridge
+		if br, err = ds.ari.CreateBridge(BridgeParams{Type: "mixing"}); err != nil {
+			return
+		}
+		ds.bridgeID = br.ID
+		return ds.ari.AddChannel(ds.bridgeID, ds.p.Inbound, leg.channelID)
+	}
+	return ds.ari.AddChannel(ds.bridgeID, leg.channelID)
+}
+
+// answered ends the dial with the leg connected to the inbound channel
+func (ds *DialSession) answered(leg *dialLeg) {
+	ds.hangupLegs(leg, DialStatusCancel)
+	ds.res = &DialResult{Status: DialStatusAnswer, Endpoint: leg.endpoint, ChannelID: leg.channelID}
+	if ds.p.Inbound != "" {
+		if ds.err = ds.ari.Answer(ds.p.Inbound); ds.err == nil && ds.earlyLeg != leg {
+			ds.err = ds.bridge(leg)
+		}
+		ds.res.BridgeID = ds.bridgeID
+	}
+	ds.end()
+}
+
+// fail ends the dial without answer, the early media bridge is destroyed
+func (ds *DialSession) fail(status string) {
+	if ds.bridgeID != "" {
+		ds.ari.DestroyBridge(ds.bridgeID)
+	}
+	ds.res = &DialResult{Status: status}
+	ds.end()
+}
+
+func (ds *DialSession) end() {
+	close(ds.progress)
+	close(ds.done)
+}
@@ -0,0 +1,209 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package aringo
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// dialScripts are the dial statuses sent by the fake for each endpoint
+var dialScripts = map[string][]string{
+	"PJSIP/answer":    {DialStatusRinging, DialStatusProgress, DialStatusAnswer},
+	"PJSIP/busy":      {DialStatusBusy},
+	"PJSIP/congested": {DialStatusCongestion},
+	"PJSIP/ring":      {DialStatusRinging},
+}
+
+// dialFake answers the REST calls of the dials, logging them
+type dialFake struct {
+	*fakeARI
+	endpoints map[string]string // endpoint of each created channel, guarded by reqMux
+}
+
+func newDialFake(t *testing.T) (fake *dialFake, ari *ARInGO) {
+	var lastID int
+	fake = &dialFake{endpoints: make(map[string]string)}
+	fake.fakeARI, ari = newFakeARI(t, WithIDGenerator(func() string {
+		lastID++
+		return "id" + strconv.Itoa(lastID)
+	}))
+	fake.handleREST("/ari/", func(rw http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/ari/channels/create":
+			id := r.URL.Query().Get("channelId")
+			fake.reqMux.Lock()
+			fake.endpoints[id] = r.URL.Query().Get("endpoint")
+			fake.reqMux.Unlock()
+			rw.Write([]byte(`{"id":"` + id + `","state":"Down"}`))
+		case strings.HasSuffix(r.URL.Path, "/dial"):
+			id := strings.Split(r.URL.Path, "/")[3]
+			fake.reqMux.Lock()
+			script := dialScripts[fake.endpoints[id]]
+			fake.reqMux.Unlock()
+			rw.WriteHeader(http.StatusNoContent)
+			fake.send(`{"type":"Dial","peer":{"id":"` + id + `"},"dialstatus":""}`)
+			for _, status := range script {
+				fake.send(`{"type":"Dial","peer":{"id":"` + id + `"},"dialstatus":"` + status + `"}`)
+			}
+		case r.Method == HTTP_POST && strings.HasPrefix(r.URL.Path, "/ari/bridges/") && strings.Count(r.URL.Path, "/") == 3:
+			rw.Write([]byte(`{"id":"` + strings.TrimPrefix(r.URL.Path, "/ari/bridges/") + `"}`))
+		default:
+			rw.WriteHeader(http.StatusNoContent)
+		}
+	})
+	return
+}
+
+// waitDial collects the progress and the outcome of the dial
+func waitDial(t *testing.T, ds *DialSession) (progress []DialProgress, res *DialResult, err error) {
+	t.Helper()
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case prg, ok := <-ds.Progress():
+			if !ok {
+				res, err = ds.Wait()
+				return
+			}
+			progress = append(progress, *prg)
+		case <-timeout:
+			t.Fatal("dial not finished")
+		}
+	}
+}
+
+func TestDialFork(t *testing.T) {
+	fake, ari := newDialFake(t)
+	ds, err := ari.Dial(context.Background(), DialParams{
+		Endpoints: []string{"PJSIP/busy", "PJSIP/ring", "PJSIP/answer"},
+		Inbound:   "in1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	progress, res, err := waitDial(t, ds)
+	if err != nil {
+		t.Error(err)
+	}
+	expRes := &DialResult{Status: DialStatusAnswer, Endpoint: "PJSIP/answer", ChannelID: "id3", BridgeID: "id4"}
+	if !reflect.DeepEqual(expRes, res) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", expRes, res)
+	}
+	expProgress := []DialProgress{
+		{Endpoint: "PJSIP/busy", ChannelID: "id1", Status: DialStatusBusy},
+		{Endpoint: "PJSIP/ring", ChannelID: "id2", Status: DialStatusRinging},
+		{Endpoint: "PJSIP/answer", ChannelID: "id3", Status: DialStatusRinging},
+		{Endpoint: "PJSIP/answer", ChannelID: "id3", Status: DialStatusProgress},
+		{Endpoint: "PJSIP/answer", ChannelID: "id3", Status: DialStatusAnswer},
+		{Endpoint: "PJSIP/ring", ChannelID: "id2", Status: DialStatusCancel},
+	}
+	if !reflect.DeepEqual(expProgress, progress) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", expProgress, progress)
+	}
+	expReqs := []string{
+		"POST /ari/channels/create?app=test&channelId=id1&endpoint=PJSIP%2Fbusy&originator=in1",
+		"POST /ari/channels/id1/dial?caller=in1",
+		"POST /ari/channels/create?app=test&channelId=id2&endpoint=PJSIP%2Fring&originator=in1",
+		"POST /ari/channels/id2/dial?caller=in1",
+		"POST /ari/channels/create?app=test&channelId=id3&endpoint=PJSIP%2Fanswer&originator=in1",
+		"POST /ari/channels/id3/dial?caller=in1",
+		"DELETE /ari/channels/id2",
+		"POST /ari/channels/in1/answer",
+		"POST /ari/bridges/id4?type=mixing",
+		"POST /ari/bridges/id4/addChannel?channel=in1%2Cid3",
+	}
+	if rcv := fake.requests(); !reflect.DeepEqual(expReqs, rcv) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", expReqs, rcv)
+	}
+}
+
+func TestDialEarlyMedia(t *testing.T) {
+	fake, ari := newDialFake(t)
+	ds, err := ari.Dial(context.Background(), DialParams{Endpoints: []string{"PJSIP/answer"}, Inbound: "in1", EarlyMedia: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, res, err := waitDial(t, ds); err != nil {
+		t.Error(err)
+	} else if res.Status != DialStatusAnswer || res.BridgeID != "id2" {
+		t.Errorf("Unexpected result: %+v", res)
+	}
+	expReqs := []string{
+		"POST /ari/channels/create?app=test&channelId=id1&endpoint=PJSIP%2Fanswer&originator=in1",
+		"POST /ari/channels/id1/dial?caller=in1",
+		"POST /ari/bridges/id2?type=mixing",
+		"POST /ari/bridges/id2/addChannel?channel=in1%2Cid1",
+		"POST /ari/channels/in1/answer",
+	}
+	if rcv := fake.requests(); !reflect.DeepEqual(expReqs, rcv) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", expReqs, rcv)
+	}
+}
+
+func TestDialFailures(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		p         DialParams
+		cancel    bool   // cancels the dial once started
+		event     string // sent once the dial started
+		expStatus string
+		expHangup bool
+	}{
+		{name: "allfailed", p: DialParams{Endpoints: []string{"PJSIP/busy", "PJSIP/congested"}}, expStatus: DialStatusCongestion},
+		{name: "timeout", p: DialParams{Endpoints: []string{"PJSIP/ring"}, Timeout: 50 * time.Millisecond},
+			expStatus: DialStatusNoAnswer, expHangup: true},
+		{name: "cancel", p: DialParams{Endpoints: []string{"PJSIP/ring"}}, cancel: true,
+			expStatus: DialStatusCancel, expHangup: true},
+		{name: "inboundhangup", p: DialParams{Endpoints: []string{"PJSIP/ring"}, Inbound: "in1"},
+			event: `{"type":"StasisEnd","channel":{"id":"in1"}}`, expStatus: DialStatusCancel, expHangup: true},
+		{name: "leghangup", p: DialParams{Endpoints: []string{"PJSIP/ring"}},
+			event: `{"type":"ChannelDestroyed","channel":{"id":"id1"}}`, expStatus: DialStatusChanUnavail},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fake, ari := newDialFake(t)
+			ds, err := ari.Dial(context.Background(), tc.p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.cancel {
+				ds.Cancel()
+			}
+			if tc.event != "" {
+				fake.send(tc.event)
+			}
+			if _, res, err := waitDial(t, ds); err != nil {
+				t.Error(err)
+			} else if res.Status != tc.expStatus {
+				t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", tc.expStatus, res.Status)
+			}
+			var hungup bool
+			for _, req := range fake.requests() {
+				hungup = hungup || req == "DELETE /ari/channels/id1"
+			}
+			if hungup != tc.expHangup {
+				t.Errorf("Unexpected requests: %+v", fake.requests())
+			}
+		})
+	}
+}
+
+func TestDialParamsErrors(t *testing.T) {
+	ari := NewReplayARInGO(nil)
+	if _, err := ari.Dial(context.Background(), DialParams{}); err != ErrNoEndpoints {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", ErrNoEndpoints, err)
+	}
+	if _, err := ari.Dial(context.Background(), DialParams{Endpoints: []string{"PJSIP/1001"}}); err != ErrMissingApplication {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", ErrMissingApplication, err)
+	}
+}
@@ -0,0 +1,272 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package aringo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// struct tags used by the typed helpers
+const (
+	varTag = "var" // channel variable or dialplan function of the field, ie: `var:"CALLERID(num)"`
+	argTag = "arg" // Stasis argument of the field, by position (ie: `arg:"0"`) or by key of key=value arguments
+)
+
+var (
+	ErrNotStructPointer    = errors.New("NOT_STRUCT_POINTER")
+	ErrUnsupportedVarField = errors.New("UNSUPPORTED_FIELD_TYPE")
+)
+
+// variable is the reply of the variable queries
+type variable struct {
+	Value string `json:"value"`
+}
+
+// GetChannelVar returns the value of one variable, or dialplan function (ie: PJSIP_HEADER(read,X-Foo)), of the channel
+func (ari *ARInGO) GetChannelVar(channelID, variableName string) (value string, err error) {
+	var v variable
+	if err = ari.callDecode(context.Background(), HTTP_GET, ari.resourceURL("channels", channelID, "variable"),
+		url.Values{"variable": {variableName}}, &v); err != nil {
+		return
+	}
+	return v.Value, nil
+}
+
+// GetGlobalVar returns the value of one global variable
+func (ari *ARInGO) GetGlobalVar(variableName string) (value string, err error) {
+	var v variable
+	if err = ari.callDecode(context.Background(), HTTP_GET, ari.resourceURL("asterisk", "variable"),
+		url.Values{"variable": {variableName}}, &v); err != nil {
+		return
+	}
+	return v.Value, nil
+}
+
+// SetGlobalVar sets one global variable
+func (ari *ARInGO) SetGlobalVar(variableName, value string) error {
+	return ari.callDecode(context.Background(), HTTP_POST, ari.resourceURL("asterisk", "variable"),
+		url.Values{"variable": {variableName}, "value": {value}}, nil)
+}
+
+// GetChannelVars populates the fields of the struct pointed by v out of the channel variables named by their var tags
+// Fields tagged with omitempty (ie: `var:"X-Foo,omitempty"`) are left unchanged if the variable is not set
+func (ari *ARInGO) GetChannelVars(channelID string, v interface{}) (err error) {
+	var fields []taggedField
+	if fields, err = structFields(v, varTag); err != nil {
+		return
+	}
+	for _, fld := range fields {
+		var value string
+		if value, err = ari.GetChannelVar(channelID, fld.name); err != nil {
+			if fld.omitEmpty && IsReplyCode(err, http.StatusNotFound) {
+				err = nil
+				continue
+			}
+			return
+		}
+		if value == "" && fld.omitEmpty {
+			continue
+		}
+		if err = setField(fld.value, value); err != nil {
+			return fmt.Errorf("variable %s: %w", fld.name, err)
+		}
+	}
+	return
+}
+
+// SetChannelVars sets the channel variables named by the var tags of the struct pointed by v
+// Fields tagged with omitempty are not set while holding their zero value
+func (ari *ARInGO) SetChannelVars(channelID string, v interface{}) (err error) {
+	var fields []taggedField
+	if fields, err = structFields(v, varTag); err != nil {
+		return
+	}
+	for _, fld := range fields {
+		if fld.omitEmpty && fld.value.IsZero() {
+			continue
+		}
+		var value string
+		if value, err = formatField(fld.value); err != nil {
+			return fmt.Errorf("variable %s: %w", fld.name, err)
+		}
+		if err = ari.SetChannelVar(channelID, fld.name, value); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// DecodeArgs populates the fields of the struct pointed by v out of the arguments passed to Stasis()
+// The arg tag selects the argument by position (ie: `arg:"0"`) or by key for key=value arguments (ie: `arg:"cgr_reqtype"`)
+// Fields without matching argument are left unchanged
+func (ss *StasisStart) DecodeArgs(v interface{}) (err error) {
+	var fields []taggedField
+	if fields, err = structFields(v, argTag); err != nil {
+		return
+	}
+	keyed := make(map[string]string)
+	for _, arg := range ss.Args {
+		if key, value, has := strings.Cut(arg, "="); has {
+			keyed[key] = value
+		}
+	}
+	for _, fld := range fields {
+		value, has := keyed[fld.name]
+		if idx, errIdx := strconv.Atoi(fld.name); errIdx == nil {
+			if has = idx >= 0 && idx < len(ss.Args); has {
+				value = ss.Args[idx]
+			}
+		}
+		if !has {
+			continue
+		}
+		if err = setField(fld.value, value); err != nil {
+			return fmt.Errorf("argument %s: %w", fld.name, err)
+		}
+	}
+	return
+}
+
+// ContinueParams are the parameters of ContinueInDialplan, the current location is kept for the empty ones
+type ContinueParams struct {
+	Context   string
+	Extension string
+	Priority  int
+	Label     string            // used instead of Priority if set
+	Variables map[string]string // set on the channel, ordered by name, before leaving Stasis
+}
+
+// ContinueInDialplan sets the variables of p on the channel and makes it leave Stasis towards the dialplan
+func (ari *ARInGO) ContinueInDialplan(channelID string, p ContinueParams) (err error) {
+	names := make([]string, 0, len(p.Variables))
+	for name := range p.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err = ari.SetChannelVar(channelID, name, p.Variables[name]); err != nil {
+			return
+		}
+	}
+	params := url.Values{}
+	for key, val := range map[string]string{
+		"context":   p.Context,
+		"extension": p.Extension,
+		"label":     p.Label,
+	} {
+		if val != "" {
+			params.Set(key, val)
+		}
+	}
+	if p.Priority != 0 && p.Label == "" {
+		params.Set("priority", strconv.Itoa(p.Priority))
+	}
+	return ari.callDecode(context.Background(), HTTP_POST, ari.resourceURL("channels", channelID, "continue"), params, nil)
+}
+
+// taggedField is one struct field selected by its tag
+type taggedField struct {
+	name      string
+	omitEmpty bool
+	value     reflect.Value
+}
+
+// structFields returns the fields of the struct pointed by v which carry the tag
+func structFields(v interface{}, tag string) (fields []taggedField, err error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return nil, ErrNotStructPointer
+	}
+	rv = rv.Elem()
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		tagVal, has := rt.Field(i).Tag.Lookup(tag)
+		if !has || tagVal == "-" || !rt.Field(i).IsExported() {
+			continue
+		}
+		name, omitEmpty := strings.CutSuffix(tagVal, ",omitempty") // dialplan functions contain commas themselves
+		fields = append(fields, taggedField{name: name, omitEmpty: omitEmpty, value: rv.Field(i)})
+	}
+	return
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setField parses the string into the field, by its type
+func setField(fv reflect.Value, s string) (err error) {
+	if fv.Type() == durationType {
+		var d time.Duration
+		if d, err = time.ParseDuration(s); err != nil {
+			return
+		}
+		fv.SetInt(int64(d))
+		return
+	}
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(s)
+	case reflect.Bool:
+		var b bool
+		if b, err = strconv.ParseBool(s); err != nil {
+			return
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		if i, err = strconv.ParseInt(s, 10, fv.Type().Bits()); err != nil {
+			return
+		}
+		fv.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var u uint64
+		if u, err = strconv.ParseUint(s, 10, fv.Type().Bits()); err != nil {
+			return
+		}
+		fv.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		if f, err = strconv.ParseFloat(s, fv.Type().Bits()); err != nil {
+			return
+		}
+		fv.SetFloat(f)
+	default:
+		return ErrUnsupportedVarField
+	}
+	return
+}
+
+// formatField returns the string form of the field, as parsed back by setField
+func formatField(fv reflect.Value) (s string, err error) {
+	if fv.Type() == durationType {
+		return time.Duration(fv.Int()).String(), nil
+	}
+	switch fv.Kind() {
+	case reflect.String:
+		s = fv.String()
+	case reflect.Bool:
+		s = strconv.FormatBool(fv.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s = strconv.FormatInt(fv.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s = strconv.FormatUint(fv.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		s = strconv.FormatFloat(fv.Float(), 'f', -1, fv.Type().Bits())
+	default:
+		err = ErrUnsupportedVarField
+	}
+	return
+}
@@ -0,0 +1,183 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package aringo
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newVarsServer serves the channel variables of ch1 and the global ones, logging the changes
+func newVarsServer(t *testing.T, vars map[string]string) (ari *ARInGO, received *[]string) {
+	received = new([]string)
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("variable")
+		switch r.URL.Path {
+		case "/ari/channels/ch1/variable", "/ari/asterisk/variable":
+			if r.Method == HTTP_GET {
+				value, has := vars[name]
+				if !has {
+					rw.WriteHeader(http.StatusNotFound)
+					rw.Write([]byte(`{"message":"Provided variable was not found"}`))
+					return
+				}
+				rw.Write([]byte(`{"value":"` + value + `"}`))
+				return
+			}
+		}
+		*received = append(*received, r.Method+" "+r.URL.RequestURI())
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(srv.Close)
+	return NewRESTARInGO(srv.URL+"/ari", "", "", "aringo"), received
+}
+
+type callVars struct {
+	Number   string        `var:"CALLERID(num)"`
+	Header   string        `var:"PJSIP_HEADER(read,X-Foo),omitempty"`
+	Credit   float64       `var:"CREDIT"`
+	MaxTime  time.Duration `var:"MAX_TIME"`
+	Retries  int           `var:"RETRIES,omitempty"`
+	Priority bool          `var:"PRIORITY"`
+	ignored  string        `var:"IGNORED"`
+	Skipped  string        `var:"-"`
+	Untagged string
+}
+
+func TestGetChannelVars(t *testing.T) {
+	ari, _ := newVarsServer(t, map[string]string{
+		"CALLERID(num)": "1001",
+		"CREDIT":        "12.5",
+		"MAX_TIME":      "1m30s",
+		"RETRIES":       "",
+		"PRIORITY":      "true",
+	})
+	if value, err := ari.GetChannelVar("ch1", "CALLERID(num)"); err != nil {
+		t.Error(err)
+	} else if value != "1001" {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", "1001", value)
+	}
+	vars := callVars{Header: "unchanged", Retries: 3, Untagged: "unchanged"}
+	if err := ari.GetChannelVars("ch1", &vars); err != nil {
+		t.Fatal(err)
+	}
+	exp := callVars{Number: "1001", Header: "unchanged", Credit: 12.5, MaxTime: 90 * time.Second,
+		Retries: 3, Priority: true, Untagged: "unchanged"}
+	if !reflect.DeepEqual(exp, vars) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp, vars)
+	}
+	var missing struct {
+		Missing string `var:"MISSING"`
+	}
+	if err := ari.GetChannelVars("ch1", &missing); !IsReplyCode(err, http.StatusNotFound) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	var invalid struct {
+		Credit int `var:"CREDIT"`
+	}
+	if err := ari.GetChannelVars("ch1", &invalid); err == nil || err.Error() != `variable CREDIT: strconv.ParseInt: parsing "12.5": invalid syntax` {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := ari.GetChannelVars("ch1", vars); err != ErrNotStructPointer {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", ErrNotStructPointer, err)
+	}
+}
+
+func TestSetChannelVars(t *testing.T) {
+	ari, received := newVarsServer(t, nil)
+	if err := ari.SetChannelVars("ch1", &callVars{Number: "1001", Header: "bar", Credit: 0.5, MaxTime: time.Minute}); err != nil {
+		t.Fatal(err)
+	}
+	exp := []string{
+		"POST /ari/channels/ch1/variable?value=1001&variable=CALLERID%28num%29",
+		"POST /ari/channels/ch1/variable?value=bar&variable=PJSIP_HEADER%28read%2CX-Foo%29",
+		"POST /ari/channels/ch1/variable?value=0.5&variable=CREDIT",
+		"POST /ari/channels/ch1/variable?value=1m0s&variable=MAX_TIME",
+		"POST /ari/channels/ch1/variable?value=false&variable=PRIORITY",
+	}
+	if !reflect.DeepEqual(exp, *received) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp, *received)
+	}
+	var unsupported struct {
+		Codecs []string `var:"CODECS"`
+	}
+	if err := ari.SetChannelVars("ch1", &unsupported); !errors.Is(err, ErrUnsupportedVarField) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestGlobalVars(t *testing.T) {
+	ari, received := newVarsServer(t, map[string]string{"TRUNK": "PJSIP/carrier"})
+	if value, err := ari.GetGlobalVar("TRUNK"); err != nil {
+		t.Error(err)
+	} else if value != "PJSIP/carrier" {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", "PJSIP/carrier", value)
+	}
+	if err := ari.SetGlobalVar("TRUNK", "PJSIP/backup"); err != nil {
+		t.Error(err)
+	}
+	exp := []string{"POST /ari/asterisk/variable?value=PJSIP%2Fbackup&variable=TRUNK"}
+	if !reflect.DeepEqual(exp, *received) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp, *received)
+	}
+}
+
+func TestDecodeArgs(t *testing.T) {
+	ss := &StasisStart{Args: []string{"inbound", "cgr_reqtype=*prepaid", "cgr_maxusage=30s", "cgr_retries=2"}}
+	var args struct {
+		Direction string        `arg:"0"`
+		ReqType   string        `arg:"cgr_reqtype"`
+		MaxUsage  time.Duration `arg:"cgr_maxusage"`
+		Retries   uint8         `arg:"cgr_retries"`
+		Missing   string        `arg:"7"`
+		Account   string        `arg:"cgr_account"`
+	}
+	args.Account = "unchanged"
+	if err := ss.DecodeArgs(&args); err != nil {
+		t.Fatal(err)
+	}
+	if args.Direction != "inbound" || args.ReqType != "*prepaid" || args.MaxUsage != 30*time.Second ||
+		args.Retries != 2 || args.Missing != "" || args.Account != "unchanged" {
+		t.Errorf("Unexpected args: %+v", args)
+	}
+	var invalid struct {
+		Retries bool `arg:"cgr_retries"`
+	}
+	if err := ss.DecodeArgs(&invalid); err == nil {
+		t.Error("Expected error for invalid bool")
+	}
+}
+
+func TestContinueInDialplan(t *testing.T) {
+	ari, received := newVarsServer(t, nil)
+	if err := ari.ContinueInDialplan("ch1", ContinueParams{
+		Context:   "from-stasis",
+		Extension: "s",
+		Priority:  2,
+		Label:     "authorized",
+		Variables: map[string]string{"CGR_RESULT": "OK", "CGR_MAXUSAGE": "30"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ari.ContinueInDialplan("ch1", ContinueParams{Priority: 3}); err != nil {
+		t.Fatal(err)
+	}
+	exp := []string{
+		"POST /ari/channels/ch1/variable?value=30&variable=CGR_MAXUSAGE",
+		"POST /ari/channels/ch1/variable?value=OK&variable=CGR_RESULT",
+		"POST /ari/channels/ch1/continue?context=from-stasis&extension=s&label=authorized",
+		"POST /ari/channels/ch1/continue?priority=3",
+	}
+	if !reflect.DeepEqual(exp, *received) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp, *received)
+	}
+}
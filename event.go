@@ -0,0 +1,115 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package aringo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Event is one message received from Asterisk over the websocket
+// Only the envelope is decoded on receive, the rest of the message is kept raw
+// so unknown fields and event types pass through untouched
+type Event struct {
+	Type        string          `json:"type"`
+	Application string          `json:"application"`
+	Timestamp   string          `json:"timestamp"`
+	AsteriskID  string          `json:"asterisk_id"`
+	Raw         json.RawMessage `json:"-"`
+	ctx         context.Context // carries the trace of the event channel, if traced
+}
+
+// Context returns the context of the event, carrying the trace of its channel when tracing is enabled
+// Use it for the REST calls handling the event so they join the same trace
+func (ev *Event) Context() context.Context {
+	if ev.ctx == nil {
+		return context.Background()
+	}
+	return ev.ctx
+}
+
+// Decode unmarshals the raw event into v, typically one of the typed event structs
+func (ev *Event) Decode(v interface{}) error {
+	return json.Unmarshal(ev.Raw, v)
+}
+
+// Fields returns the raw event decoded as a generic map
+func (ev *Event) Fields() (fields map[string]interface{}, err error) {
+	err = json.Unmarshal(ev.Raw, &fields)
+	return
+}
+
+// DecodeError is reported when a websocket frame cannot be decoded as an event
+// The frame is skipped while the connection stays up
+type DecodeError struct {
+	Raw []byte // the frame as received from Asterisk
+	Err error
+}
+
+func (err *DecodeError) Error() string {
+	return fmt.Sprintf("DECODE_ERROR: %s, frame: <%s>", err.Err, err.Raw)
+}
+
+func (err *DecodeError) Unwrap() error {
+	return err.Err
+}
+
+// decodeEvent decodes the envelope of a raw frame
+func decodeEvent(raw []byte) (ev *Event, err error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(raw), []byte("{")) {
+		if err = json.Unmarshal(raw, new(interface{})); err == nil { // valid JSON but not an event
+			err = ErrNotAnObject
+		}
+		return nil, &DecodeError{Raw: raw, Err: err}
+	}
+	ev = new(Event)
+	if err = json.Unmarshal(raw, ev); err != nil {
+		return nil, &DecodeError{Raw: raw, Err: err}
+	}
+	ev.Raw = raw
+	return
+}
+
+// ChannelID returns the id of the channel the event refers to, if any
+func (ev *Event) ChannelID() string {
+	return ev.objectID("channel")
+}
+
+// BridgeID returns the id of the bridge the event refers to, if any
+func (ev *Event) BridgeID() string {
+	return ev.objectID("bridge")
+}
+
+// PlaybackID returns the id of the playback the event refers to, if any
+func (ev *Event) PlaybackID() string {
+	return ev.objectID("playback")
+}
+
+// RecordingName returns the name of the live recording the event refers to, if any
+func (ev *Event) RecordingName() string {
+	var rec struct {
+		Recording struct {
+			Name string `json:"name"`
+		} `json:"recording"`
+	}
+	if err := ev.Decode(&rec); err != nil {
+		return ""
+	}
+	return rec.Recording.Name
+}
+
+// objectID decodes the id of the object stored under key
+func (ev *Event) objectID(key string) string {
+	var objs map[string]json.RawMessage
+	if err := ev.Decode(&objs); err != nil {
+		return ""
+	}
+	return objectID(objs[key])
+}
@@ -0,0 +1,69 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package aringo
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestEventDecode(t *testing.T) {
+	raw := []byte(`{"type":"StasisStart","application":"cgrates_auth","timestamp":"2024-01-01T00:00:00.000+0000",` +
+		`"args":["cgr_reqtype=*prepaid"],"channel":{"id":"1234.5"},"newField":true}`)
+	ev, err := decodeEvent(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := &Event{
+		Type:        "StasisStart",
+		Application: "cgrates_auth",
+		Timestamp:   "2024-01-01T00:00:00.000+0000",
+		Raw:         raw,
+	}
+	if !reflect.DeepEqual(exp, ev) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp, ev)
+	}
+	var typed struct {
+		Args    []string
+		Channel struct {
+			ID string
+		}
+	}
+	if err = ev.Decode(&typed); err != nil {
+		t.Fatal(err)
+	} else if typed.Channel.ID != "1234.5" || !reflect.DeepEqual(typed.Args, []string{"cgr_reqtype=*prepaid"}) {
+		t.Errorf("Unexpected decoded event: <%+v>", typed)
+	}
+	fields, err := ev.Fields()
+	if err != nil {
+		t.Fatal(err)
+	} else if fields["newField"] != true {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", true, fields["newField"])
+	}
+}
+
+func TestEventDecodeErrors(t *testing.T) {
+	for _, raw := range []string{"", "invalid", "{\"type\":1}", "null", "[]", "\"StasisStart\""} {
+		ev, err := decodeEvent([]byte(raw))
+		var dErr *DecodeError
+		if !errors.As(err, &dErr) {
+			t.Errorf("Expected DecodeError for <%s>, received: <%+v>", raw, err)
+			continue
+		}
+		if ev != nil {
+			t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", nil, ev)
+		}
+		if string(dErr.Raw) != raw {
+			t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", raw, string(dErr.Raw))
+		}
+	}
+	if _, err := decodeEvent([]byte("null")); !errors.Is(err, ErrNotAnObject) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", ErrNotAnObject, err)
+	}
+}
@@ -0,0 +1,190 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package externalmedia
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Codec converts between the RTP payload and 16 bit signed linear PCM samples
+type Codec struct {
+	Name        string // format name as known by Asterisk
+	PayloadType uint8
+	SampleRate  int
+	decode      func(payload []byte) []int16
+	encode      func(pcm []int16) []byte
+}
+
+var (
+	ULaw   = &Codec{Name: "ulaw", PayloadType: 0, SampleRate: 8000, decode: decodeULaw, encode: encodeULaw}
+	ALaw   = &Codec{Name: "alaw", PayloadType: 8, SampleRate: 8000, decode: decodeALaw, encode: encodeALaw}
+	SLin16 = &Codec{Name: "slin16", PayloadType: 118, SampleRate: 16000, decode: decodeSLin, encode: encodeSLin} // big endian, dynamic payload type as used by Asterisk
+)
+
+// CodecByName returns the codec of an Asterisk format
+func CodecByName(name string) (*Codec, error) {
+	for _, codec := range []*Codec{ULaw, ALaw, SLin16} {
+		if codec.Name == name {
+			return codec, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported format: %s", name)
+}
+
+// Decode converts one RTP payload into PCM samples
+func (c *Codec) Decode(payload []byte) []int16 {
+	return c.decode(payload)
+}
+
+// Encode converts PCM samples into one RTP payload
+func (c *Codec) Encode(pcm []int16) []byte {
+	return c.encode(pcm)
+}
+
+// samplesPerPacket is the number of samples inside 20ms of audio
+func (c *Codec) samplesPerPacket() int {
+	return c.SampleRate / 50
+}
+
+func decodeSLin(payload []byte) []int16 {
+	pcm := make([]int16, len(payload)/2)
+	for i := range pcm {
+		pcm[i] = int16(binary.BigEndian.Uint16(payload[2*i:]))
+	}
+	return pcm
+}
+
+func encodeSLin(pcm []int16) []byte {
+	payload := make([]byte, 2*len(pcm))
+	for i, sample := range pcm {
+		binary.BigEndian.PutUint16(payload[2*i:], uint16(sample))
+	}
+	return payload
+}
+
+// G.711 conversions as in the reference implementation of Sun Microsystems
+const (
+	g711SignBit   = 0x80
+	g711QuantMask = 0x0f
+	g711SegShift  = 4
+	g711SegMask   = 0x70
+	uLawBias      = 0x84
+	uLawClip      = 8159
+)
+
+var (
+	uLawSegEnds = [8]int{0x3f, 0x7f, 0xff, 0x1ff, 0x3ff, 0x7ff, 0xfff, 0x1fff}
+	aLawSegEnds = [8]int{0x1f, 0x3f, 0x7f, 0xff, 0x1ff, 0x3ff, 0x7ff, 0xfff}
+)
+
+// segment returns the index of the first segment end not lower than val
+func segment(val int, ends *[8]int) (seg int) {
+	for seg < len(ends) && val > ends[seg] {
+		seg++
+	}
+	return
+}
+
+func linearToULaw(sample int16) byte {
+	val := int(sample) >> 2
+	mask := 0xff
+	if val < 0 {
+		val = -val
+		mask = 0x7f
+	}
+	if val > uLawClip {
+		val = uLawClip
+	}
+	val += uLawBias >> 2
+	seg := segment(val, &uLawSegEnds)
+	if seg >= 8 {
+		return byte(0x7f ^ mask)
+	}
+	return byte((seg<<g711SegShift | (val>>(seg+1))&g711QuantMask) ^ mask)
+}
+
+func uLawToLinear(u byte) int16 {
+	u = ^u
+	t := (int(u&g711QuantMask) << 3) + uLawBias
+	t <<= (u & g711SegMask) >> g711SegShift
+	if u&g711SignBit != 0 {
+		return int16(uLawBias - t)
+	}
+	return int16(t - uLawBias)
+}
+
+func linearToALaw(sample int16) byte {
+	val := int(sample) >> 3
+	mask := 0xd5
+	if val < 0 {
+		mask = 0x55
+		val = -val - 1
+	}
+	seg := segment(val, &aLawSegEnds)
+	if seg >= 8 {
+		return byte(0x7f ^ mask)
+	}
+	aval := seg << g711SegShift
+	if seg < 2 {
+		aval |= (val >> 1) & g711QuantMask
+	} else {
+		aval |= (val >> seg) & g711QuantMask
+	}
+	return byte(aval ^ mask)
+}
+
+func aLawToLinear(a byte) int16 {
+	a ^= 0x55
+	t := int(a&g711QuantMask) << 4
+	switch seg := (a & g711SegMask) >> g711SegShift; seg {
+	case 0:
+		t += 8
+	case 1:
+		t += 0x108
+	default:
+		t += 0x108
+		t <<= seg - 1
+	}
+	if a&g711SignBit != 0 {
+		return int16(t)
+	}
+	return int16(-t)
+}
+
+func decodeULaw(payload []byte) []int16 {
+	pcm := make([]int16, len(payload))
+	for i, u := range payload {
+		pcm[i] = uLawToLinear(u)
+	}
+	return pcm
+}
+
+func encodeULaw(pcm []int16) []byte {
+	payload := make([]byte, len(pcm))
+	for i, sample := range pcm {
+		payload[i] = linearToULaw(sample)
+	}
+	return payload
+}
+
+func decodeALaw(payload []byte) []int16 {
+	pcm := make([]int16, len(payload))
+	for i, a := range payload {
+		pcm[i] = aLawToLinear(a)
+	}
+	return pcm
+}
+
+func encodeALaw(pcm []int16) []byte {
+	payload := make([]byte, len(pcm))
+	for i, sample := range pcm {
+		payload[i] = linearToALaw(sample)
+	}
+	return payload
+}
@@ -0,0 +1,65 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package externalmedia
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestG711(t *testing.T) {
+	for _, tc := range []struct {
+		codec  *Codec
+		sample int16
+		coded  byte
+	}{
+		{ULaw, 0, 0xff},
+		{ULaw, -32124, 0x00},
+		{ULaw, 32124, 0x80},
+		{ALaw, 8, 0xd5},
+		{ALaw, -8, 0x55},
+		{ALaw, 32256, 0xaa},
+	} {
+		if coded := tc.codec.Encode([]int16{tc.sample}); coded[0] != tc.coded {
+			t.Errorf("%s encode %d, \nExpected: <%+v>, \nReceived: <%+v>", tc.codec.Name, tc.sample, tc.coded, coded[0])
+		}
+		if pcm := tc.codec.Decode([]byte{tc.coded}); pcm[0] != tc.sample {
+			t.Errorf("%s decode %x, \nExpected: <%+v>, \nReceived: <%+v>", tc.codec.Name, tc.coded, tc.sample, pcm[0])
+		}
+	}
+	for _, codec := range []*Codec{ULaw, ALaw} { // the quantization error grows with the amplitude
+		for sample := -32768; sample < 32768; sample += 7 {
+			decoded := int(codec.Decode(codec.Encode([]int16{int16(sample)}))[0])
+			if diff := decoded - sample; diff < -1100 || diff > 1100 ||
+				(sample > -256 && sample < 256 && (diff < -16 || diff > 16)) {
+				t.Fatalf("%s roundtrip of %d returned %d", codec.Name, sample, decoded)
+			}
+		}
+	}
+}
+
+func TestSLin16(t *testing.T) {
+	pcm := []int16{0, 1, -1, 32767, -32768}
+	payload := SLin16.Encode(pcm)
+	exp := []byte{0, 0, 0, 1, 0xff, 0xff, 0x7f, 0xff, 0x80, 0}
+	if !reflect.DeepEqual(exp, payload) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp, payload)
+	}
+	if rcv := SLin16.Decode(payload); !reflect.DeepEqual(pcm, rcv) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", pcm, rcv)
+	}
+}
+
+func TestCodecByName(t *testing.T) {
+	if codec, err := CodecByName("alaw"); err != nil || codec != ALaw {
+		t.Errorf("Unexpected codec: %+v, error: %v", codec, err)
+	}
+	if _, err := CodecByName("opus"); err == nil {
+		t.Error("Expected error for unsupported format")
+	}
+}
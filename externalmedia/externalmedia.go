@@ -0,0 +1,89 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package externalmedia
+
+import (
+	"net"
+
+	"github.com/cgrates/aringo"
+)
+
+// Params configure the externalMedia channel together with its local RTP endpoint
+type Params struct {
+	App        string // Stasis application receiving the events of the channel
+	ChannelID  string // generated if empty
+	Codec      *Codec // ULaw if nil
+	ListenAddr string // local UDP address receiving the RTP, ie: 0.0.0.0:0 (default 127.0.0.1:0)
+	// AdvertiseAddr is the host:port Asterisk sends the RTP to, the listen address if empty
+	// Needed when listening on all interfaces or behind NAT
+	AdvertiseAddr string
+	Direction     string // both (default), in or out
+}
+
+// Start binds the local RTP endpoint and creates the externalMedia channel sending the audio to it
+// The session is closed once the channel leaves the application or is hung up
+func Start(ari *aringo.ARInGO, p Params) (s *Session, err error) {
+	if p.Codec == nil {
+		p.Codec = ULaw
+	}
+	if p.ListenAddr == "" {
+		p.ListenAddr = "127.0.0.1:0"
+	}
+	var laddr *net.UDPAddr
+	if laddr, err = net.ResolveUDPAddr("udp", p.ListenAddr); err != nil {
+		return
+	}
+	var conn *net.UDPConn
+	if conn, err = net.ListenUDP("udp", laddr); err != nil {
+		return
+	}
+	if p.AdvertiseAddr == "" {
+		p.AdvertiseAddr = conn.LocalAddr().String()
+	}
+	if p.ChannelID == "" {
+		p.ChannelID = ari.NewID()
+	}
+	sub := ari.Subscribe(aringo.AllFilters(aringo.TypeFilter("StasisEnd", "ChannelDestroyed"),
+		aringo.ChannelFilter(p.ChannelID))) // before creating, the channel can go away before the reply
+	var ch *aringo.Channel
+	if ch, err = ari.ExternalMedia(aringo.ExternalMediaParams{
+		ChannelID:    p.ChannelID,
+		App:          p.App,
+		ExternalHost: p.AdvertiseAddr,
+		Format:       p.Codec.Name,
+		Direction:    p.Direction,
+	}); err != nil {
+		sub.Close()
+		conn.Close()
+		return
+	}
+	s = NewSession(conn, p.Codec, asteriskAddr(ch))
+	s.Channel = ch
+	go func() {
+		defer sub.Close()
+		select {
+		case <-sub.Events():
+			s.Close()
+		case <-s.done:
+		}
+	}()
+	return
+}
+
+// asteriskAddr returns the address Asterisk sends the RTP from, nil if not advertised or bound to all interfaces
+func asteriskAddr(ch *aringo.Channel) *net.UDPAddr {
+	host, port := ch.ChannelVars["UNICASTRTP_LOCAL_ADDRESS"], ch.ChannelVars["UNICASTRTP_LOCAL_PORT"]
+	if host == "" || port == "" {
+		return nil
+	}
+	addr, err := net.ResolveUDPAddr("udp", net.JoinHostPort(host, port))
+	if err != nil || addr.IP.IsUnspecified() {
+		return nil
+	}
+	return addr
+}
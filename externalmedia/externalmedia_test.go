@@ -0,0 +1,76 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package externalmedia
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cgrates/aringo"
+	"golang.org/x/net/websocket"
+)
+
+func TestStart(t *testing.T) {
+	peer := newPeer(t) // Asterisk RTP
+	wsConns := make(chan *websocket.Conn, 1)
+	mux := http.NewServeMux()
+	mux.Handle("/ari/events", websocket.Handler(func(c *websocket.Conn) {
+		wsConns <- c
+		c.Read(make([]byte, 1))
+	}))
+	mux.HandleFunc("/ari/channels/externalMedia", func(rw http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("format") != "alaw" || query.Get("app") != "test" || query.Get("channelId") != "em1" ||
+			!strings.HasPrefix(query.Get("external_host"), "127.0.0.1:") {
+			t.Errorf("Unexpected query: %s", r.URL.RawQuery)
+		}
+		rw.Write([]byte(`{"id":"em1","channelvars":{"UNICASTRTP_LOCAL_ADDRESS":"127.0.0.1","UNICASTRTP_LOCAL_PORT":"` +
+			strconv.Itoa(peer.LocalAddr().(*net.UDPAddr).Port) + `"}}`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	stopChan := make(chan struct{})
+	defer close(stopChan)
+	ari, err := aringo.NewARInGO("ws"+strings.TrimPrefix(srv.URL, "http")+"/ari/events?app=test", srv.URL, "", "", "",
+		nil, make(chan error, 1), stopChan, 1, 0, 0, func(time.Duration, time.Duration) func() time.Duration { return nil },
+		aringo.WithIDGenerator(func() string { return "em1" }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws := <-wsConns
+
+	s, err := Start(ari, Params{App: "test", Codec: ALaw})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Channel.ID != "em1" || s.RemoteAddr().String() != peer.LocalAddr().String() {
+		t.Errorf("Unexpected session, channel: %+v, remote: %v", s.Channel, s.RemoteAddr())
+	}
+	if err = s.WriteFrame([]int16{8}); err != nil { // Asterisk address known upfront
+		t.Fatal(err)
+	}
+	buf := make([]byte, maxPacketSize)
+	peer.SetReadDeadline(time.Now().Add(time.Second))
+	if n, err := peer.Read(buf); err != nil {
+		t.Fatal(err)
+	} else if n != rtpHeaderSize+1 || buf[rtpHeaderSize] != 0xd5 {
+		t.Errorf("Unexpected packet: %x", buf[:n])
+	}
+
+	ws.Write([]byte(`{"type":"StasisEnd","channel":{"id":"em1"}}`)) // session follows the channel
+	select {
+	case <-s.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Session not closed on StasisEnd")
+	}
+}
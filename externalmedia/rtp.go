@@ -0,0 +1,80 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+// Package externalmedia streams the audio of Asterisk channels into Go over RTP, via ARI externalMedia channels
+package externalmedia
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	rtpVersion    = 2
+	rtpHeaderSize = 12
+)
+
+var ErrInvalidPacket = errors.New("INVALID_RTP_PACKET")
+
+// Packet is one RTP packet as defined by RFC 3550
+// CSRCs and header extensions are skipped on receive and never sent
+type Packet struct {
+	PayloadType    uint8
+	Marker         bool
+	SequenceNumber uint16
+	Timestamp      uint32
+	SSRC           uint32
+	Payload        []byte
+}
+
+// Unmarshal parses the packet out of buf, the payload references buf
+func (p *Packet) Unmarshal(buf []byte) error {
+	if len(buf) < rtpHeaderSize || buf[0]>>6 != rtpVersion {
+		return ErrInvalidPacket
+	}
+	padding := buf[0]&0x20 != 0
+	extension := buf[0]&0x10 != 0
+	offset := rtpHeaderSize + 4*int(buf[0]&0x0f) // CSRCs
+	p.Marker = buf[1]&0x80 != 0
+	p.PayloadType = buf[1] & 0x7f
+	p.SequenceNumber = binary.BigEndian.Uint16(buf[2:4])
+	p.Timestamp = binary.BigEndian.Uint32(buf[4:8])
+	p.SSRC = binary.BigEndian.Uint32(buf[8:12])
+	if extension {
+		if len(buf) < offset+4 {
+			return ErrInvalidPacket
+		}
+		offset += 4 + 4*int(binary.BigEndian.Uint16(buf[offset+2:offset+4]))
+	}
+	end := len(buf)
+	if padding {
+		if end == 0 {
+			return ErrInvalidPacket
+		}
+		end -= int(buf[end-1])
+	}
+	if offset > end {
+		return ErrInvalidPacket
+	}
+	p.Payload = buf[offset:end]
+	return nil
+}
+
+// Marshal returns the packet in wire format
+func (p *Packet) Marshal() []byte {
+	buf := make([]byte, rtpHeaderSize+len(p.Payload))
+	buf[0] = rtpVersion << 6
+	buf[1] = p.PayloadType & 0x7f
+	if p.Marker {
+		buf[1] |= 0x80
+	}
+	binary.BigEndian.PutUint16(buf[2:4], p.SequenceNumber)
+	binary.BigEndian.PutUint32(buf[4:8], p.Timestamp)
+	binary.BigEndian.PutUint32(buf[8:12], p.SSRC)
+	copy(buf[rtpHeaderSize:], p.Payload)
+	return buf
+}
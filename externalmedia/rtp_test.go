@@ -0,0 +1,57 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package externalmedia
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestPacketMarshal(t *testing.T) {
+	pkt := Packet{PayloadType: 8, Marker: true, SequenceNumber: 65535, Timestamp: 160, SSRC: 0xdeadbeef, Payload: []byte{1, 2, 3}}
+	raw := pkt.Marshal()
+	exp := []byte{0x80, 0x88, 0xff, 0xff, 0, 0, 0, 160, 0xde, 0xad, 0xbe, 0xef, 1, 2, 3}
+	if !bytes.Equal(exp, raw) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp, raw)
+	}
+	var rcv Packet
+	if err := rcv.Unmarshal(raw); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(pkt, rcv) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", pkt, rcv)
+	}
+}
+
+func TestPacketUnmarshal(t *testing.T) {
+	raw := []byte{
+		0xb1, 0x00, 0, 1, 0, 0, 0, 2, 0, 0, 0, 3, // padding, extension, one CSRC
+		0, 0, 0, 4, // CSRC
+		0xbe, 0xde, 0, 1, 0xaa, 0xbb, 0xcc, 0xdd, // extension of one word
+		7, 8, // payload
+		0, 0, 3, // padding
+	}
+	var pkt Packet
+	if err := pkt.Unmarshal(raw); err != nil {
+		t.Fatal(err)
+	}
+	exp := Packet{SequenceNumber: 1, Timestamp: 2, SSRC: 3, Payload: []byte{7, 8}}
+	if !reflect.DeepEqual(exp, pkt) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp, pkt)
+	}
+	for _, invalid := range [][]byte{
+		{0x80, 0, 0, 1},                              // too short
+		{0x40, 0, 0, 1, 0, 0, 0, 2, 0, 0, 0, 3},      // version 1
+		{0x82, 0, 0, 1, 0, 0, 0, 2, 0, 0, 0, 3, 0},   // missing CSRCs
+		{0xa0, 0, 0, 1, 0, 0, 0, 2, 0, 0, 0, 3, 200}, // padding over the payload
+	} {
+		if err := pkt.Unmarshal(invalid); err != ErrInvalidPacket {
+			t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", ErrInvalidPacket, err)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package externalmedia
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/cgrates/aringo"
+)
+
+const (
+	frameBuffer   = 100 // frames (2s of audio) queued before dropping
+	maxPacketSize = 1500
+)
+
+var ErrNoRemote = errors.New("NO_REMOTE_ADDRESS")
+
+// Session exchanges the audio of one externalMedia channel over RTP
+// The received audio is delivered as PCM frames on Frames or as little endian 16 bit PCM via Read, not both
+type Session struct {
+	Channel *aringo.Channel // externalMedia channel, set when created via Start
+
+	conn      *net.UDPConn
+	codec     *Codec
+	frames    chan []int16
+	done      chan struct{}
+	closeOnce sync.Once
+
+	remoteMux sync.RWMutex
+	remote    *net.UDPAddr // learned from the first packet received if not known upfront
+
+	readBuf []byte // PCM bytes left over from the last Read
+
+	writeMux sync.Mutex // protects the sending state below
+	seq      uint16
+	ts       uint32
+	ssrc     uint32
+	pending  []byte    // PCM bytes not filling a whole packet yet
+	nextSend time.Time // pacing of Write
+}
+
+// NewSession starts exchanging RTP over conn, remote can be nil and is learned out of the first received packet
+func NewSession(conn *net.UDPConn, codec *Codec, remote *net.UDPAddr) (s *Session) {
+	s = &Session{
+		conn:   conn,
+		codec:  codec,
+		remote: remote,
+		frames: make(chan []int16, frameBuffer),
+		done:   make(chan struct{}),
+		seq:    uint16(rand.Uint32()),
+		ts:     rand.Uint32(),
+		ssrc:   rand.Uint32(),
+	}
+	go s.receive()
+	return
+}
+
+// receive reads the RTP packets until the connection is closed
+func (s *Session) receive() {
+	defer close(s.frames)
+	buf := make([]byte, maxPacketSize)
+	var pkt Packet
+	for {
+		n, addr, err := s.conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		if pkt.Unmarshal(buf[:n]) != nil || pkt.PayloadType != s.codec.PayloadType {
+			continue // not audio of the negotiated format (ie: comfort noise)
+		}
+		s.remoteMux.Lock()
+		if s.remote == nil { // symmetric RTP, answer where the audio comes from
+			s.remote = addr
+		}
+		s.remoteMux.Unlock()
+		select {
+		case s.frames <- s.codec.Decode(pkt.Payload):
+		default: // consumer not keeping up
+		}
+	}
+}
+
+// Codec returns the audio format of the session
+func (s *Session) Codec() *Codec {
+	return s.codec
+}
+
+// LocalAddr returns the address receiving the RTP
+func (s *Session) LocalAddr() *net.UDPAddr {
+	return s.conn.LocalAddr().(*net.UDPAddr)
+}
+
+// RemoteAddr returns the address the audio is sent to, nil if not known yet
+func (s *Session) RemoteAddr() *net.UDPAddr {
+	s.remoteMux.RLock()
+	defer s.remoteMux.RUnlock()
+	return s.remote
+}
+
+// Frames returns the channel receiving the decoded audio, one frame per packet
+// Frames are dropped if not consumed in time, the channel is closed together with the session
+func (s *Session) Frames() <-chan []int16 {
+	return s.frames
+}
+
+// Done is closed once the session is closed
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
+
+// Read reads the received audio as little endian 16 bit PCM, io.EOF once the session is closed
+func (s *Session) Read(p []byte) (n int, err error) {
+	if len(s.readBuf) == 0 {
+		frame, ok := <-s.frames
+		if !ok {
+			return 0, io.EOF
+		}
+		s.readBuf = make([]byte, 2*len(frame))
+		for i, sample := range frame {
+			binary.LittleEndian.PutUint16(s.readBuf[2*i:], uint16(sample))
+		}
+	}
+	n = copy(p, s.readBuf)
+	s.readBuf = s.readBuf[n:]
+	return
+}
+
+// WriteFrame sends the PCM samples towards Asterisk as one RTP packet, without pacing
+func (s *Session) WriteFrame(pcm []int16) error {
+	s.writeMux.Lock()
+	defer s.writeMux.Unlock()
+	return s.sendFrame(pcm)
+}
+
+func (s *Session) sendFrame(pcm []int16) (err error) {
+	remote := s.RemoteAddr()
+	if remote == nil {
+		return ErrNoRemote
+	}
+	pkt := Packet{
+		PayloadType:    s.codec.PayloadType,
+		SequenceNumber: s.seq,
+		Timestamp:      s.ts,
+		SSRC:           s.ssrc,
+		Payload:        s.codec.Encode(pcm),
+	}
+	if _, err = s.conn.WriteToUDP(pkt.Marshal(), remote); err != nil {
+		return
+	}
+	s.seq++
+	s.ts += uint32(len(pcm))
+	return
+}
+
+// Write sends little endian 16 bit PCM towards Asterisk in packets of 20ms, paced in real time
+// so whole prompts can be written at once; incomplete packets are kept until the next Write
+// On error n counts the bytes of p sent within the packets before, the rest of p is not kept
+func (s *Session) Write(p []byte) (n int, err error) {
+	s.writeMux.Lock()
+	defer s.writeMux.Unlock()
+	kept := len(s.pending) // out of the previous writes, reported as written already
+	s.pending = append(s.pending, p...)
+	packetBytes := 2 * s.codec.samplesPerPacket()
+	var sent int
+	fail := func(err error) (int, error) {
+		if n = sent - kept; n < 0 {
+			n = 0
+		}
+		s.pending = s.pending[:len(s.pending)-(len(p)-n)]
+		return n, err
+	}
+	for len(s.pending) >= packetBytes {
+		if now := time.Now(); s.nextSend.Before(now) { // first packet or caller paused
+			s.nextSend = now
+		}
+		select {
+		case <-time.After(time.Until(s.nextSend)):
+		case <-s.done:
+			return fail(net.ErrClosed)
+		}
+		pcm := make([]int16, packetBytes/2)
+		for i := range pcm {
+			pcm[i] = int16(binary.LittleEndian.Uint16(s.pending[2*i:]))
+		}
+		if err = s.sendFrame(pcm); err != nil {
+			return fail(err)
+		}
+		s.pending = s.pending[packetBytes:]
+		sent += packetBytes
+		s.nextSend = s.nextSend.Add(20 * time.Millisecond)
+	}
+	return len(p), nil
+}
+
+// Close stops exchanging audio, the externalMedia channel is not hung up
+func (s *Session) Close() (err error) {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		err = s.conn.Close()
+	})
+	return
+}
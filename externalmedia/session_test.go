@@ -0,0 +1,142 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package externalmedia
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newPeer returns an UDP socket acting as the Asterisk side
+func newPeer(t *testing.T) *net.UDPConn {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func newTestSession(t *testing.T, codec *Codec, remote *net.UDPAddr) *Session {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewSession(conn, codec, remote)
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestSessionReceive(t *testing.T) {
+	peer := newPeer(t)
+	s := newTestSession(t, SLin16, nil)
+	if err := s.WriteFrame([]int16{1}); err != ErrNoRemote {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", ErrNoRemote, err)
+	}
+	for i, pkt := range []Packet{
+		{PayloadType: 13, SequenceNumber: 1, Payload: []byte{0}}, // comfort noise is skipped
+		{PayloadType: SLin16.PayloadType, SequenceNumber: 2, Payload: SLin16.Encode([]int16{1, -2})},
+		{PayloadType: SLin16.PayloadType, SequenceNumber: 3, Payload: SLin16.Encode([]int16{300})},
+	} {
+		if _, err := peer.WriteToUDP(pkt.Marshal(), s.LocalAddr()); err != nil {
+			t.Fatal(i, err)
+		}
+	}
+	select {
+	case frame := <-s.Frames():
+		if !reflect.DeepEqual(frame, []int16{1, -2}) {
+			t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", []int16{1, -2}, frame)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("No frame received")
+	}
+	buf := make([]byte, 1)
+	var pcm []byte
+	for len(pcm) < 2 {
+		n, err := s.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pcm = append(pcm, buf[:n]...)
+	}
+	if sample := int16(binary.LittleEndian.Uint16(pcm)); sample != 300 {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", 300, sample)
+	}
+	if s.RemoteAddr().String() != peer.LocalAddr().String() { // learned out of the received audio
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", peer.LocalAddr(), s.RemoteAddr())
+	}
+
+	s.Close()
+	if _, err := s.Read(buf); err != io.EOF {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", io.EOF, err)
+	}
+}
+
+func TestSessionWrite(t *testing.T) {
+	peer := newPeer(t)
+	s := newTestSession(t, ULaw, peer.LocalAddr().(*net.UDPAddr))
+	pcm := make([]byte, 2*(2*160+10)) // two packets of 20ms, the rest kept for the next Write
+	for i := 0; i < len(pcm)/2; i++ {
+		binary.LittleEndian.PutUint16(pcm[2*i:], uint16(i*8))
+	}
+	start := time.Now()
+	if n, err := s.Write(pcm); err != nil {
+		t.Fatal(err)
+	} else if n != len(pcm) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", len(pcm), n)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond { // paced
+		t.Errorf("Write not paced, took: %v", elapsed)
+	}
+	buf := make([]byte, maxPacketSize)
+	var pkts []Packet
+	for i := 0; i < 2; i++ {
+		peer.SetReadDeadline(time.Now().Add(time.Second))
+		n, err := peer.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var pkt Packet
+		if err = pkt.Unmarshal(append([]byte(nil), buf[:n]...)); err != nil {
+			t.Fatal(err)
+		}
+		pkts = append(pkts, pkt)
+	}
+	if pkts[1].SequenceNumber != pkts[0].SequenceNumber+1 || pkts[1].Timestamp != pkts[0].Timestamp+160 ||
+		pkts[0].SSRC != pkts[1].SSRC || pkts[0].PayloadType != ULaw.PayloadType || len(pkts[1].Payload) != 160 {
+		t.Errorf("Unexpected packets: %+v", pkts)
+	}
+	if sample := ULaw.Decode(pkts[1].Payload[:1])[0]; sample < 160*8-64 || sample > 160*8+64 {
+		t.Errorf("Unexpected first sample of the second packet: %d", sample)
+	}
+	if len(s.pending) != 20 {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", 20, len(s.pending))
+	}
+}
+
+func TestSessionWriteClosed(t *testing.T) {
+	peer := newPeer(t)
+	s := newTestSession(t, ULaw, peer.LocalAddr().(*net.UDPAddr))
+	s.pending = make([]byte, 20) // kept out of a previous Write
+	pcm := make([]byte, 2*(5*160))
+	time.AfterFunc(30*time.Millisecond, func() { s.Close() })
+	n, err := s.Write(pcm)
+	if err != net.ErrClosed {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", net.ErrClosed, err)
+	}
+	if n == 0 || n >= len(pcm) || (n+20)%320 != 0 { // whole packets sent before closing
+		t.Errorf("Unexpected written: %d", n)
+	}
+	if len(s.pending) != 0 {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", 0, len(s.pending))
+	}
+}
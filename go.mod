@@ -1,5 +1,19 @@
 module github.com/cgrates/aringo
 
-go 1.17
+go 1.21
 
-require golang.org/x/net v0.0.0-20191119073136-fc4aabc6c914
+require (
+	go.opentelemetry.io/otel v1.24.0
+	go.opentelemetry.io/otel/sdk v1.24.0
+	go.opentelemetry.io/otel/trace v1.24.0
+	golang.org/x/net v0.26.0
+)
+
+require (
+	github.com/go-logr/logr v1.4.1 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/stretchr/testify v1.9.0 // indirect
+	go.opentelemetry.io/otel/metric v1.24.0 // indirect
+)
+
+require golang.org/x/sys v0.22.0 // indirect
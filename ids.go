@@ -0,0 +1,47 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package aringo
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// IDGenerator returns unique ids for the resources created by the helpers (channels, bridges, playbacks, recordings)
+type IDGenerator func() string
+
+// NewUUID returns a random (version 4) UUID
+func NewUUID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// PrefixedIDGenerator prefixes the ids of gen with the node prefix so they can be traced back to their creator
+func PrefixedIDGenerator(prefix string, gen IDGenerator) IDGenerator {
+	return func() string {
+		return prefix + "-" + gen()
+	}
+}
+
+// WithIDGenerator overwrites the default UUID generator of the resource ids
+func WithIDGenerator(gen IDGenerator) Option {
+	return func(ari *ARInGO) {
+		ari.idGenerator = gen
+	}
+}
+
+// NewID generates one resource id using the configured IDGenerator
+func (ari *ARInGO) NewID() string {
+	if ari.idGenerator == nil {
+		return NewUUID()
+	}
+	return ari.idGenerator()
+}
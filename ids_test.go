@@ -0,0 +1,40 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package aringo
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestNewUUID(t *testing.T) {
+	uuidRgx := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		id := NewUUID()
+		if !uuidRgx.MatchString(id) {
+			t.Fatalf("Invalid UUID: <%s>", id)
+		}
+		if seen[id] {
+			t.Fatalf("Duplicated UUID: <%s>", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestPrefixedIDGenerator(t *testing.T) {
+	gen := PrefixedIDGenerator("node1", func() string { return "id" })
+	if id := gen(); id != "node1-id" {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", "node1-id", id)
+	}
+	ari := &ARInGO{}
+	WithIDGenerator(gen)(ari)
+	if id := ari.NewID(); id != "node1-id" {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", "node1-id", id)
+	}
+}
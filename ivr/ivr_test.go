@@ -0,0 +1,207 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package ivr
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cgrates/aringo"
+	"github.com/cgrates/aringo/aringotest"
+)
+
+// fakeChannel answers the playback requests of one channel, script feeds the caller reaction to each prompt
+type fakeChannel struct {
+	ari     *aringo.ARInGO
+	mux     sync.Mutex
+	played  [][]string
+	stopped []string
+	script  func(n int, playbackID string) []string
+}
+
+func newFakeChannel(t *testing.T, script func(n int, playbackID string) []string) (fc *fakeChannel) {
+	fc = &fakeChannel{script: script}
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		fc.mux.Lock()
+		defer fc.mux.Unlock()
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/ari/channels/ch1/play/"):
+			playbackID := strings.TrimPrefix(r.URL.Path, "/ari/channels/ch1/play/")
+			fc.played = append(fc.played, r.URL.Query()["media"])
+			aringotest.Inject(fc.ari, fc.script(len(fc.played)-1, playbackID)...)
+			rw.Write([]byte(`{"id":"` + playbackID + `","target_uri":"channel:ch1","state":"playing"}`))
+		case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/ari/playbacks/"):
+			fc.stopped = append(fc.stopped, strings.TrimPrefix(r.URL.Path, "/ari/playbacks/"))
+			rw.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("Unexpected request: %s %s", r.Method, r.URL)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	fc.ari = aringo.NewReplayARInGO(nil, aringo.WithRESTURL(srv.URL+"/ari"))
+	return
+}
+
+func dtmf(digits string) (frames []string) {
+	for _, digit := range digits {
+		frames = append(frames, `{"type":"ChannelDtmfReceived","digit":"`+string(digit)+`","channel":{"id":"ch1"}}`)
+	}
+	return
+}
+
+func finished(playbackID string) string {
+	return `{"type":"PlaybackFinished","playback":{"id":"` + playbackID + `","target_uri":"channel:ch1","state":"done"}}`
+}
+
+func run(t *testing.T, fc *fakeChannel, menu *Menu) (*Selection, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	return Run(ctx, fc.ari, "ch1", menu)
+}
+
+func TestRunMenuTree(t *testing.T) {
+	sales := &Menu{Name: "sales"}
+	support := &Menu{
+		Name:    "support",
+		Prompts: []string{"sound:support-menu"},
+		Options: map[string]*Menu{"1": {Name: "billing"}, "21": {Name: "technical"}, "22": {Name: "outages"}},
+	}
+	main := &Menu{
+		Name:           "main",
+		Prompts:        []string{"sound:welcome", "sound:main-menu"},
+		InvalidPrompts: []string{"sound:invalid"},
+		Options:        map[string]*Menu{"1": sales, "2": support},
+	}
+	fc := newFakeChannel(t, func(n int, playbackID string) []string {
+		switch n {
+		case 0:
+			return dtmf("9") // invalid, barging in
+		case 1:
+			return append([]string{finished(playbackID)}, dtmf("2")...)
+		}
+		return append(dtmf("2"), dtmf("2")...) // complete without waiting for more digits
+	})
+	sel, err := run(t, fc, main)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sel.Menu != support.Options["22"] || sel.Digits != "22" || !reflect.DeepEqual(sel.Path, []string{"2", "22"}) {
+		t.Errorf("Unexpected selection: %+v", sel)
+	}
+	expPlayed := [][]string{
+		{"sound:welcome", "sound:main-menu"},
+		{"sound:invalid", "sound:welcome", "sound:main-menu"},
+		{"sound:support-menu"},
+	}
+	if !reflect.DeepEqual(expPlayed, fc.played) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", expPlayed, fc.played)
+	}
+	if len(fc.stopped) != 2 { // barge-in on the first and last prompts
+		t.Errorf("Unexpected stopped playbacks: %+v", fc.stopped)
+	}
+}
+
+func TestRunFreeInput(t *testing.T) {
+	fc := newFakeChannel(t, func(n int, playbackID string) []string {
+		return append([]string{finished(playbackID)}, dtmf("1234#")...)
+	})
+	sel, err := run(t, fc, &Menu{Name: "account", Prompts: []string{"sound:enter-account"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sel.Digits != "1234" || sel.Menu.Name != "account" {
+		t.Errorf("Unexpected selection: %+v", sel)
+	}
+	if len(fc.stopped) != 0 {
+		t.Errorf("Unexpected stopped playbacks: %+v", fc.stopped)
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	fc := newFakeChannel(t, func(n int, playbackID string) []string {
+		if n == 1 {
+			return append([]string{finished(playbackID)}, dtmf("1")...) // inter digit timeout for the free input
+		}
+		return []string{finished(playbackID)}
+	})
+	menu := &Menu{
+		Prompts:           []string{"sound:enter-pin"},
+		TimeoutPrompts:    []string{"sound:are-you-there"},
+		FirstDigitTimeout: 10 * time.Millisecond,
+		InterDigitTimeout: 10 * time.Millisecond,
+	}
+	sel, err := run(t, fc, menu)
+	if err != nil {
+		t.Fatal(err)
+	} else if sel.Digits != "1" {
+		t.Errorf("Unexpected selection: %+v", sel)
+	}
+	if exp := []string{"sound:are-you-there", "sound:enter-pin"}; !reflect.DeepEqual(exp, fc.played[1]) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp, fc.played[1])
+	}
+
+	fc = newFakeChannel(t, func(n int, playbackID string) []string { return []string{finished(playbackID)} })
+	menu.MaxRetries = 1
+	if _, err = run(t, fc, menu); err != ErrMaxRetries {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", ErrMaxRetries, err)
+	}
+	if len(fc.played) != 2 {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", 2, len(fc.played))
+	}
+}
+
+func TestRunNoBargeIn(t *testing.T) {
+	fc := newFakeChannel(t, func(n int, playbackID string) []string {
+		return append(append(dtmf("1"), finished(playbackID)), dtmf("2")...)
+	})
+	sel, err := run(t, fc, &Menu{
+		Prompts:   []string{"sound:legal-notice"},
+		Options:   map[string]*Menu{"1": {Name: "one"}, "2": {Name: "two"}},
+		NoBargeIn: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if sel.Menu.Name != "two" {
+		t.Errorf("Unexpected selection: %+v", sel)
+	}
+	if len(fc.stopped) != 0 {
+		t.Errorf("Unexpected stopped playbacks: %+v", fc.stopped)
+	}
+}
+
+func TestRunHangup(t *testing.T) {
+	fc := newFakeChannel(t, func(n int, playbackID string) []string {
+		return []string{`{"type":"StasisEnd","channel":{"id":"ch1"}}`}
+	})
+	if _, err := run(t, fc, &Menu{Prompts: []string{"sound:welcome"}}); err != ErrHangup {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", ErrHangup, err)
+	}
+}
+
+func TestSessionPlaybackFilter(t *testing.T) {
+	sess := new(session)
+	ev := &aringo.Event{Type: "PlaybackFinished", Raw: json.RawMessage(finished("pb1"))}
+	if sess.playbackFilter(ev) {
+		t.Error("Expected no match without playback")
+	}
+	sess.setPlaying("pb2") // playback of another channel or an older prompt
+	if sess.playbackFilter(ev) {
+		t.Error("Expected pb1 not to match")
+	}
+	sess.setPlaying("pb1")
+	if !sess.playbackFilter(ev) {
+		t.Error("Expected pb1 to match")
+	}
+}
@@ -0,0 +1,101 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+// Package ivr drives channels through declarative IVR menus: prompts played via ARI and DTMF selections collected
+package ivr
+
+import (
+	"time"
+)
+
+const (
+	DefaultFirstDigitTimeout = 5 * time.Second
+	DefaultInterDigitTimeout = 3 * time.Second
+	DefaultMaxRetries        = 3
+	DefaultTerminator        = "#"
+)
+
+// Menu is one node of the IVR tree
+// A menu without Options collects free input (ie: an account number) which ends the run
+// An option leading to a menu without Prompts ends the run with that menu as selection
+type Menu struct {
+	Name           string
+	Prompts        []string         // media URIs played when entering the menu, ie: sound:main-menu
+	InvalidPrompts []string         // played before retrying when the input matches no option
+	TimeoutPrompts []string         // played before retrying when nothing was entered
+	Options        map[string]*Menu // digit sequences leading to the next menus
+	MaxDigits      int              // input complete once reached, the longest option by default, unlimited for free input
+	Terminator     string           // digit completing the input, DefaultTerminator if empty
+	// FirstDigitTimeout is the time allowed for the first digit once the prompts finished, DefaultFirstDigitTimeout if 0
+	FirstDigitTimeout time.Duration
+	InterDigitTimeout time.Duration // DefaultInterDigitTimeout if 0
+	MaxRetries        int           // retries on invalid or missing input, DefaultMaxRetries if 0, negative for none
+	NoBargeIn         bool          // digits do not interrupt the prompts, the ones entered while playing are ignored
+}
+
+func (m *Menu) terminator() string {
+	if m.Terminator == "" {
+		return DefaultTerminator
+	}
+	return m.Terminator
+}
+
+func (m *Menu) firstDigitTimeout() time.Duration {
+	if m.FirstDigitTimeout == 0 {
+		return DefaultFirstDigitTimeout
+	}
+	return m.FirstDigitTimeout
+}
+
+func (m *Menu) interDigitTimeout() time.Duration {
+	if m.InterDigitTimeout == 0 {
+		return DefaultInterDigitTimeout
+	}
+	return m.InterDigitTimeout
+}
+
+func (m *Menu) maxRetries() int {
+	switch {
+	case m.MaxRetries == 0:
+		return DefaultMaxRetries
+	case m.MaxRetries < 0:
+		return 0
+	}
+	return m.MaxRetries
+}
+
+func (m *Menu) maxDigits() (maxDigits int) {
+	if m.MaxDigits != 0 {
+		return m.MaxDigits
+	}
+	for option := range m.Options {
+		if len(option) > maxDigits {
+			maxDigits = len(option)
+		}
+	}
+	return
+}
+
+// complete checks if the input cannot be continued into another option
+func (m *Menu) complete(digits string) bool {
+	if len(m.Options) == 0 {
+		return false
+	}
+	for option := range m.Options {
+		if len(option) > len(digits) && option[:len(digits)] == digits {
+			return false
+		}
+	}
+	return true
+}
+
+// Selection is the outcome of a run
+type Selection struct {
+	Menu   *Menu    // the menu selected, or the one collecting the free input
+	Path   []string // the input entered at each level
+	Digits string   // the input entered at the last level
+}
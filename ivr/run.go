@@ -0,0 +1,174 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package ivr
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/cgrates/aringo"
+)
+
+var (
+	ErrMaxRetries = errors.New("MAX_RETRIES")
+	ErrHangup     = errors.New("CHANNEL_HANGUP")
+)
+
+// outcomes of one collection attempt
+const (
+	inputTimeout = iota
+	inputInvalid
+)
+
+// Run drives the channel through the menu tree and returns the caller selection
+// Returns ErrMaxRetries when the retries of a menu are exhausted and ErrHangup if the channel goes away
+func Run(ctx context.Context, ari *aringo.ARInGO, channelID string, menu *Menu) (sel *Selection, err error) {
+	sess := &session{
+		ari:       ari,
+		channelID: channelID,
+	}
+	sess.sub = ari.Subscribe(aringo.AnyFilter( // one subscription so the digits and the playback ends are seen in order
+		aringo.AllFilters(aringo.TypeFilter("ChannelDtmfReceived", "StasisEnd", "ChannelDestroyed"),
+			aringo.ChannelFilter(channelID)),
+		aringo.AllFilters(aringo.TypeFilter("PlaybackFinished"), sess.playbackFilter)))
+	defer sess.sub.Close()
+	sel = new(Selection)
+	for {
+		var digits string
+		if digits, err = sess.menu(ctx, menu); err != nil {
+			return nil, err
+		}
+		sel.Path = append(sel.Path, digits)
+		sel.Menu, sel.Digits = menu, digits
+		if len(menu.Options) == 0 {
+			return
+		}
+		next := menu.Options[digits]
+		sel.Menu = next
+		if len(next.Prompts) == 0 {
+			return
+		}
+		menu = next
+	}
+}
+
+// session is the state of one run
+type session struct {
+	ari       *aringo.ARInGO
+	channelID string
+	sub       *aringo.Subscription
+
+	pbMux   sync.Mutex
+	playing string // id of the prompts playback, generated before playing so its end is not missed
+}
+
+// playbackFilter matches the events of the prompts playback
+func (sess *session) playbackFilter(ev *aringo.Event) bool {
+	sess.pbMux.Lock()
+	playbackID := sess.playing
+	sess.pbMux.Unlock()
+	return playbackID != "" && aringo.PlaybackFilter(playbackID)(ev)
+}
+
+func (sess *session) setPlaying(playbackID string) {
+	sess.pbMux.Lock()
+	sess.playing = playbackID
+	sess.pbMux.Unlock()
+}
+
+// menu collects the input of one menu, retrying until valid
+func (sess *session) menu(ctx context.Context, menu *Menu) (digits string, err error) {
+	prompts := menu.Prompts
+	for retry := 0; retry <= menu.maxRetries(); retry++ {
+		if digits, err = sess.collect(ctx, menu, prompts); err != nil {
+			return
+		}
+		failure := inputInvalid
+		switch {
+		case digits == "":
+			failure = inputTimeout
+		case len(menu.Options) == 0:
+			return
+		case menu.Options[digits] != nil:
+			return
+		}
+		prompts = menu.Prompts
+		if failure == inputTimeout && len(menu.TimeoutPrompts) != 0 {
+			prompts = append(append([]string{}, menu.TimeoutPrompts...), menu.Prompts...)
+		} else if failure == inputInvalid && len(menu.InvalidPrompts) != 0 {
+			prompts = append(append([]string{}, menu.InvalidPrompts...), menu.Prompts...)
+		}
+	}
+	return "", ErrMaxRetries
+}
+
+// collect plays the prompts and gathers the digits until complete, terminated or timed out
+func (sess *session) collect(ctx context.Context, menu *Menu, prompts []string) (digits string, err error) {
+	var playbackID string
+	if len(prompts) != 0 {
+		playbackID = sess.ari.NewID()
+		sess.setPlaying(playbackID)
+		if _, err = sess.ari.PlayWithID(sess.channelID, playbackID, prompts...); err != nil {
+			return
+		}
+	}
+	stopPlayback := func() {
+		if playbackID != "" {
+			sess.ari.StopPlayback(playbackID) // finished meanwhile if failing
+			playbackID = ""
+		}
+	}
+	defer stopPlayback()
+	var timeout <-chan time.Time
+	if playbackID == "" {
+		timeout = time.After(menu.firstDigitTimeout())
+	}
+	maxDigits := menu.maxDigits()
+	for {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-timeout:
+			return
+		case ev, ok := <-sess.sub.Events():
+			if !ok {
+				return "", sess.sub.Err()
+			}
+			switch ev.Type {
+			case "StasisEnd", "ChannelDestroyed":
+				return "", ErrHangup
+			case "PlaybackFinished":
+				if playbackID != "" && ev.PlaybackID() == playbackID {
+					playbackID = ""
+					if digits == "" {
+						timeout = time.After(menu.firstDigitTimeout())
+					}
+				}
+			case "ChannelDtmfReceived":
+				if playbackID != "" && menu.NoBargeIn {
+					continue
+				}
+				stopPlayback()
+				var dtmf aringo.ChannelDtmfReceived
+				if ev.Decode(&dtmf) != nil {
+					continue
+				}
+				if dtmf.Digit == menu.terminator() {
+					return
+				}
+				digits += dtmf.Digit
+				if (maxDigits != 0 && len(digits) >= maxDigits) || menu.complete(digits) {
+					return
+				}
+				timeout = time.After(menu.interDigitTimeout())
+			}
+		}
+	}
+}
@@ -0,0 +1,149 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package aringo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/url"
+)
+
+const redacted = "REDACTED"
+
+// credentialKeys are the parameters hidden out of the logs
+var credentialKeys = []string{"api_key", "password", "secret"}
+
+// WithLogger enables logging of the connection lifecycle, retries, dropped events and REST failures
+func WithLogger(logger *slog.Logger) Option {
+	return func(ari *ARInGO) {
+		ari.logger = logger
+	}
+}
+
+// WithWireLogging logs at debug level the REST requests/replies and the raw websocket frames
+// Credentials are redacted out of the URLs, the form bodies, the JSON bodies and the frames
+func WithWireLogging() Option {
+	return func(ari *ARInGO) {
+		ari.wireLogging = true
+	}
+}
+
+// nopHandler discards all records, used when no logger is configured
+type nopHandler struct{}
+
+func (nopHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (nopHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h nopHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h nopHandler) WithGroup(string) slog.Handler           { return h }
+
+var nopLogger = slog.New(nopHandler{})
+
+// log returns the configured logger or one discarding everything
+func (ari *ARInGO) log() *slog.Logger {
+	if ari.logger == nil {
+		return nopLogger
+	}
+	return ari.logger
+}
+
+// logWire logs one message exchanged with Asterisk if wire logging is enabled
+func (ari *ARInGO) logWire(msg string, args ...any) {
+	if ari.wireLogging {
+		ari.log().Debug(msg, args...)
+	}
+}
+
+// redactURL hides the credentials inside an URL, the whole URL if it cannot be parsed
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return redacted
+	}
+	if _, has := u.User.Password(); has {
+		u.User = url.UserPassword(u.User.Username(), redacted)
+	}
+	if u.RawQuery != "" {
+		u.RawQuery = redactValues(u.Query()).Encode()
+	}
+	return u.String()
+}
+
+// redactValues hides the credentials inside form values
+func redactValues(vals url.Values) url.Values {
+	var redactedVals url.Values
+	for _, key := range credentialKeys {
+		if _, has := vals[key]; !has {
+			continue
+		}
+		if redactedVals == nil {
+			redactedVals = make(url.Values, len(vals))
+			for k, v := range vals {
+				redactedVals[k] = v
+			}
+		}
+		redactedVals[key] = []string{redacted}
+	}
+	if redactedVals == nil {
+		return vals
+	}
+	return redactedVals
+}
+
+// isCredentialKey checks if the parameter holds credentials
+func isCredentialKey(key string) bool {
+	for _, credKey := range credentialKeys {
+		if key == credKey {
+			return true
+		}
+	}
+	return false
+}
+
+// redactJSON hides the credentials inside a JSON body, bodies which are not JSON are returned as they are
+// Besides the credential keys, the values of the dynamic configuration tuples with credential attributes are hidden
+func redactJSON(body []byte) string {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber() // keep the numbers as received
+	var v interface{}
+	if dec.Decode(&v) != nil || !redactJSONValue(v) {
+		return string(body)
+	}
+	redactedBody, err := json.Marshal(v)
+	if err != nil {
+		return string(body)
+	}
+	return string(redactedBody)
+}
+
+// redactJSONValue hides the credentials inside a decoded JSON value, returns true if any was found
+func redactJSONValue(v interface{}) (found bool) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, val := range v {
+			if isCredentialKey(key) {
+				v[key], found = redacted, true
+			} else if redactJSONValue(val) {
+				found = true
+			}
+		}
+		if attr, isStr := v["attribute"].(string); isStr && isCredentialKey(attr) { // ConfigTuple
+			if _, has := v["value"]; has {
+				v["value"], found = redacted, true
+			}
+		}
+	case []interface{}:
+		for _, val := range v {
+			if redactJSONValue(val) {
+				found = true
+			}
+		}
+	}
+	return
+}
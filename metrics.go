@@ -0,0 +1,71 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package aringo
+
+import (
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Metrics receives the runtime measurements of one ARInGO connection
+// Implementations need to be safe for concurrent use
+type Metrics interface {
+	EventReceived(evType string) // one event decoded out of the websocket, synthetic ones included
+	DecodeFailed()               // one frame which could not be decoded as event
+	ReconnectAttempted()
+	Reconnected()
+	ConnectionUp()   // websocket connected, the uptime starts counting
+	ConnectionDown() // websocket disconnected
+	// RESTCall measures one REST request, statusCode is 0 if no reply was received
+	RESTCall(method, resource string, statusCode int, duration time.Duration)
+	EventQueueDepth(depth int) // events waiting in the evChannel
+}
+
+// WithMetrics enables collecting runtime measurements into m
+func WithMetrics(m Metrics) Option {
+	return func(ari *ARInGO) {
+		ari.metrics = m
+	}
+}
+
+// staticSegments are the fixed parts of the ARI resource paths, all the others are ids
+var staticSegments = map[string]bool{
+	"applications": true, "subscription": true, "eventFilter": true,
+	"asterisk": true, "info": true, "ping": true, "modules": true, "logging": true, "rotate": true,
+	"config": true, "dynamic": true, "variable": true,
+	"bridges": true, "addChannel": true, "removeChannel": true, "videoSource": true,
+	"channels": true, "create": true, "externalMedia": true, "continue": true, "move": true, "redirect": true,
+	"answer": true, "ring": true, "dtmf": true, "mute": true, "hold": true, "moh": true, "silence": true,
+	"play": true, "record": true, "snoop": true, "dial": true, "rtp_statistics": true, "transfer_progress": true,
+	"endpoints": true, "sendMessage": true, "refer": true, "deviceStates": true, "mailboxes": true,
+	"events": true, "user": true, "playbacks": true, "control": true,
+	"recordings": true, "stored": true, "live": true, "file": true, "copy": true,
+	"stop": true, "pause": true, "unpause": true, "sounds": true,
+}
+
+// resourceTemplate replaces the ids inside the path of a REST request so requests can be aggregated
+// ie: http://127.0.0.1:8088/ari/channels/1234.5/play/pb1 becomes /channels/{id}/play/{id}
+func resourceTemplate(restURL string, u *url.URL) string {
+	segments := resourceSegments(restURL, u)
+	for i, segment := range segments {
+		if !staticSegments[segment] {
+			segments[i] = "{id}"
+		}
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+// resourceSegments returns the path segments of a REST request relative to the REST base URL
+func resourceSegments(restURL string, u *url.URL) []string {
+	path := u.Path
+	if base, err := url.Parse(restURL); err == nil {
+		path = strings.TrimPrefix(path, strings.TrimSuffix(base.Path, "/"))
+	}
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
@@ -0,0 +1,76 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package aringo
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordedMetrics stores the measurements as strings for comparison
+type recordedMetrics struct {
+	sync.Mutex
+	calls []string
+}
+
+func (rm *recordedMetrics) add(call string) {
+	rm.Lock()
+	rm.calls = append(rm.calls, call)
+	rm.Unlock()
+}
+
+func (rm *recordedMetrics) EventReceived(evType string) { rm.add("EventReceived:" + evType) }
+func (rm *recordedMetrics) DecodeFailed()               { rm.add("DecodeFailed") }
+func (rm *recordedMetrics) ReconnectAttempted()         { rm.add("ReconnectAttempted") }
+func (rm *recordedMetrics) Reconnected()                { rm.add("Reconnected") }
+func (rm *recordedMetrics) ConnectionUp()               { rm.add("ConnectionUp") }
+func (rm *recordedMetrics) ConnectionDown()             { rm.add("ConnectionDown") }
+func (rm *recordedMetrics) EventQueueDepth(depth int)   {}
+func (rm *recordedMetrics) RESTCall(method, resource string, statusCode int, duration time.Duration) {
+	rm.add(method + " " + resource + " " + http.StatusText(statusCode))
+}
+
+func TestResourceTemplate(t *testing.T) {
+	for reqURL, exp := range map[string]string{
+		"http://127.0.0.1:8088/ari/channels":                         "/channels",
+		"http://127.0.0.1:8088/ari/channels/1234.5/play/pb1":         "/channels/{id}/play/{id}",
+		"http://127.0.0.1:8088/ari/recordings/stored/rec1/file":      "/recordings/stored/{id}/file",
+		"http://127.0.0.1:8088/ari/asterisk/modules/res_pjsip.so":    "/asterisk/modules/{id}",
+		"http://127.0.0.1:8088/ari/endpoints/PJSIP/1001?x=y":         "/endpoints/{id}/{id}",
+		"http://127.0.0.1:8088/ari/bridges/br1/addChannel?channel=c": "/bridges/{id}/addChannel",
+	} {
+		u, _ := url.Parse(reqURL)
+		if rcv := resourceTemplate("http://127.0.0.1:8088/ari", u); rcv != exp {
+			t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp, rcv)
+		}
+	}
+}
+
+func TestMetricsCollection(t *testing.T) {
+	rm := new(recordedMetrics)
+	fake, ari := newFakeARI(t, WithMetrics(rm))
+	fake.mux.HandleFunc("/ari/channels/ch1", func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusNotFound)
+	})
+	fake.send(`invalid`)
+	fake.send(`{"type":"StasisStart"}`)
+	<-ari.evChannel
+	if _, err := ari.Call(HTTP_DELETE, ari.resourceURL("channels", "ch1"), nil); !IsReplyCode(err, http.StatusNotFound) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	exp := []string{"ConnectionUp", "DecodeFailed", "EventReceived:StasisStart", "DELETE /channels/{id} Not Found"}
+	rm.Lock()
+	defer rm.Unlock()
+	if !reflect.DeepEqual(exp, rm.calls) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp, rm.calls)
+	}
+}
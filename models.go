@@ -0,0 +1,170 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package aringo
+
+// CallerID as defined by ARI
+type CallerID struct {
+	Name   string `json:"name"`
+	Number string `json:"number"`
+}
+
+// DialplanCEP is the dialplan location of a channel
+type DialplanCEP struct {
+	Context  string `json:"context"`
+	Exten    string `json:"exten"`
+	Priority int    `json:"priority"`
+	AppName  string `json:"app_name,omitempty"`
+	AppData  string `json:"app_data,omitempty"`
+}
+
+// Channel as defined by ARI
+type Channel struct {
+	ID           string            `json:"id"`
+	Name         string            `json:"name"`
+	State        string            `json:"state"`
+	Caller       CallerID          `json:"caller"`
+	Connected    CallerID          `json:"connected"`
+	AccountCode  string            `json:"accountcode"`
+	Dialplan     DialplanCEP       `json:"dialplan"`
+	CreationTime string            `json:"creationtime"`
+	Language     string            `json:"language"`
+	ChannelVars  map[string]string `json:"channelvars,omitempty"`
+}
+
+// Bridge as defined by ARI
+type Bridge struct {
+	ID          string   `json:"id"`
+	Technology  string   `json:"technology"`
+	BridgeType  string   `json:"bridge_type"`
+	BridgeClass string   `json:"bridge_class"`
+	Creator     string   `json:"creator"`
+	Name        string   `json:"name"`
+	Channels    []string `json:"channels"`
+}
+
+// Playback as defined by ARI
+type Playback struct {
+	ID        string `json:"id"`
+	MediaURI  string `json:"media_uri"`
+	TargetURI string `json:"target_uri"`
+	Language  string `json:"language"`
+	State     string `json:"state"`
+}
+
+// StasisStart is posted when a channel enters the Stasis application
+type StasisStart struct {
+	Args           []string `json:"args"`
+	Channel        Channel  `json:"channel"`
+	ReplaceChannel *Channel `json:"replace_channel,omitempty"`
+}
+
+// StasisEnd is posted when a channel leaves the Stasis application
+type StasisEnd struct {
+	Channel Channel `json:"channel"`
+	Missed  bool    `json:"missed"` // generated on resync
+}
+
+// ChannelDestroyed is posted when a channel is hung up
+type ChannelDestroyed struct {
+	Channel  Channel `json:"channel"`
+	Cause    int     `json:"cause"`
+	CauseTxt string  `json:"cause_txt"`
+}
+
+// PlaybackEvent covers PlaybackStarted, PlaybackContinuing and PlaybackFinished
+type PlaybackEvent struct {
+	Playback Playback `json:"playback"`
+	Missed   bool     `json:"missed"` // generated on resync
+}
+
+// LiveRecording as defined by ARI
+type LiveRecording struct {
+	Name            string `json:"name"`
+	Format          string `json:"format"`
+	TargetURI       string `json:"target_uri"`
+	State           string `json:"state"`
+	Duration        int    `json:"duration,omitempty"`
+	TalkingDuration int    `json:"talking_duration,omitempty"`
+	SilenceDuration int    `json:"silence_duration,omitempty"`
+	Cause           string `json:"cause,omitempty"`
+}
+
+// RecordingEvent is posted on the state changes of a live recording: RecordingStarted, RecordingFinished and RecordingFailed
+type RecordingEvent struct {
+	Recording LiveRecording `json:"recording"`
+}
+
+// StoredRecording as defined by ARI, a finished recording kept by Asterisk
+type StoredRecording struct {
+	Name   string `json:"name"`
+	Format string `json:"format"`
+}
+
+// ConfigTuple is one attribute of a dynamic configuration object, as defined by ARI
+type ConfigTuple struct {
+	Attribute string `json:"attribute"`
+	Value     string `json:"value"`
+}
+
+// AsteriskInfo as defined by ARI, returned by GET /asterisk/info
+type AsteriskInfo struct {
+	Build  *BuildInfo  `json:"build,omitempty"`
+	System *SystemInfo `json:"system,omitempty"`
+	Config *ConfigInfo `json:"config,omitempty"`
+	Status *StatusInfo `json:"status,omitempty"`
+}
+
+// BuildInfo describes how Asterisk was built
+type BuildInfo struct {
+	OS      string `json:"os"`
+	Kernel  string `json:"kernel"`
+	Options string `json:"options"`
+	Machine string `json:"machine"`
+	Date    string `json:"date"`
+	User    string `json:"user"`
+}
+
+// SystemInfo describes the Asterisk system
+type SystemInfo struct {
+	Version  string `json:"version"`
+	EntityID string `json:"entity_id"`
+}
+
+// ConfigInfo is the Asterisk configuration
+type ConfigInfo struct {
+	Name            string  `json:"name"`
+	DefaultLanguage string  `json:"default_language"`
+	MaxChannels     int     `json:"max_channels,omitempty"`
+	MaxOpenFiles    int     `json:"max_open_files,omitempty"`
+	MaxLoad         float64 `json:"max_load,omitempty"`
+}
+
+// StatusInfo is the Asterisk uptime information
+type StatusInfo struct {
+	StartupTime    string `json:"startup_time"`
+	LastReloadTime string `json:"last_reload_time"`
+}
+
+// BridgeChannelEvent covers ChannelEnteredBridge and ChannelLeftBridge
+type BridgeChannelEvent struct {
+	Bridge  Bridge  `json:"bridge"`
+	Channel Channel `json:"channel"`
+}
+
+// ChannelTalking covers ChannelTalkingStarted and ChannelTalkingFinished, posted once TALK_DETECT is set on the channel
+type ChannelTalking struct {
+	Channel  Channel `json:"channel"`
+	Duration int     `json:"duration"` // milliseconds of talking, ChannelTalkingFinished only
+}
+
+// ChannelDtmfReceived is posted when a DTMF digit is received on a channel
+type ChannelDtmfReceived struct {
+	Digit      string  `json:"digit"`
+	DurationMs int     `json:"duration_ms"`
+	Channel    Channel `json:"channel"`
+}
@@ -0,0 +1,133 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+// Package prommetrics exposes the ARInGO runtime measurements as Prometheus metrics
+// It is a separate module so the Prometheus dependencies are only pulled by the applications using it
+package prommetrics
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cgrates/aringo"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics implements aringo.Metrics on top of Prometheus collectors
+type Metrics struct {
+	events            *prometheus.CounterVec
+	decodeFailures    prometheus.Counter
+	reconnectAttempts prometheus.Counter
+	reconnects        prometheus.Counter
+	connected         prometheus.Gauge
+	restDuration      *prometheus.HistogramVec
+	queueDepth        prometheus.Gauge
+
+	upMux   sync.RWMutex
+	upSince time.Time // zero while disconnected
+}
+
+var _ aringo.Metrics = (*Metrics)(nil)
+
+// New creates the collectors and registers them into reg
+// Use constLabels to differentiate between multiple connections registered into the same registry
+func New(reg prometheus.Registerer, namespace string, constLabels prometheus.Labels) (m *Metrics, err error) {
+	m = &Metrics{
+		events: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "ari", Name: "events_received_total",
+			Help: "Events received from Asterisk, by type.", ConstLabels: constLabels,
+		}, []string{"type"}),
+		decodeFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "ari", Name: "decode_failures_total",
+			Help: "Websocket frames which could not be decoded as events.", ConstLabels: constLabels,
+		}),
+		reconnectAttempts: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "ari", Name: "reconnect_attempts_total",
+			Help: "Attempts to reconnect the websocket.", ConstLabels: constLabels,
+		}),
+		reconnects: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "ari", Name: "reconnects_total",
+			Help: "Successful websocket reconnects.", ConstLabels: constLabels,
+		}),
+		connected: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace, Subsystem: "ari", Name: "websocket_connected",
+			Help: "1 while the websocket is connected.", ConstLabels: constLabels,
+		}),
+		restDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Subsystem: "ari", Name: "rest_request_duration_seconds",
+			Help: "Duration of the REST requests, by method, resource and status code.", ConstLabels: constLabels,
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "resource", "status"}),
+		queueDepth: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace, Subsystem: "ari", Name: "event_queue_depth",
+			Help: "Events waiting to be consumed out of the event channel.", ConstLabels: constLabels,
+		}),
+	}
+	uptime := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace, Subsystem: "ari", Name: "websocket_uptime_seconds",
+		Help: "Seconds since the websocket was connected, 0 while disconnected.", ConstLabels: constLabels,
+	}, m.uptime)
+	for _, c := range []prometheus.Collector{m.events, m.decodeFailures, m.reconnectAttempts,
+		m.reconnects, m.connected, m.restDuration, m.queueDepth, uptime} {
+		if err = reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+func (m *Metrics) uptime() float64 {
+	m.upMux.RLock()
+	defer m.upMux.RUnlock()
+	if m.upSince.IsZero() {
+		return 0
+	}
+	return time.Since(m.upSince).Seconds()
+}
+
+func (m *Metrics) EventReceived(evType string) {
+	m.events.WithLabelValues(evType).Inc()
+}
+
+func (m *Metrics) DecodeFailed() {
+	m.decodeFailures.Inc()
+}
+
+func (m *Metrics) ReconnectAttempted() {
+	m.reconnectAttempts.Inc()
+}
+
+func (m *Metrics) Reconnected() {
+	m.reconnects.Inc()
+}
+
+func (m *Metrics) ConnectionUp() {
+	m.upMux.Lock()
+	m.upSince = time.Now()
+	m.upMux.Unlock()
+	m.connected.Set(1)
+}
+
+func (m *Metrics) ConnectionDown() {
+	m.upMux.Lock()
+	m.upSince = time.Time{}
+	m.upMux.Unlock()
+	m.connected.Set(0)
+}
+
+func (m *Metrics) RESTCall(method, resource string, statusCode int, duration time.Duration) {
+	status := "none" // no reply received
+	if statusCode != 0 {
+		status = strconv.Itoa(statusCode)
+	}
+	m.restDuration.WithLabelValues(method, resource, status).Observe(duration.Seconds())
+}
+
+func (m *Metrics) EventQueueDepth(depth int) {
+	m.queueDepth.Set(float64(depth))
+}
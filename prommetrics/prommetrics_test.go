@@ -0,0 +1,77 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package prommetrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics(t *testing.T) {
+	reg := prometheus.NewPedanticRegistry()
+	m, err := New(reg, "cgrates", prometheus.Labels{"connection": "ast1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.ConnectionUp()
+	m.EventReceived("StasisStart")
+	m.EventReceived("StasisStart")
+	m.EventReceived("StasisEnd")
+	m.DecodeFailed()
+	m.ReconnectAttempted()
+	m.ReconnectAttempted()
+	m.Reconnected()
+	m.EventQueueDepth(3)
+	m.RESTCall("GET", "/channels", 200, 10*time.Millisecond)
+	m.RESTCall("POST", "/channels", 0, time.Second)
+
+	exp := `
+# HELP cgrates_ari_events_received_total Events received from Asterisk, by type.
+# TYPE cgrates_ari_events_received_total counter
+cgrates_ari_events_received_total{connection="ast1",type="StasisEnd"} 1
+cgrates_ari_events_received_total{connection="ast1",type="StasisStart"} 2
+# HELP cgrates_ari_decode_failures_total Websocket frames which could not be decoded as events.
+# TYPE cgrates_ari_decode_failures_total counter
+cgrates_ari_decode_failures_total{connection="ast1"} 1
+# HELP cgrates_ari_reconnect_attempts_total Attempts to reconnect the websocket.
+# TYPE cgrates_ari_reconnect_attempts_total counter
+cgrates_ari_reconnect_attempts_total{connection="ast1"} 2
+# HELP cgrates_ari_reconnects_total Successful websocket reconnects.
+# TYPE cgrates_ari_reconnects_total counter
+cgrates_ari_reconnects_total{connection="ast1"} 1
+# HELP cgrates_ari_websocket_connected 1 while the websocket is connected.
+# TYPE cgrates_ari_websocket_connected gauge
+cgrates_ari_websocket_connected{connection="ast1"} 1
+# HELP cgrates_ari_event_queue_depth Events waiting to be consumed out of the event channel.
+# TYPE cgrates_ari_event_queue_depth gauge
+cgrates_ari_event_queue_depth{connection="ast1"} 3
+`
+	if err = testutil.GatherAndCompare(reg, strings.NewReader(exp),
+		"cgrates_ari_events_received_total", "cgrates_ari_decode_failures_total", "cgrates_ari_reconnect_attempts_total",
+		"cgrates_ari_reconnects_total", "cgrates_ari_websocket_connected", "cgrates_ari_event_queue_depth"); err != nil {
+		t.Error(err)
+	}
+	if cnt := testutil.CollectAndCount(m.restDuration); cnt != 2 {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", 2, cnt)
+	}
+	if m.uptime() <= 0 {
+		t.Errorf("Expected positive uptime, received: %v", m.uptime())
+	}
+	m.ConnectionDown()
+	if m.uptime() != 0 || testutil.ToFloat64(m.connected) != 0 {
+		t.Errorf("Expected disconnected, received uptime: %v", m.uptime())
+	}
+
+	if _, err = New(reg, "cgrates", prometheus.Labels{"connection": "ast1"}); err == nil {
+		t.Error("Expected duplicate registration error")
+	}
+}
@@ -0,0 +1,250 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package queue
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cgrates/aringo"
+)
+
+// answer is an agent which answered the offered call
+type answer struct {
+	agent     *agent
+	channelID string
+}
+
+// distribute offers the waiting callers to the available agents whenever the queue changes
+func (q *Queue) distribute() {
+	for {
+		select {
+		case <-q.kickChan:
+		case <-q.closed:
+			return
+		}
+		q.mux.Lock()
+		for _, c := range q.callers { // in order, the longest waiting picks first
+			if c.ringing {
+				continue
+			}
+			agents := q.pick(c)
+			if len(agents) == 0 {
+				continue
+			}
+			if c.tried == nil {
+				c.tried = make(map[*agent]bool)
+			}
+			var ctx context.Context
+			ctx, c.cancelRing = context.WithCancel(context.Background())
+			c.ringing = true
+			for _, a := range agents {
+				a.Status = AgentRinging
+				c.tried[a] = true
+			}
+			go q.ring(ctx, c, agents)
+		}
+		q.mux.Unlock()
+	}
+}
+
+// available checks if the agent can be offered the caller, called with the lock held
+func available(a *agent, c *caller) bool {
+	if a.Status != AgentIdle || a.Paused || c.tried[a] {
+		return false
+	}
+	for _, skill := range c.skills {
+		var has bool
+		for _, agentSkill := range a.Skills {
+			if agentSkill == skill {
+				has = true
+				break
+			}
+		}
+		if !has {
+			return false
+		}
+	}
+	return true
+}
+
+// pick selects the agents to offer the caller according to the strategy, called with the lock held
+// The agents tried already are skipped, unless all the available ones were tried
+func (q *Queue) pick(c *caller) (picked []*agent) {
+	if picked = q.pickUntried(c); len(picked) == 0 && len(c.tried) != 0 {
+		c.tried = nil // start a new round
+		picked = q.pickUntried(c)
+	}
+	return
+}
+
+func (q *Queue) pickUntried(c *caller) (picked []*agent) {
+	if q.cfg.Strategy == StrategyRoundRobin {
+		for i := range q.agents {
+			idx := (q.rrNext + i) % len(q.agents)
+			if available(q.agents[idx], c) {
+				q.rrNext = idx + 1
+				return []*agent{q.agents[idx]}
+			}
+		}
+		return
+	}
+	for _, a := range q.agents {
+		if available(a, c) {
+			picked = append(picked, a)
+		}
+	}
+	if len(picked) < 2 || q.cfg.Strategy == StrategyRingAll {
+		return
+	}
+	best := picked[0]
+	for _, a := range picked[1:] {
+		if q.cfg.Strategy == StrategySkills && len(a.Skills) != len(best.Skills) {
+			if len(a.Skills) < len(best.Skills) { // keep the generalists for the callers needing them
+				best = a
+			}
+			continue
+		}
+		if a.IdleSince.Before(best.IdleSince) {
+			best = a
+		}
+	}
+	return []*agent{best}
+}
+
+// ring offers the caller to the agents, the first one answering is connected and the others are hung up
+func (q *Queue) ring(ctx context.Context, c *caller, agents []*agent) {
+	answered := make(chan *answer, len(agents))
+	var wg sync.WaitGroup
+	for _, a := range agents {
+		wg.Add(1)
+		go func(a *agent) {
+			defer wg.Done()
+			q.ringAgent(ctx, a, answered)
+		}(a)
+	}
+	go func() {
+		wg.Wait()
+		close(answered)
+	}()
+	var connected bool
+	for ans := range answered {
+		if !connected {
+			if cl := q.claimCall(c, ans); cl != nil {
+				connected = true
+				c.cancelRing() // stop ringing the others
+				go q.connect(c, ans, cl)
+				continue
+			}
+		}
+		q.ari.Hangup(ans.channelID, "") // answered late or the caller is gone
+		q.agentDone(ans.agent, false)
+	}
+	c.cancelRing()
+	if !connected {
+		q.mux.Lock()
+		c.ringing = false
+		q.kick()
+		q.mux.Unlock()
+	}
+}
+
+// ringAgent originates towards the agent and reports if answered within the ring timeout
+func (q *Queue) ringAgent(ctx context.Context, a *agent, answered chan<- *answer) {
+	ringCtx, cancel := context.WithTimeout(ctx, q.cfg.RingTimeout)
+	defer cancel()
+	p := aringo.OriginateParams{
+		Endpoint:  a.Endpoint,
+		App:       q.cfg.App,
+		ChannelID: q.ari.NewID(),
+		Timeout:   int(q.cfg.RingTimeout / time.Second),
+	}
+	ch, err := q.ari.OriginateAndWait(ringCtx, p)
+	if err == nil {
+		answered <- &answer{agent: a, channelID: ch.ID}
+		return
+	}
+	if ringCtx.Err() != nil {
+		q.ari.Hangup(p.ChannelID, "") // still ringing
+	}
+	q.agentDone(a, ctx.Err() == nil) // not missed if the caller was served by another agent or gone
+}
+
+// agentDone makes the agent available again after an offer
+func (q *Queue) agentDone(a *agent, missed bool) {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	a.Status = AgentIdle
+	if missed {
+		a.Missed++
+		q.noAnswer++
+	}
+	q.kick()
+}
+
+// claimCall takes the caller out of the waiting list and registers its call with the agent, nil if gone already
+// Both are done under the same lock so the hangups of the caller are tracked all along
+func (q *Queue) claimCall(c *caller, ans *answer) (cl *call) {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	if !q.unwait(c) {
+		return
+	}
+	cl = &call{bridgeID: q.ari.NewID(), callerChannelID: c.channelID, agent: ans.agent}
+	ans.agent.Status = AgentBusy
+	ans.agent.channelID = ans.channelID
+	q.calls[c.channelID] = cl
+	q.calls[ans.channelID] = cl
+	return
+}
+
+// connect moves the caller out of the holding bridge into a new mixing bridge together with the agent
+func (q *Queue) connect(c *caller, ans *answer, cl *call) {
+	wait := time.Since(c.joined)
+	_, err := q.ari.CreateBridge(aringo.BridgeParams{BridgeID: cl.bridgeID, Type: "mixing", Name: q.cfg.Name})
+	if err == nil {
+		err = q.ari.RemoveChannel(q.HoldingID, c.channelID)
+	}
+	if err == nil {
+		err = q.ari.AddChannel(cl.bridgeID, c.channelID, ans.channelID)
+	}
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	if cl.endedBy == c.channelID { // the caller hung up meanwhile, the call was torn down
+		q.abandoned++
+		c.finish(nil, ErrAbandoned)
+		return
+	}
+	if err != nil {
+		if cl.endedBy != "" { // torn down meanwhile by the agent hangup
+			c.finish(nil, err)
+			return
+		}
+		delete(q.calls, c.channelID)
+		delete(q.calls, ans.channelID)
+		ans.agent.Status = AgentIdle
+		ans.agent.channelID = ""
+		q.kick()
+		go func() {
+			q.ari.Hangup(ans.channelID, "")
+			q.ari.DestroyBridge(cl.bridgeID)
+		}()
+		c.finish(nil, err)
+		return
+	}
+	q.answered++
+	q.totalWait += wait
+	ans.agent.Answered++
+	c.finish(&Result{
+		AgentID:        ans.agent.ID,
+		AgentChannelID: ans.channelID,
+		BridgeID:       cl.bridgeID,
+		Wait:           wait,
+	}, nil)
+}
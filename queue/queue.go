@@ -0,0 +1,535 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+// Package queue distributes the calls waiting in ARI holding bridges to agents
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/cgrates/aringo"
+)
+
+// distribution strategies
+const (
+	StrategyRingAll     = "ringall"     // rings all the available agents, the first answering gets the call
+	StrategyLongestIdle = "longestidle" // rings the agent idle for the longest time
+	StrategyRoundRobin  = "roundrobin"  // rings the agents in turns, in the order they were added
+	StrategySkills      = "skills"      // rings the agent with the fewest skills covering the caller ones, longest idle first
+)
+
+// agent statuses
+const (
+	AgentIdle    = "idle"
+	AgentRinging = "ringing"
+	AgentBusy    = "busy"
+)
+
+const defaultRingTimeout = 15 * time.Second
+
+var (
+	ErrAbandoned       = errors.New("CALLER_ABANDONED")
+	ErrClosed          = errors.New("QUEUE_CLOSED")
+	ErrAgentExists     = errors.New("AGENT_EXISTS")
+	ErrAgentNotFound   = errors.New("AGENT_NOT_FOUND")
+	ErrUnknownCaller   = errors.New("UNKNOWN_CALLER")
+	ErrNoApplication   = errors.New("MISSING_APPLICATION")
+	ErrUnknownStrategy = errors.New("UNKNOWN_STRATEGY")
+)
+
+// Config are the settings of one queue
+type Config struct {
+	Name             string
+	App              string                      // Stasis application receiving the agent channels, the first one of the connection if empty
+	Strategy         string                      // StrategyRingAll if empty
+	MOHClass         string                      // music on hold class of the holding bridge
+	RingTimeout      time.Duration               // ringing time per agent attempt, 15s if 0
+	AnnounceInterval time.Duration               // interval between the position announcements
+	PositionPrompts  func(position int) []string // media announcing the position of a caller, no announcements if nil
+}
+
+// Agent answers the calls of the queue
+type Agent struct {
+	ID       string
+	Endpoint string   // dialed when offering a call, ie: PJSIP/1001
+	Skills   []string // the agent can only take callers requiring a subset of these
+}
+
+// AgentStatus is the state of one agent
+type AgentStatus struct {
+	Agent
+	Status    string
+	Paused    bool
+	IdleSince time.Time
+	Answered  int
+	Missed    int
+}
+
+// Result is the outcome of a caller connected to an agent
+type Result struct {
+	AgentID        string
+	AgentChannelID string
+	BridgeID       string // mixing bridge of the caller and the agent
+	Wait           time.Duration
+}
+
+// Stats are the counters of one queue
+type Stats struct {
+	Waiting       int
+	LongestWait   time.Duration // of the callers still waiting
+	Answered      int
+	Abandoned     int
+	NoAnswer      int           // agent attempts not answered
+	AverageWait   time.Duration // of the answered callers
+	AgentsIdle    int
+	AgentsRinging int
+	AgentsBusy    int
+	AgentsPaused  int
+}
+
+// agent is the internal state of one agent
+type agent struct {
+	AgentStatus
+	channelID string // while busy
+}
+
+// caller is one channel waiting in the queue
+type caller struct {
+	channelID  string
+	skills     []string
+	joined     time.Time
+	ringing    bool
+	tried      map[*agent]bool // offered without answer, skipped until all the candidates were tried
+	cancelRing context.CancelFunc
+	done       chan struct{}
+	res        *Result
+	err        error
+}
+
+func (c *caller) finish(res *Result, err error) {
+	c.res, c.err = res, err
+	close(c.done)
+}
+
+// call is one caller connected to an agent
+type call struct {
+	bridgeID        string
+	callerChannelID string
+	agent           *agent
+	endedBy         string // channel which hung up first
+}
+
+// Queue parks the callers in a holding bridge with music on hold and distributes them to its agents
+type Queue struct {
+	ari       *aringo.ARInGO
+	cfg       Config
+	HoldingID string // holding bridge of the waiting callers
+
+	mux       sync.RWMutex
+	agents    []*agent         // in the order they were added
+	callers   []*caller        // in the order they joined
+	calls     map[string]*call // indexed on both caller and agent channel ids
+	rrNext    int              // next agent index for the round robin
+	answered  int
+	abandoned int
+	noAnswer  int
+	totalWait time.Duration
+
+	sub       *aringo.Subscription
+	kickChan  chan struct{}
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// New creates the holding bridge of the queue and starts distributing its calls
+func New(ari *aringo.ARInGO, cfg Config) (q *Queue, err error) {
+	switch cfg.Strategy {
+	case "":
+		cfg.Strategy = StrategyRingAll
+	case StrategyRingAll, StrategyLongestIdle, StrategyRoundRobin, StrategySkills:
+	default:
+		return nil, ErrUnknownStrategy
+	}
+	if cfg.App == "" {
+		apps := ari.Applications()
+		if len(apps) == 0 {
+			return nil, ErrNoApplication
+		}
+		cfg.App = apps[0]
+	}
+	if cfg.RingTimeout == 0 {
+		cfg.RingTimeout = defaultRingTimeout
+	}
+	q = &Queue{
+		ari:      ari,
+		cfg:      cfg,
+		calls:    make(map[string]*call),
+		kickChan: make(chan struct{}, 1),
+		closed:   make(chan struct{}),
+	}
+	var holding *aringo.Bridge
+	if holding, err = ari.CreateBridge(aringo.BridgeParams{Type: "holding", Name: cfg.Name}); err != nil {
+		return nil, err
+	}
+	q.HoldingID = holding.ID
+	if err = ari.StartBridgeMOH(q.HoldingID, cfg.MOHClass); err != nil {
+		ari.DestroyBridge(q.HoldingID)
+		return nil, err
+	}
+	q.sub = q.subscribe()
+	go q.handleEvents()
+	go q.distribute()
+	if cfg.PositionPrompts != nil && cfg.AnnounceInterval > 0 {
+		go q.announce()
+	}
+	return
+}
+
+// AddAgent makes the agent available for the calls of the queue
+func (q *Queue) AddAgent(a Agent) error {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	if q.agent(a.ID) != nil {
+		return ErrAgentExists
+	}
+	q.agents = append(q.agents, &agent{AgentStatus: AgentStatus{Agent: a, Status: AgentIdle, IdleSince: time.Now()}})
+	q.kick()
+	return nil
+}
+
+// RemoveAgent stops offering calls to the agent, its current call is not affected
+func (q *Queue) RemoveAgent(agentID string) error {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	for i, a := range q.agents {
+		if a.ID == agentID {
+			q.agents = append(q.agents[:i], q.agents[i+1:]...)
+			if q.rrNext > i {
+				q.rrNext--
+			}
+			return nil
+		}
+	}
+	return ErrAgentNotFound
+}
+
+// PauseAgent stops, or resumes, offering calls to the agent
+func (q *Queue) PauseAgent(agentID string, paused bool) error {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	a := q.agent(agentID)
+	if a == nil {
+		return ErrAgentNotFound
+	}
+	a.Paused = paused
+	q.kick()
+	return nil
+}
+
+// Agents returns the status of the agents, in the order they were added
+func (q *Queue) Agents() (agents []AgentStatus) {
+	q.mux.RLock()
+	defer q.mux.RUnlock()
+	agents = make([]AgentStatus, len(q.agents))
+	for i, a := range q.agents {
+		agents[i] = a.AgentStatus
+	}
+	return
+}
+
+// Enqueue parks the channel in the holding bridge until an agent answers
+// Only the agents having all the skills are offered the call
+// Returns ErrAbandoned if the caller hangs up while waiting, on ctx done the caller is taken out of the queue
+func (q *Queue) Enqueue(ctx context.Context, channelID string, skills ...string) (res *Result, err error) {
+	c := &caller{channelID: channelID, skills: skills, joined: time.Now(), done: make(chan struct{})}
+	q.mux.Lock()
+	select {
+	case <-q.closed:
+		q.mux.Unlock()
+		return nil, ErrClosed
+	default:
+	}
+	q.callers = append(q.callers, c) // before bridging so the hangups are tracked
+	q.mux.Unlock()
+	if err = q.ari.AddChannel(q.HoldingID, channelID); err != nil {
+		q.claim(c)
+		return
+	}
+	q.mux.Lock()
+	q.kick()
+	q.mux.Unlock()
+	select {
+	case <-c.done:
+	case <-ctx.Done():
+		if q.claim(c) {
+			if c.cancelRing != nil { // stop ringing the agents offered the caller
+				c.cancelRing()
+			}
+			q.ari.RemoveChannel(q.HoldingID, channelID)
+			return nil, ctx.Err()
+		}
+		<-c.done // being connected or abandoned meanwhile
+	}
+	return c.res, c.err
+}
+
+// Position returns the 1 based position of the caller in the queue
+func (q *Queue) Position(channelID string) (int, error) {
+	q.mux.RLock()
+	defer q.mux.RUnlock()
+	for i, c := range q.callers {
+		if c.channelID == channelID {
+			return i + 1, nil
+		}
+	}
+	return 0, ErrUnknownCaller
+}
+
+// Stats returns the counters of the queue
+func (q *Queue) Stats() (st Stats) {
+	q.mux.RLock()
+	defer q.mux.RUnlock()
+	st = Stats{
+		Waiting:   len(q.callers),
+		Answered:  q.answered,
+		Abandoned: q.abandoned,
+		NoAnswer:  q.noAnswer,
+	}
+	if len(q.callers) != 0 {
+		st.LongestWait = time.Since(q.callers[0].joined)
+	}
+	if q.answered != 0 {
+		st.AverageWait = q.totalWait / time.Duration(q.answered)
+	}
+	for _, a := range q.agents {
+		switch {
+		case a.Paused:
+			st.AgentsPaused++
+		case a.Status == AgentIdle:
+			st.AgentsIdle++
+		case a.Status == AgentRinging:
+			st.AgentsRinging++
+		default:
+			st.AgentsBusy++
+		}
+	}
+	return
+}
+
+// Close stops the queue and destroys its holding bridge
+// The waiting callers get ErrClosed and are left in the Stasis application
+// The connected calls are followed until they end, the hangup of one side still tears down the other
+func (q *Queue) Close() (err error) {
+	q.closeOnce.Do(func() {
+		q.mux.Lock()
+		close(q.closed)
+		callers := q.callers
+		q.callers = nil
+		q.mux.Unlock()
+		for _, c := range callers {
+			if c.cancelRing != nil {
+				c.cancelRing()
+			}
+			c.finish(nil, ErrClosed)
+		}
+		err = q.ari.DestroyBridge(q.HoldingID)
+	})
+	return
+}
+
+// agent returns the agent with the given id, called with the lock held
+func (q *Queue) agent(agentID string) *agent {
+	for _, a := range q.agents {
+		if a.ID == agentID {
+			return a
+		}
+	}
+	return nil
+}
+
+// kick wakes up the distribution, called with the lock held
+func (q *Queue) kick() {
+	select {
+	case q.kickChan <- struct{}{}:
+	default: // already pending
+	}
+}
+
+// claim takes the caller out of the waiting list, false if gone already
+func (q *Queue) claim(c *caller) bool {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	return q.unwait(c)
+}
+
+// unwait removes the caller out of the waiting list, called with the lock held
+func (q *Queue) unwait(c *caller) bool {
+	for i, waiting := range q.callers {
+		if waiting == c {
+			q.callers = append(q.callers[:i], q.callers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// tracks checks if the channel is a waiting caller or part of a connected call
+func (q *Queue) tracks(channelID string) bool {
+	q.mux.RLock()
+	defer q.mux.RUnlock()
+	if _, has := q.calls[channelID]; has {
+		return true
+	}
+	for _, c := range q.callers {
+		if c.channelID == channelID {
+			return true
+		}
+	}
+	return false
+}
+
+// subscribe follows the hangups of the tracked channels
+func (q *Queue) subscribe() *aringo.Subscription {
+	return q.ari.Subscribe(aringo.AllFilters(aringo.TypeFilter("StasisEnd", "ChannelDestroyed"),
+		func(ev *aringo.Event) bool { return q.tracks(ev.ChannelID()) }))
+}
+
+// handleEvents processes the hangups of the callers and of the connected calls
+// Once the queue is closed it continues until the connected calls end
+func (q *Queue) handleEvents() {
+	defer func() { q.sub.Close() }()
+	closed := q.closed
+	for {
+		select {
+		case ev, ok := <-q.sub.Events():
+			if !ok { // overflown, the hangups lost meanwhile are found out of the channels still in Asterisk
+				q.sub = q.subscribe()
+				q.resync()
+				continue
+			}
+			q.hangup(ev.ChannelID())
+		case <-closed:
+			closed = nil
+		}
+		if closed == nil && q.drained() {
+			return
+		}
+	}
+}
+
+// resync processes the tracked channels gone out of Asterisk while the subscription was overflown
+// If the channels cannot be listed the waiting callers are taken out of the queue with the error
+func (q *Queue) resync() {
+	chs, err := q.ari.ListChannels()
+	q.mux.Lock()
+	if err != nil {
+		failed := q.callers
+		q.callers = nil
+		q.mux.Unlock()
+		for _, c := range failed {
+			if c.cancelRing != nil {
+				c.cancelRing()
+			}
+			q.ari.RemoveChannel(q.HoldingID, c.channelID)
+			c.finish(nil, err)
+		}
+		return
+	}
+	live := make(map[string]bool, len(chs))
+	for _, ch := range chs {
+		live[ch.ID] = true
+	}
+	var gone []string
+	for _, c := range q.callers {
+		if !live[c.channelID] {
+			gone = append(gone, c.channelID)
+		}
+	}
+	for channelID := range q.calls {
+		if !live[channelID] {
+			gone = append(gone, channelID)
+		}
+	}
+	q.mux.Unlock()
+	for _, channelID := range gone {
+		q.hangup(channelID)
+	}
+}
+
+// drained checks if no connected call is left
+func (q *Queue) drained() bool {
+	q.mux.RLock()
+	defer q.mux.RUnlock()
+	return len(q.calls) == 0
+}
+
+// hangup processes one tracked channel leaving Stasis
+func (q *Queue) hangup(channelID string) {
+	q.mux.Lock()
+	if cl, has := q.calls[channelID]; has { // either side ends the call
+		delete(q.calls, cl.callerChannelID)
+		delete(q.calls, cl.agent.channelID)
+		cl.endedBy = channelID
+		other := cl.agent.channelID
+		if channelID == other {
+			other = cl.callerChannelID
+		}
+		cl.agent.Status = AgentIdle
+		cl.agent.IdleSince = time.Now()
+		cl.agent.channelID = ""
+		q.kick()
+		q.mux.Unlock()
+		q.ari.Hangup(other, "")
+		q.ari.DestroyBridge(cl.bridgeID)
+		return
+	}
+	var abandoned *caller
+	for i, c := range q.callers {
+		if c.channelID == channelID {
+			abandoned = c
+			q.callers = append(q.callers[:i], q.callers[i+1:]...)
+			q.abandoned++
+			break
+		}
+	}
+	q.mux.Unlock()
+	if abandoned != nil {
+		if abandoned.cancelRing != nil {
+			abandoned.cancelRing()
+		}
+		abandoned.finish(nil, ErrAbandoned)
+	}
+}
+
+// announce plays periodically their position to the waiting callers not being offered to agents
+func (q *Queue) announce() {
+	ticker := time.NewTicker(q.cfg.AnnounceInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-q.closed:
+			return
+		}
+		q.mux.RLock()
+		positions := make(map[string]int)
+		for i, c := range q.callers {
+			if !c.ringing {
+				positions[c.channelID] = i + 1
+			}
+		}
+		q.mux.RUnlock()
+		for channelID, pos := range positions {
+			if media := q.cfg.PositionPrompts(pos); len(media) != 0 {
+				q.ari.Play(channelID, media...)
+			}
+		}
+	}
+}
@@ -0,0 +1,457 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cgrates/aringo"
+	"github.com/cgrates/aringo/aringotest"
+)
+
+// fakeAsterisk answers the REST calls of the queue, logging them
+// Originates towards PJSIP/answer are answered, PJSIP/busy are rejected and the others keep ringing
+// The channels are listed only out of live
+type fakeAsterisk struct {
+	ari          *aringo.ARInGO
+	mux          sync.Mutex
+	reqs         []string
+	hangupOnCall string   // channel hanging up while the call is set up
+	live         []string // channels listed
+}
+
+func newFakeAsterisk(t *testing.T) (fa *fakeAsterisk) {
+	fa = new(fakeAsterisk)
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		req := r.Method + " " + r.URL.Path
+		if r.URL.RawQuery != "" {
+			req += "?" + r.URL.RawQuery
+		}
+		fa.mux.Lock()
+		fa.reqs = append(fa.reqs, req)
+		hangupOnCall := fa.hangupOnCall
+		live := fa.live
+		fa.mux.Unlock()
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/ari/channels":
+			chs := make([]aringo.Channel, len(live))
+			for i, id := range live {
+				chs[i].ID = id
+			}
+			json.NewEncoder(rw).Encode(chs)
+		case r.Method == http.MethodPost && r.URL.Path == "/ari/channels":
+			id := r.URL.Query().Get("channelId")
+			rw.Write([]byte(`{"id":"` + id + `"}`))
+			switch r.URL.Query().Get("endpoint") {
+			case "PJSIP/answer":
+				aringotest.Inject(fa.ari, `{"type":"StasisStart","channel":{"id":"`+id+`","state":"Up"}}`)
+			case "PJSIP/busy":
+				aringotest.Inject(fa.ari, `{"type":"ChannelDestroyed","cause":17,"channel":{"id":"`+id+`"}}`)
+			}
+		case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/ari/bridges/") && strings.Count(r.URL.Path, "/") == 3:
+			if hangupOnCall != "" && r.URL.Query().Get("type") == "mixing" {
+				aringotest.Inject(fa.ari, `{"type":"StasisEnd","channel":{"id":"`+hangupOnCall+`"}}`)
+				for start := time.Now(); !fa.has("DELETE "+r.URL.Path) && time.Since(start) < time.Second; { // torn down before the reply
+					time.Sleep(5 * time.Millisecond)
+				}
+			}
+			rw.Write([]byte(`{"id":"` + path.Base(r.URL.Path) + `"}`))
+		case strings.Contains(r.URL.Path, "/play/"):
+			rw.Write([]byte(`{"id":"` + path.Base(r.URL.Path) + `","state":"playing"}`))
+		default:
+			rw.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	fa.ari = aringo.NewReplayARInGO(nil, aringo.WithRESTURL(srv.URL+"/ari"))
+	return
+}
+
+// has checks if the request was received
+func (fa *fakeAsterisk) has(req string) bool {
+	fa.mux.Lock()
+	defer fa.mux.Unlock()
+	for _, rcv := range fa.reqs {
+		if rcv == req {
+			return true
+		}
+	}
+	return false
+}
+
+// originated returns the channel ids originated towards the endpoint
+func (fa *fakeAsterisk) originated(endpoint string) (ids []string) {
+	fa.mux.Lock()
+	defer fa.mux.Unlock()
+	for _, req := range fa.reqs {
+		if strings.HasPrefix(req, "POST /ari/channels?") && strings.Contains(req, "endpoint="+strings.ReplaceAll(endpoint, "/", "%2F")) {
+			i := strings.Index(req, "channelId=") + len("channelId=")
+			ids = append(ids, strings.SplitN(req[i:], "&", 2)[0])
+		}
+	}
+	return
+}
+
+// played checks if the media was played on the channel
+func (fa *fakeAsterisk) played(channelID, media string) bool {
+	fa.mux.Lock()
+	defer fa.mux.Unlock()
+	for _, req := range fa.reqs {
+		if strings.HasPrefix(req, "POST /ari/channels/"+channelID+"/play/") && strings.Contains(req, media) {
+			return true
+		}
+	}
+	return false
+}
+
+func waitFor(t *testing.T, descr string, cond func() bool) {
+	t.Helper()
+	for start := time.Now(); !cond(); time.Sleep(5 * time.Millisecond) {
+		if time.Since(start) > time.Second {
+			t.Fatalf("timeout waiting for %s", descr)
+		}
+	}
+}
+
+func newQueue(t *testing.T, fa *fakeAsterisk, cfg Config) (q *Queue) {
+	t.Helper()
+	cfg.App = "test"
+	var err error
+	if q, err = New(fa.ari, cfg); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { q.Close() })
+	if !fa.has("POST /ari/bridges/"+q.HoldingID+"?name=support&type=holding") ||
+		!fa.has("POST /ari/bridges/"+q.HoldingID+"/moh") {
+		t.Error("Holding bridge not created")
+	}
+	return
+}
+
+func enqueue(t *testing.T, q *Queue, channelID string, skills ...string) (*Result, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	return q.Enqueue(ctx, channelID, skills...)
+}
+
+func TestNewErrors(t *testing.T) {
+	ari := aringo.NewReplayARInGO(nil)
+	if _, err := New(ari, Config{Strategy: "random"}); err != ErrUnknownStrategy {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", ErrUnknownStrategy, err)
+	}
+	if _, err := New(ari, Config{}); err != ErrNoApplication {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", ErrNoApplication, err)
+	}
+}
+
+func TestPick(t *testing.T) {
+	now := time.Now()
+	agents := func() []*agent {
+		return []*agent{
+			{AgentStatus: AgentStatus{Agent: Agent{ID: "a1", Skills: []string{"en", "de", "fr"}}, Status: AgentIdle, IdleSince: now.Add(-time.Minute)}},
+			{AgentStatus: AgentStatus{Agent: Agent{ID: "a2", Skills: []string{"en"}}, Status: AgentBusy, IdleSince: now.Add(-time.Hour)}},
+			{AgentStatus: AgentStatus{Agent: Agent{ID: "a3", Skills: []string{"en", "de"}}, Status: AgentIdle, IdleSince: now.Add(-time.Second)}},
+			{AgentStatus: AgentStatus{Agent: Agent{ID: "a4", Skills: []string{"en"}}, Status: AgentIdle, Paused: true, IdleSince: now.Add(-time.Hour)}},
+			{AgentStatus: AgentStatus{Agent: Agent{ID: "a5", Skills: []string{"en"}}, Status: AgentIdle, IdleSince: now}},
+		}
+	}
+	for _, tc := range []struct {
+		strategy string
+		rrNext   int
+		skills   []string
+		exp      []string
+		expNext  int
+	}{
+		{strategy: StrategyRingAll, exp: []string{"a1", "a3", "a5"}},
+		{strategy: StrategyRingAll, skills: []string{"de"}, exp: []string{"a1", "a3"}},
+		{strategy: StrategyRingAll, skills: []string{"it"}},
+		{strategy: StrategyLongestIdle, exp: []string{"a1"}},
+		{strategy: StrategyLongestIdle, skills: []string{"en", "de"}, exp: []string{"a1"}},
+		{strategy: StrategySkills, skills: []string{"en"}, exp: []string{"a5"}},
+		{strategy: StrategySkills, skills: []string{"de"}, exp: []string{"a3"}},
+		{strategy: StrategyRoundRobin, rrNext: 1, exp: []string{"a3"}, expNext: 3},
+		{strategy: StrategyRoundRobin, rrNext: 3, exp: []string{"a5"}, expNext: 5},
+		{strategy: StrategyRoundRobin, rrNext: 5, exp: []string{"a1"}, expNext: 1},
+		{strategy: StrategyRoundRobin, rrNext: 2, skills: []string{"fr"}, exp: []string{"a1"}, expNext: 1},
+	} {
+		q := &Queue{cfg: Config{Strategy: tc.strategy}, agents: agents(), rrNext: tc.rrNext}
+		var rcv []string
+		for _, a := range q.pick(&caller{skills: tc.skills}) {
+			rcv = append(rcv, a.ID)
+		}
+		if !reflect.DeepEqual(tc.exp, rcv) {
+			t.Errorf("%s %v \nExpected: <%+v>, \nReceived: <%+v>", tc.strategy, tc.skills, tc.exp, rcv)
+		}
+		if tc.strategy == StrategyRoundRobin && q.rrNext != tc.expNext {
+			t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", tc.expNext, q.rrNext)
+		}
+	}
+}
+
+func TestPickTried(t *testing.T) {
+	a1 := &agent{AgentStatus: AgentStatus{Agent: Agent{ID: "a1"}, Status: AgentIdle, IdleSince: time.Now().Add(-time.Hour)}}
+	a2 := &agent{AgentStatus: AgentStatus{Agent: Agent{ID: "a2"}, Status: AgentIdle, IdleSince: time.Now()}}
+	q := &Queue{cfg: Config{Strategy: StrategyLongestIdle}, agents: []*agent{a1, a2}}
+	c := &caller{tried: map[*agent]bool{a1: true}}
+	if rcv := q.pick(c); len(rcv) != 1 || rcv[0] != a2 {
+		t.Errorf("Unexpected pick: %+v", rcv)
+	}
+	c.tried[a2] = true // all tried, new round
+	if rcv := q.pick(c); len(rcv) != 1 || rcv[0] != a1 || c.tried != nil {
+		t.Errorf("Unexpected pick: %+v, tried: %+v", rcv, c.tried)
+	}
+}
+
+func TestQueueRingAll(t *testing.T) {
+	fa := newFakeAsterisk(t)
+	q := newQueue(t, fa, Config{Name: "support"})
+	q.AddAgent(Agent{ID: "a1", Endpoint: "PJSIP/ring"})
+	q.AddAgent(Agent{ID: "a2", Endpoint: "PJSIP/answer"})
+	if err := q.AddAgent(Agent{ID: "a2"}); err != ErrAgentExists {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", ErrAgentExists, err)
+	}
+	res, err := enqueue(t, q, "caller1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	agentChannels := fa.originated("PJSIP/answer")
+	if res.AgentID != "a2" || len(agentChannels) != 1 || res.AgentChannelID != agentChannels[0] {
+		t.Errorf("Unexpected result: %+v", res)
+	}
+	for _, req := range []string{
+		"POST /ari/bridges/" + q.HoldingID + "/addChannel?channel=caller1",
+		"POST /ari/bridges/" + res.BridgeID + "?name=support&type=mixing",
+		"POST /ari/bridges/" + q.HoldingID + "/removeChannel?channel=caller1",
+		"POST /ari/bridges/" + res.BridgeID + "/addChannel?channel=caller1%2C" + res.AgentChannelID,
+	} {
+		if !fa.has(req) {
+			t.Errorf("Missing request: %s", req)
+		}
+	}
+	waitFor(t, "ringing agent hung up", func() bool {
+		ids := fa.originated("PJSIP/ring")
+		return len(ids) == 1 && fa.has("DELETE /ari/channels/"+ids[0])
+	})
+	waitFor(t, "ringing agent idle", func() bool { return q.Stats().AgentsIdle == 1 })
+	if st := q.Stats(); st.Answered != 1 || st.NoAnswer != 0 || st.AgentsBusy != 1 || st.Waiting != 0 {
+		t.Errorf("Unexpected stats: %+v", st)
+	}
+	// the caller hangs up, the agent is hung up too
+	aringotest.Inject(fa.ari, `{"type":"StasisEnd","channel":{"id":"caller1"}}`)
+	waitFor(t, "call teardown", func() bool {
+		return fa.has("DELETE /ari/channels/"+res.AgentChannelID) && fa.has("DELETE /ari/bridges/"+res.BridgeID)
+	})
+	if agents := q.Agents(); agents[1].Status != AgentIdle || agents[1].Answered != 1 {
+		t.Errorf("Unexpected agents: %+v", agents)
+	}
+}
+
+func TestQueueNoAnswer(t *testing.T) {
+	fa := newFakeAsterisk(t)
+	q := newQueue(t, fa, Config{Name: "support", Strategy: StrategyLongestIdle, RingTimeout: 50 * time.Millisecond})
+	q.AddAgent(Agent{ID: "a1", Endpoint: "PJSIP/ring"}) // idle for the longest
+	q.AddAgent(Agent{ID: "a2", Endpoint: "PJSIP/answer"})
+	res, err := enqueue(t, q, "caller1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.AgentID != "a2" {
+		t.Errorf("Unexpected result: %+v", res)
+	}
+	if ids := fa.originated("PJSIP/ring"); len(ids) != 1 || !fa.has("DELETE /ari/channels/"+ids[0]) {
+		t.Errorf("Unexpected originates: %+v", ids)
+	}
+	if st := q.Stats(); st.NoAnswer != 1 || st.Answered != 1 {
+		t.Errorf("Unexpected stats: %+v", st)
+	}
+	if agents := q.Agents(); agents[0].Missed != 1 || agents[0].Status != AgentIdle {
+		t.Errorf("Unexpected agents: %+v", agents)
+	}
+}
+
+func TestQueueSkills(t *testing.T) {
+	fa := newFakeAsterisk(t)
+	q := newQueue(t, fa, Config{Name: "support", Strategy: StrategySkills})
+	q.AddAgent(Agent{ID: "a1", Endpoint: "PJSIP/answer", Skills: []string{"en"}})
+	q.AddAgent(Agent{ID: "a2", Endpoint: "PJSIP/answer", Skills: []string{"en", "de"}})
+	if res, err := enqueue(t, q, "caller1", "de"); err != nil {
+		t.Fatal(err)
+	} else if res.AgentID != "a2" {
+		t.Errorf("Unexpected result: %+v", res)
+	}
+	if res, err := enqueue(t, q, "caller2", "en"); err != nil {
+		t.Fatal(err)
+	} else if res.AgentID != "a1" {
+		t.Errorf("Unexpected result: %+v", res)
+	}
+}
+
+func TestQueueAbandon(t *testing.T) {
+	fa := newFakeAsterisk(t)
+	q := newQueue(t, fa, Config{Name: "support"})
+	q.AddAgent(Agent{ID: "a1", Endpoint: "PJSIP/answer"})
+	q.PauseAgent("a1", true)
+	if err := q.PauseAgent("a2", true); err != ErrAgentNotFound {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", ErrAgentNotFound, err)
+	}
+	errChan := make(chan error, 1)
+	go func() {
+		_, err := enqueue(t, q, "caller1")
+		errChan <- err
+	}()
+	waitFor(t, "caller queued", func() bool { pos, _ := q.Position("caller1"); return pos == 1 })
+	aringotest.Inject(fa.ari, `{"type":"ChannelDestroyed","channel":{"id":"caller1"}}`)
+	if err := <-errChan; err != ErrAbandoned {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", ErrAbandoned, err)
+	}
+	if _, err := q.Position("caller1"); err != ErrUnknownCaller {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", ErrUnknownCaller, err)
+	}
+	if st := q.Stats(); st.Abandoned != 1 || st.Waiting != 0 || st.AgentsPaused != 1 {
+		t.Errorf("Unexpected stats: %+v", st)
+	}
+	if len(fa.originated("PJSIP/answer")) != 0 {
+		t.Error("Paused agent offered a call")
+	}
+}
+
+func TestQueueResync(t *testing.T) {
+	fa := newFakeAsterisk(t)
+	fa.live = []string{"caller2"}
+	q := newQueue(t, fa, Config{Name: "support"})
+	errChan := make(chan error, 2)
+	for _, channelID := range []string{"caller1", "caller2"} {
+		go func(channelID string) {
+			_, err := enqueue(t, q, channelID)
+			errChan <- err
+		}(channelID)
+	}
+	waitFor(t, "callers queued", func() bool { return q.Stats().Waiting == 2 })
+	// the subscription ends while caller1 hangs up, the hangup is found out of the listed channels
+	q.sub.Close()
+	if err := <-errChan; err != ErrAbandoned {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", ErrAbandoned, err)
+	}
+	if pos, err := q.Position("caller2"); err != nil || pos != 1 {
+		t.Errorf("Unexpected position: %d, %v", pos, err)
+	}
+	// hangups are followed again
+	aringotest.Inject(fa.ari, `{"type":"StasisEnd","channel":{"id":"caller2"}}`)
+	if err := <-errChan; err != ErrAbandoned {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", ErrAbandoned, err)
+	}
+}
+
+func TestQueueContextDone(t *testing.T) {
+	fa := newFakeAsterisk(t)
+	q := newQueue(t, fa, Config{Name: "support", RingTimeout: time.Minute})
+	q.AddAgent(Agent{ID: "a1", Endpoint: "PJSIP/ring"})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := q.Enqueue(ctx, "caller1"); err != context.DeadlineExceeded {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", context.DeadlineExceeded, err)
+	}
+	if !fa.has("POST /ari/bridges/" + q.HoldingID + "/removeChannel?channel=caller1") {
+		t.Error("Caller not removed from the holding bridge")
+	}
+	// the agent offered the caller stops ringing, without counting a missed offer
+	waitFor(t, "ringing agent hung up", func() bool {
+		ids := fa.originated("PJSIP/ring")
+		return len(ids) == 1 && fa.has("DELETE /ari/channels/"+ids[0])
+	})
+	waitFor(t, "agent idle", func() bool { return q.Stats().AgentsIdle == 1 })
+	if st := q.Stats(); st.NoAnswer != 0 {
+		t.Errorf("Unexpected stats: %+v", st)
+	}
+}
+
+func TestQueueAnnounce(t *testing.T) {
+	fa := newFakeAsterisk(t)
+	q := newQueue(t, fa, Config{
+		Name:             "support",
+		AnnounceInterval: 20 * time.Millisecond,
+		PositionPrompts: func(pos int) []string {
+			return []string{"sound:queue-youarenext", "number:" + string(rune('0'+pos))}
+		},
+	})
+	for i, channelID := range []string{"caller1", "caller2"} {
+		go enqueue(t, q, channelID)
+		waitFor(t, "caller queued", func() bool { pos, _ := q.Position(channelID); return pos == i+1 })
+	}
+	waitFor(t, "position announcements", func() bool {
+		return fa.played("caller1", "number%3A1") && fa.played("caller2", "number%3A2")
+	})
+}
+
+func TestQueueClose(t *testing.T) {
+	fa := newFakeAsterisk(t)
+	q := newQueue(t, fa, Config{Name: "support"})
+	errChan := make(chan error, 1)
+	go func() {
+		_, err := enqueue(t, q, "caller1")
+		errChan <- err
+	}()
+	waitFor(t, "caller queued", func() bool { pos, _ := q.Position("caller1"); return pos == 1 })
+	if err := q.Close(); err != nil {
+		t.Error(err)
+	}
+	if err := <-errChan; err != ErrClosed {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", ErrClosed, err)
+	}
+	if _, err := enqueue(t, q, "caller2"); err != ErrClosed {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", ErrClosed, err)
+	}
+	if !fa.has("DELETE /ari/bridges/" + q.HoldingID) {
+		t.Error("Holding bridge not destroyed")
+	}
+}
+
+func TestQueueAbandonOnConnect(t *testing.T) {
+	fa := newFakeAsterisk(t)
+	fa.hangupOnCall = "caller1"
+	q := newQueue(t, fa, Config{Name: "support"})
+	q.AddAgent(Agent{ID: "a1", Endpoint: "PJSIP/answer"})
+	if _, err := enqueue(t, q, "caller1"); err != ErrAbandoned {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", ErrAbandoned, err)
+	}
+	agentChannels := fa.originated("PJSIP/answer")
+	if len(agentChannels) != 1 {
+		t.Fatalf("Unexpected originates: %+v", agentChannels)
+	}
+	waitFor(t, "agent hung up", func() bool { return fa.has("DELETE /ari/channels/" + agentChannels[0]) })
+	if st := q.Stats(); st.Abandoned != 1 || st.Answered != 0 || st.AgentsIdle != 1 {
+		t.Errorf("Unexpected stats: %+v", st)
+	}
+}
+
+func TestQueueCloseConnected(t *testing.T) {
+	fa := newFakeAsterisk(t)
+	q := newQueue(t, fa, Config{Name: "support"})
+	q.AddAgent(Agent{ID: "a1", Endpoint: "PJSIP/answer"})
+	res, err := enqueue(t, q, "caller1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Close(); err != nil {
+		t.Error(err)
+	}
+	// the connected call is still followed, the agent hangs up and the caller is hung up too
+	aringotest.Inject(fa.ari, `{"type":"StasisEnd","channel":{"id":"`+res.AgentChannelID+`"}}`)
+	waitFor(t, "call teardown", func() bool {
+		return fa.has("DELETE /ari/channels/caller1") && fa.has("DELETE /ari/bridges/"+res.BridgeID)
+	})
+}
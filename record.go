@@ -0,0 +1,152 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package aringo
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	RecordKindFrame = "frame" // websocket frame received from Asterisk
+	RecordKindREST  = "rest"  // REST request together with its reply
+)
+
+// RecordEntry is one line of a recording
+type RecordEntry struct {
+	Time    time.Time       `json:"time"`
+	Kind    string          `json:"kind"`
+	Frame   json.RawMessage `json:"frame,omitempty"`  // frames which are valid JSON
+	Raw     string          `json:"raw,omitempty"`    // frames which are not valid JSON
+	Method  string          `json:"method,omitempty"` // REST only
+	URL     string          `json:"url,omitempty"`
+	Request string          `json:"request,omitempty"`
+	Status  int             `json:"status,omitempty"`
+	Reply   string          `json:"reply,omitempty"`
+}
+
+// frame returns the recorded frame as received
+func (re *RecordEntry) frame() []byte {
+	if re.Frame != nil {
+		return re.Frame
+	}
+	return []byte(re.Raw)
+}
+
+// Recorder writes the frames received from Asterisk, and optionally the REST exchanges, as timestamped JSON lines
+type Recorder struct {
+	mux      sync.Mutex
+	enc      *json.Encoder
+	withREST bool
+	err      error
+}
+
+// NewRecorder creates a recorder writing into w, use it with WithRecorder
+func NewRecorder(w io.Writer, recordREST bool) *Recorder {
+	return &Recorder{
+		enc:      json.NewEncoder(w),
+		withREST: recordREST,
+	}
+}
+
+// WithRecorder records the event stream of the connection into rec
+func WithRecorder(rec *Recorder) Option {
+	return func(ari *ARInGO) {
+		ari.recorder = rec
+	}
+}
+
+// Err returns the first error encountered while writing the recording
+func (rec *Recorder) Err() error {
+	rec.mux.Lock()
+	defer rec.mux.Unlock()
+	return rec.err
+}
+
+func (rec *Recorder) write(re *RecordEntry) {
+	rec.mux.Lock()
+	defer rec.mux.Unlock()
+	if rec.err != nil {
+		return
+	}
+	rec.err = rec.enc.Encode(re)
+}
+
+// recordFrame records one frame as received
+func (rec *Recorder) recordFrame(raw []byte) {
+	re := &RecordEntry{Time: time.Now(), Kind: RecordKindFrame}
+	if json.Valid(raw) {
+		re.Frame = raw
+	} else {
+		re.Raw = string(raw)
+	}
+	rec.write(re)
+}
+
+// recordREST records one REST exchange, the URL and the bodies are expected with the credentials redacted
+func (rec *Recorder) recordREST(method, reqURL, reqBody string, status int, reply string) {
+	if !rec.withREST {
+		return
+	}
+	rec.write(&RecordEntry{
+		Time:    time.Now(),
+		Kind:    RecordKindREST,
+		Method:  method,
+		URL:     reqURL,
+		Request: reqBody,
+		Status:  status,
+		Reply:   reply,
+	})
+}
+
+// NewReplayARInGO returns an ARInGO without websocket connection, its events are fed out of recordings via Replay
+func NewReplayARInGO(evChannel chan map[string]interface{}, opts ...Option) (ari *ARInGO) {
+	ari = &ARInGO{
+		httpClient: new(http.Client),
+		evChannel:  evChannel,
+	}
+	for _, opt := range opts {
+		opt(ari)
+	}
+	return
+}
+
+// Replay feeds the frames out of a recording into the event stream, as if received from Asterisk
+// speed scales the original timing (ie: 2 replays twice as fast), 0 replays without delays
+func (ari *ARInGO) Replay(ctx context.Context, r io.Reader, speed float64) (err error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024) // frames can be big (ie: channel variables)
+	var lastTime time.Time
+	for scanner.Scan() {
+		var re RecordEntry
+		if err = json.Unmarshal(scanner.Bytes(), &re); err != nil {
+			return
+		}
+		if re.Kind != RecordKindFrame {
+			continue
+		}
+		if speed > 0 && !lastTime.IsZero() && re.Time.After(lastTime) {
+			select {
+			case <-time.After(time.Duration(float64(re.Time.Sub(lastTime)) / speed)):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		lastTime = re.Time
+		if err = ctx.Err(); err != nil {
+			return
+		}
+		ari.handleFrame(re.frame())
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,147 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package aringo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent use
+type syncBuffer struct {
+	sync.Mutex
+	buf bytes.Buffer
+}
+
+func (sb *syncBuffer) Write(p []byte) (int, error) {
+	sb.Lock()
+	defer sb.Unlock()
+	return sb.buf.Write(p)
+}
+
+func (sb *syncBuffer) String() string {
+	sb.Lock()
+	defer sb.Unlock()
+	return sb.buf.String()
+}
+
+func TestRecorder(t *testing.T) {
+	out := new(syncBuffer)
+	rec := NewRecorder(out, true)
+	fake, ari := newFakeARI(t, WithRecorder(rec))
+	fake.mux.HandleFunc("/ari/channels", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte(`[{"id":"ch1"}]`))
+	})
+	fake.mux.HandleFunc("/ari/asterisk/config/dynamic/res_pjsip/auth/1001", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte(`[{"attribute":"password","value":"CGRateS.org"}]`))
+	})
+	fake.send(`{"type":"StasisStart","channel":{"id":"ch1"}}`)
+	fake.send(`invalid`)
+	fake.send(`{"type":"StasisEnd","channel":{"id":"ch1"}}`)
+	<-ari.evChannel
+	<-ari.evChannel
+	if _, err := ari.Call(HTTP_GET, ari.resourceURL("channels")+"?api_key=cgrates:CGRateS.org", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ari.UpdateDynamicConfig("res_pjsip", "auth", "1001", []ConfigTuple{{Attribute: "password", Value: "CGRateS.org"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "CGRateS.org") {
+		t.Errorf("Credentials leaked: %s", out.String())
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("\nExpected: <%+v>, \nReceived: <%+v>", 5, lines)
+	}
+	entries := make([]RecordEntry, len(lines))
+	for i, line := range lines {
+		if err := json.Unmarshal([]byte(line), &entries[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if string(entries[0].Frame) != `{"type":"StasisStart","channel":{"id":"ch1"}}` {
+		t.Errorf("Unexpected frame: %s", entries[0].Frame)
+	}
+	if entries[1].Raw != "invalid" || entries[1].Frame != nil {
+		t.Errorf("Unexpected frame: %+v", entries[1])
+	}
+	exp := RecordEntry{
+		Kind:   RecordKindREST,
+		Method: HTTP_GET,
+		URL:    ari.restURL + "/channels?api_key=REDACTED",
+		Status: http.StatusOK,
+		Reply:  `[{"id":"ch1"}]`,
+	}
+	entries[3].Time = time.Time{}
+	if !(entries[3].Kind == exp.Kind && entries[3].URL == exp.URL && entries[3].Status == exp.Status &&
+		entries[3].Reply == exp.Reply && entries[3].Method == exp.Method) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp, entries[3])
+	}
+}
+
+func TestReplay(t *testing.T) {
+	start := time.Now()
+	recording := new(bytes.Buffer)
+	enc := json.NewEncoder(recording)
+	for _, re := range []RecordEntry{
+		{Time: start, Kind: RecordKindFrame, Frame: json.RawMessage(`{"type":"StasisStart","channel":{"id":"ch1"}}`)},
+		{Time: start.Add(50 * time.Millisecond), Kind: RecordKindREST, Method: HTTP_DELETE, URL: "http://127.0.0.1/ari/channels/ch1"},
+		{Time: start.Add(100 * time.Millisecond), Kind: RecordKindFrame, Raw: "invalid"},
+		{Time: start.Add(100 * time.Millisecond), Kind: RecordKindFrame, Frame: json.RawMessage(`{"type":"RESTResponse","request_id":"1"}`)},
+		{Time: start.Add(200 * time.Millisecond), Kind: RecordKindFrame, Frame: json.RawMessage(`{"type":"StasisEnd","channel":{"id":"ch1"}}`)},
+	} {
+		enc.Encode(re)
+	}
+	var decodeErrs int
+	ari := NewReplayARInGO(nil, WithDecodeErrorHandler(func(*DecodeError) { decodeErrs++ }))
+	sub := ari.Subscribe(TypeFilter("StasisStart", "StasisEnd"))
+	defer sub.Close()
+	replies := ari.Subscribe(TypeFilter(EventTypeRESTResponse))
+	defer replies.Close()
+
+	replayStart := time.Now()
+	if err := ari.Replay(context.Background(), bytes.NewReader(recording.Bytes()), 4); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(replayStart); elapsed < 50*time.Millisecond || elapsed > 150*time.Millisecond {
+		t.Errorf("Expected replay at 4x speed, took: %v", elapsed)
+	}
+	if decodeErrs != 1 {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", 1, decodeErrs)
+	}
+	for _, expType := range []string{"StasisStart", "StasisEnd"} {
+		select {
+		case ev := <-sub.Events():
+			if ev.Type != expType || ev.ChannelID() != "ch1" {
+				t.Errorf("Unexpected event: %+v", ev)
+			}
+		default:
+			t.Fatalf("Missing event: %s", expType)
+		}
+	}
+	select {
+	case ev := <-replies.Events():
+		t.Errorf("Reply replayed as event: %+v", ev)
+	default:
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := ari.Replay(ctx, bytes.NewReader(recording.Bytes()), 1); err != context.DeadlineExceeded {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", context.DeadlineExceeded, err)
+	}
+}
@@ -0,0 +1,362 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+// Package recording manages the live recordings of channels and bridges and the upload of the stored ones
+package recording
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/cgrates/aringo"
+)
+
+// recording states, as reported by Asterisk
+const (
+	StateQueued    = "queued"
+	StateRecording = "recording"
+	StatePaused    = "paused"
+	StateDone      = "done"
+	StateFailed    = "failed"
+	StateCanceled  = "canceled"
+)
+
+var (
+	ErrRecordingFailed   = errors.New("RECORDING_FAILED")
+	ErrRecordingCanceled = errors.New("RECORDING_CANCELED")
+	ErrRecordingFinished = errors.New("RECORDING_FINISHED")
+	ErrManagerClosed     = errors.New("MANAGER_CLOSED")
+)
+
+// Manager starts the recordings and follows their states out of the ARI events, created with NewManager
+type Manager struct {
+	ari *aringo.ARInGO
+
+	mux  sync.RWMutex
+	recs map[string]*Recording // recordings in progress, by name
+
+	sub       *aringo.Subscription
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewManager subscribes to the recording events of ari
+func NewManager(ari *aringo.ARInGO) (m *Manager) {
+	m = &Manager{
+		ari:  ari,
+		recs: make(map[string]*Recording),
+		done: make(chan struct{}),
+	}
+	m.sub = m.subscribe()
+	go m.run()
+	return
+}
+
+// subscribe follows the events of the registered recordings
+func (m *Manager) subscribe() *aringo.Subscription {
+	return m.ari.Subscribe(aringo.AllFilters(
+		aringo.TypeFilter("RecordingStarted", "RecordingFinished", "RecordingFailed"),
+		func(ev *aringo.Event) bool { return m.get(ev.RecordingName()) != nil }))
+}
+
+// StartChannel starts recording the audio of the channel
+func (m *Manager) StartChannel(channelID string, p aringo.RecordParams) (*Recording, error) {
+	return m.start(p, func(p aringo.RecordParams) (*aringo.LiveRecording, error) {
+		return m.ari.Record(channelID, p)
+	})
+}
+
+// StartBridge starts recording the mix of the bridge
+func (m *Manager) StartBridge(bridgeID string, p aringo.RecordParams) (*Recording, error) {
+	return m.start(p, func(p aringo.RecordParams) (*aringo.LiveRecording, error) {
+		return m.ari.RecordBridge(bridgeID, p)
+	})
+}
+
+// start registers the recording before creating it so no event is missed
+// The lock is not held during the REST call since the event filter (m.get) needs it, the dispatch of all events would stall
+func (m *Manager) start(p aringo.RecordParams,
+	record func(aringo.RecordParams) (*aringo.LiveRecording, error)) (rec *Recording, err error) {
+	select {
+	case <-m.done:
+		return nil, ErrManagerClosed
+	default:
+	}
+	if p.Name == "" {
+		p.Name = m.ari.NewID()
+	}
+	rec = &Recording{
+		Name:  p.Name,
+		m:     m,
+		state: aringo.LiveRecording{Name: p.Name, Format: p.Format},
+		done:  make(chan struct{}),
+	}
+	m.mux.Lock()
+	m.recs[rec.Name] = rec
+	m.mux.Unlock()
+	var live *aringo.LiveRecording
+	if live, err = record(p); err != nil {
+		m.remove(rec.Name)
+		return nil, err
+	}
+	rec.mux.Lock()
+	if rec.state.State == "" { // no event received yet
+		rec.state = *live
+	}
+	rec.mux.Unlock()
+	return
+}
+
+// Recordings returns a snapshot of the recordings in progress, ordered by name
+func (m *Manager) Recordings() (recs []aringo.LiveRecording) {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+	recs = make([]aringo.LiveRecording, 0, len(m.recs))
+	for _, rec := range m.recs {
+		recs = append(recs, rec.State())
+	}
+	sort.Slice(recs, func(i, j int) bool { return recs[i].Name < recs[j].Name })
+	return
+}
+
+// Upload streams the stored recording into w, deleting it from Asterisk afterwards if deleteAfter is set
+// The recording is kept if the upload fails
+func (m *Manager) Upload(ctx context.Context, name string, w io.Writer, deleteAfter bool) (n int64, err error) {
+	if n, err = m.ari.DownloadRecording(ctx, name, w); err != nil || !deleteAfter {
+		return
+	}
+	err = m.ari.DeleteStoredRecording(name)
+	return
+}
+
+// Done is closed once the manager is closed
+func (m *Manager) Done() <-chan struct{} {
+	return m.done
+}
+
+// Close stops following the recordings, the ones in progress continue inside Asterisk
+func (m *Manager) Close() {
+	m.closeOnce.Do(func() {
+		close(m.done)
+	})
+}
+
+func (m *Manager) get(name string) *Recording {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+	return m.recs[name]
+}
+
+func (m *Manager) remove(name string) {
+	m.mux.Lock()
+	delete(m.recs, name)
+	m.mux.Unlock()
+}
+
+// run handles the recording events until the manager is closed
+func (m *Manager) run() {
+	defer func() { m.sub.Close() }()
+	for {
+		var ev *aringo.Event
+		select {
+		case <-m.done:
+			return
+		case ev = <-m.sub.Events():
+		}
+		if ev == nil { // overflown, the states lost meanwhile are queried
+			m.sub = m.subscribe()
+			m.resync()
+			continue
+		}
+		var recEv aringo.RecordingEvent
+		if ev.Decode(&recEv) != nil {
+			continue
+		}
+		rec := m.get(recEv.Recording.Name)
+		if rec == nil {
+			continue
+		}
+		if ev.Type == "RecordingStarted" {
+			rec.update(recEv.Recording)
+			continue
+		}
+		if recEv.Recording.State == "" {
+			recEv.Recording.State = StateDone
+			if ev.Type == "RecordingFailed" {
+				recEv.Recording.State = StateFailed
+			}
+		}
+		rec.finish(recEv.Recording)
+	}
+}
+
+// resync refreshes the recordings whose events were lost while the subscription was overflown
+// The ones not live anymore are finished as done, the ones still being created are left to their reply
+func (m *Manager) resync() {
+	m.mux.RLock()
+	recs := make([]*Recording, 0, len(m.recs))
+	for _, rec := range m.recs {
+		recs = append(recs, rec)
+	}
+	m.mux.RUnlock()
+	for _, rec := range recs {
+		state := rec.State()
+		if state.State == "" {
+			continue
+		}
+		live, err := m.ari.GetLiveRecording(rec.Name)
+		switch {
+		case aringo.IsReplyCode(err, http.StatusNotFound):
+			state.State = StateDone
+			rec.finish(state)
+		case err != nil: // kept as known
+		case live.State == StateDone || live.State == StateFailed || live.State == StateCanceled:
+			rec.finish(*live)
+		default:
+			rec.update(*live)
+		}
+	}
+}
+
+// Recording is one live recording followed by the Manager
+type Recording struct {
+	Name string
+	m    *Manager
+
+	mux   sync.Mutex
+	state aringo.LiveRecording
+	done  chan struct{}
+}
+
+// State returns a snapshot of the recording
+func (rec *Recording) State() aringo.LiveRecording {
+	rec.mux.Lock()
+	defer rec.mux.Unlock()
+	return rec.state
+}
+
+// Done is closed once the recording finished, failed or was canceled
+func (rec *Recording) Done() <-chan struct{} {
+	return rec.done
+}
+
+// Wait blocks until the recording ends and returns its final state
+// The error is ErrRecordingFailed, with the cause reported by Asterisk, or ErrRecordingCanceled if no file was stored
+func (rec *Recording) Wait(ctx context.Context) (state aringo.LiveRecording, err error) {
+	select {
+	case <-rec.done:
+	case <-rec.m.done:
+		return rec.State(), ErrManagerClosed
+	case <-ctx.Done():
+		return rec.State(), ctx.Err()
+	}
+	state = rec.State()
+	switch state.State {
+	case StateFailed:
+		err = fmt.Errorf("%w: %s", ErrRecordingFailed, state.Cause)
+	case StateCanceled:
+		err = ErrRecordingCanceled
+	}
+	return
+}
+
+// Stop ends the recording and stores it, Done is closed once Asterisk confirms
+func (rec *Recording) Stop() error {
+	if rec.ended() {
+		return ErrRecordingFinished
+	}
+	return rec.m.ari.StopRecording(rec.Name)
+}
+
+// Cancel ends the recording and discards it
+func (rec *Recording) Cancel() (err error) {
+	if rec.ended() {
+		return ErrRecordingFinished
+	}
+	if err = rec.m.ari.CancelRecording(rec.Name); err != nil {
+		return
+	}
+	state := rec.State()
+	state.State = StateCanceled
+	rec.finish(state)
+	return
+}
+
+// Pause pauses the recording, the pause is not part of the stored file
+func (rec *Recording) Pause() error {
+	return rec.setPaused(true)
+}
+
+// Unpause resumes the paused recording
+func (rec *Recording) Unpause() error {
+	return rec.setPaused(false)
+}
+
+func (rec *Recording) setPaused(paused bool) (err error) {
+	if rec.ended() {
+		return ErrRecordingFinished
+	}
+	state := StateRecording
+	if paused {
+		state = StatePaused
+		err = rec.m.ari.PauseRecording(rec.Name)
+	} else {
+		err = rec.m.ari.UnpauseRecording(rec.Name)
+	}
+	if err != nil {
+		return
+	}
+	rec.mux.Lock()
+	if !rec.isEnded() {
+		rec.state.State = state
+	}
+	rec.mux.Unlock()
+	return
+}
+
+// update replaces the state of a recording still in progress
+func (rec *Recording) update(state aringo.LiveRecording) {
+	rec.mux.Lock()
+	defer rec.mux.Unlock()
+	if !rec.isEnded() {
+		rec.state = state
+	}
+}
+
+// finish sets the final state and stops following the recording, only once
+func (rec *Recording) finish(state aringo.LiveRecording) {
+	rec.mux.Lock()
+	if rec.isEnded() {
+		rec.mux.Unlock()
+		return
+	}
+	rec.state = state
+	close(rec.done)
+	rec.mux.Unlock()
+	rec.m.remove(rec.Name)
+}
+
+func (rec *Recording) ended() bool {
+	rec.mux.Lock()
+	defer rec.mux.Unlock()
+	return rec.isEnded()
+}
+
+// isEnded checks if the done channel was closed, called with the lock held
+func (rec *Recording) isEnded() bool {
+	select {
+	case <-rec.done:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,252 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package recording
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cgrates/aringo"
+	"github.com/cgrates/aringo/aringotest"
+)
+
+var testAudio = bytes.Repeat([]byte("RIFF"), 512)
+
+// fakeRecorder answers the REST calls of the recordings, logging them
+type fakeRecorder struct {
+	ari  *aringo.ARInGO
+	mux  sync.Mutex
+	reqs []string
+}
+
+func newFakeRecorder(t *testing.T) (fr *fakeRecorder) {
+	fr = new(fakeRecorder)
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		req := r.Method + " " + r.URL.Path
+		if r.URL.RawQuery != "" {
+			req += "?" + r.URL.RawQuery
+		}
+		fr.mux.Lock()
+		fr.reqs = append(fr.reqs, req)
+		fr.mux.Unlock()
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/record"):
+			segments := strings.Split(r.URL.Path, "/") // /ari/{resource}s/{id}/record
+			rw.Write([]byte(`{"name":"` + r.URL.Query().Get("name") + `","format":"` + r.URL.Query().Get("format") +
+				`","target_uri":"` + strings.TrimSuffix(segments[2], "s") + ":" + segments[3] + `","state":"queued"}`))
+		case r.URL.Path == "/ari/recordings/stored/rec1/file":
+			rw.Write(testAudio)
+		case r.Method == http.MethodGet && r.URL.Path == "/ari/recordings/live/rec1":
+			rw.Write([]byte(`{"name":"rec1","format":"wav","target_uri":"channel:ch1","state":"recording","duration":5}`))
+		case strings.HasSuffix(r.URL.Path, "/file"),
+			r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/ari/recordings/live/"):
+			rw.WriteHeader(http.StatusNotFound)
+			rw.Write([]byte(`{"message":"Recording not found"}`))
+		default:
+			rw.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	fr.ari = aringo.NewReplayARInGO(nil, aringo.WithRESTURL(srv.URL+"/ari"),
+		aringo.WithIDGenerator(func() string { return "gen1" }))
+	return
+}
+
+func (fr *fakeRecorder) requests() []string {
+	fr.mux.Lock()
+	defer fr.mux.Unlock()
+	return append([]string(nil), fr.reqs...)
+}
+
+func waitRecording(t *testing.T, rec *Recording) (aringo.LiveRecording, error) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	return rec.Wait(ctx)
+}
+
+func TestManagerChannel(t *testing.T) {
+	fr := newFakeRecorder(t)
+	m := NewManager(fr.ari)
+	defer m.Close()
+	rec, err := m.StartChannel("ch1", aringo.RecordParams{Name: "rec1", Format: "wav"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := aringo.LiveRecording{Name: "rec1", Format: "wav", TargetURI: "channel:ch1", State: StateQueued}
+	if rcv := rec.State(); !reflect.DeepEqual(exp, rcv) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp, rcv)
+	}
+	if rcv := m.Recordings(); !reflect.DeepEqual([]aringo.LiveRecording{exp}, rcv) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", []aringo.LiveRecording{exp}, rcv)
+	}
+	if err := rec.Pause(); err != nil {
+		t.Error(err)
+	} else if rcv := rec.State().State; rcv != StatePaused {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", StatePaused, rcv)
+	}
+	if err := rec.Unpause(); err != nil {
+		t.Error(err)
+	}
+	if err := rec.Stop(); err != nil {
+		t.Error(err)
+	}
+	aringotest.Inject(fr.ari,
+		`{"type":"RecordingStarted","recording":{"name":"other","state":"recording"}}`,
+		`{"type":"RecordingStarted","recording":{"name":"rec1","format":"wav","target_uri":"channel:ch1","state":"recording"}}`,
+		`{"type":"RecordingFinished","recording":{"name":"rec1","format":"wav","target_uri":"channel:ch1","state":"done","duration":12}}`,
+	)
+	state, err := waitRecording(t, rec)
+	if err != nil {
+		t.Error(err)
+	}
+	exp = aringo.LiveRecording{Name: "rec1", Format: "wav", TargetURI: "channel:ch1", State: StateDone, Duration: 12}
+	if !reflect.DeepEqual(exp, state) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp, state)
+	}
+	if rcv := m.Recordings(); len(rcv) != 0 {
+		t.Errorf("Unexpected recordings: %+v", rcv)
+	}
+	if err := rec.Stop(); err != ErrRecordingFinished {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", ErrRecordingFinished, err)
+	}
+	expReqs := []string{
+		"POST /ari/channels/ch1/record?format=wav&name=rec1",
+		"POST /ari/recordings/live/rec1/pause",
+		"DELETE /ari/recordings/live/rec1/pause",
+		"POST /ari/recordings/live/rec1/stop",
+	}
+	if rcv := fr.requests(); !reflect.DeepEqual(expReqs, rcv) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", expReqs, rcv)
+	}
+}
+
+func TestManagerResync(t *testing.T) {
+	fr := newFakeRecorder(t)
+	m := NewManager(fr.ari)
+	defer m.Close()
+	rec1, err := m.StartChannel("ch1", aringo.RecordParams{Name: "rec1", Format: "wav"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec2, err := m.StartChannel("ch2", aringo.RecordParams{Name: "rec2", Format: "wav"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the subscription ends while rec1 starts and rec2 finishes, the states are queried
+	m.sub.Close()
+	state, err := waitRecording(t, rec2)
+	if err != nil {
+		t.Error(err)
+	}
+	exp := aringo.LiveRecording{Name: "rec2", Format: "wav", TargetURI: "channel:ch2", State: StateDone}
+	if !reflect.DeepEqual(exp, state) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp, state)
+	}
+	exp = aringo.LiveRecording{Name: "rec1", Format: "wav", TargetURI: "channel:ch1", State: StateRecording, Duration: 5}
+	for start := time.Now(); !reflect.DeepEqual(exp, rec1.State()); time.Sleep(5 * time.Millisecond) {
+		if time.Since(start) > time.Second {
+			t.Fatalf("\nExpected: <%+v>, \nReceived: <%+v>", exp, rec1.State())
+		}
+	}
+	if rcv := m.Recordings(); !reflect.DeepEqual([]aringo.LiveRecording{exp}, rcv) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", []aringo.LiveRecording{exp}, rcv)
+	}
+	// the events are followed again
+	aringotest.Inject(fr.ari, `{"type":"RecordingFinished","recording":{"name":"rec1","state":"done"}}`)
+	if _, err = waitRecording(t, rec1); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestManagerBridgeFailed(t *testing.T) {
+	fr := newFakeRecorder(t)
+	m := NewManager(fr.ari)
+	defer m.Close()
+	rec, err := m.StartBridge("br1", aringo.RecordParams{Format: "wav"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Name != "gen1" || rec.State().TargetURI != "bridge:br1" {
+		t.Errorf("Unexpected recording: %+v", rec.State())
+	}
+	aringotest.Inject(fr.ari, `{"type":"RecordingFailed","recording":{"name":"gen1","state":"failed","cause":"disk full"}}`)
+	state, err := waitRecording(t, rec)
+	if !errors.Is(err, ErrRecordingFailed) || err.Error() != "RECORDING_FAILED: disk full" {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if state.State != StateFailed {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", StateFailed, state.State)
+	}
+}
+
+func TestManagerCancel(t *testing.T) {
+	fr := newFakeRecorder(t)
+	m := NewManager(fr.ari)
+	defer m.Close()
+	rec, err := m.StartChannel("ch1", aringo.RecordParams{Name: "rec1", Format: "wav"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.Cancel(); err != nil {
+		t.Error(err)
+	}
+	if _, err := waitRecording(t, rec); err != ErrRecordingCanceled {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", ErrRecordingCanceled, err)
+	}
+	if rcv := fr.requests(); rcv[len(rcv)-1] != "DELETE /ari/recordings/live/rec1" {
+		t.Errorf("Unexpected requests: %+v", rcv)
+	}
+}
+
+func TestManagerUpload(t *testing.T) {
+	fr := newFakeRecorder(t)
+	m := NewManager(fr.ari)
+	defer m.Close()
+	var buf bytes.Buffer
+	if n, err := m.Upload(context.Background(), "rec1", &buf, true); err != nil {
+		t.Error(err)
+	} else if n != int64(len(testAudio)) || !bytes.Equal(testAudio, buf.Bytes()) {
+		t.Errorf("Unexpected upload of %d bytes", n)
+	}
+	if _, err := m.Upload(context.Background(), "rec2", &buf, true); !aringo.IsReplyCode(err, http.StatusNotFound) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	expReqs := []string{ // the failed upload is not deleted
+		"GET /ari/recordings/stored/rec1/file",
+		"DELETE /ari/recordings/stored/rec1",
+		"GET /ari/recordings/stored/rec2/file",
+	}
+	if rcv := fr.requests(); !reflect.DeepEqual(expReqs, rcv) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", expReqs, rcv)
+	}
+}
+
+func TestManagerClose(t *testing.T) {
+	fr := newFakeRecorder(t)
+	m := NewManager(fr.ari)
+	rec, err := m.StartChannel("ch1", aringo.RecordParams{Name: "rec1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Close()
+	if _, err := waitRecording(t, rec); err != ErrManagerClosed {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", ErrManagerClosed, err)
+	}
+	if _, err := m.StartChannel("ch2", aringo.RecordParams{}); err != ErrManagerClosed {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", ErrManagerClosed, err)
+	}
+}
@@ -0,0 +1,655 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package aringo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const maxErrorReply = 4096 // bytes of an error reply read when streaming
+
+var (
+	ErrChannelDestroyed = errors.New("CHANNEL_DESTROYED")
+)
+
+// resourceURL builds the URL of a REST resource out of its path segments
+func (ari *ARInGO) resourceURL(segments ...string) string {
+	escaped := make([]string, len(segments))
+	for i, segment := range segments {
+		escaped[i] = url.PathEscape(segment)
+	}
+	return ari.restURL + "/" + strings.Join(escaped, "/")
+}
+
+// NewRESTARInGO returns an ARInGO without websocket connection, usable for the REST calls only
+// restURL is the base URL of the REST resources (ie: http://127.0.0.1:8088/ari)
+func NewRESTARInGO(restURL, username, password, userAgent string, opts ...Option) (ari *ARInGO) {
+	ari = &ARInGO{
+		httpClient: new(http.Client),
+		restURL:    strings.TrimSuffix(restURL, "/"),
+		username:   username,
+		password:   password,
+		userAgent:  userAgent,
+	}
+	for _, opt := range opts {
+		opt(ari)
+	}
+	return
+}
+
+// callDecode executes the REST request with the parameters inside the URL and decodes the reply into v
+// The reply is discarded if v is nil
+func (ari *ARInGO) callDecode(ctx context.Context, method, reqURL string, params url.Values, v interface{},
+	opts ...CallOption) (err error) {
+	if len(params) != 0 {
+		reqURL += "?" + params.Encode()
+	}
+	var reply []byte
+	if reply, err = ari.CallContext(ctx, method, reqURL, nil, opts...); err != nil ||
+		v == nil { // no reply expected
+		return
+	}
+	return json.Unmarshal(reply, v)
+}
+
+// createWithID creates a resource with client supplied id, retrying as idempotent
+// Since the id is ours, a conflict on retry means a previous attempt succeeded so the resource is fetched from getURL
+func (ari *ARInGO) createWithID(ctx context.Context, method, reqURL string, params url.Values, getURL string, v interface{},
+	opts ...CallOption) (err error) {
+	if err = ari.callDecode(ctx, method, reqURL, params, v, append(opts, withClientID())...); err == errCreatedOnRetry {
+		err = ari.callDecode(ctx, HTTP_GET, getURL, nil, v)
+	}
+	return
+}
+
+// OriginateParams are the parameters of POST /channels
+// Either Extension/Context/Priority/Label or App/AppArgs should be populated
+type OriginateParams struct {
+	Endpoint       string
+	Extension      string
+	Context        string
+	Priority       int
+	Label          string
+	App            string
+	AppArgs        string
+	CallerID       string
+	Timeout        int // seconds
+	ChannelID      string
+	OtherChannelID string
+	Originator     string
+	Formats        string
+	Variables      ChannelVariables // set on the channel before dialing, ie: SIP headers with AddSIPHeader
+}
+
+// variablesOption sends the variables in the JSON body, the only way ARI accepts them
+func (p *OriginateParams) variablesOption() (opts []CallOption) {
+	if len(p.Variables) != 0 {
+		opts = append(opts, WithJSONBody(map[string]ChannelVariables{"variables": p.Variables}))
+	}
+	return
+}
+
+func (p *OriginateParams) values() url.Values {
+	data := url.Values{"endpoint": {p.Endpoint}}
+	for key, val := range map[string]string{
+		"extension":      p.Extension,
+		"context":        p.Context,
+		"label":          p.Label,
+		"app":            p.App,
+		"appArgs":        p.AppArgs,
+		"callerId":       p.CallerID,
+		"channelId":      p.ChannelID,
+		"otherChannelId": p.OtherChannelID,
+		"originator":     p.Originator,
+		"formats":        p.Formats,
+	} {
+		if val != "" {
+			data.Set(key, val)
+		}
+	}
+	if p.Priority != 0 {
+		data.Set("priority", strconv.Itoa(p.Priority))
+	}
+	if p.Timeout != 0 {
+		data.Set("timeout", strconv.Itoa(p.Timeout))
+	}
+	return data
+}
+
+// setIDs generates the missing channel ids
+func (p *OriginateParams) setIDs(ari *ARInGO) {
+	if p.ChannelID == "" {
+		p.ChannelID = ari.NewID()
+	}
+	if p.OtherChannelID == "" && strings.HasPrefix(p.Endpoint, "Local/") {
+		p.OtherChannelID = ari.NewID()
+	}
+}
+
+// Originate creates a new channel
+// The channel ids are generated if not provided so the originate can be safely retried
+func (ari *ARInGO) Originate(p OriginateParams) (ch *Channel, err error) {
+	p.setIDs(ari)
+	ch = new(Channel)
+	if err = ari.createWithID(context.Background(), HTTP_POST, ari.resourceURL("channels"), p.values(),
+		ari.resourceURL("channels", p.ChannelID), ch, p.variablesOption()...); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// OriginateAndWait creates a new channel into a Stasis application and waits for it to enter the application
+// Returns ErrChannelDestroyed if the channel is hung up before entering Stasis
+func (ari *ARInGO) OriginateAndWait(ctx context.Context, p OriginateParams) (ch *Channel, err error) {
+	p.setIDs(ari)
+	sub := ari.Subscribe(AllFilters(TypeFilter("StasisStart", "ChannelDestroyed"),
+		ChannelFilter(p.ChannelID))) // before originating, events can come before the reply
+	defer sub.Close()
+	if _, err = ari.Originate(p); err != nil {
+		return
+	}
+	var ev *Event
+	if ev, err = sub.Next(ctx); err != nil {
+		return nil, err
+	}
+	if ev.Type == "ChannelDestroyed" {
+		var destroyed ChannelDestroyed
+		ev.Decode(&destroyed)
+		return nil, fmt.Errorf("%w: %s", ErrChannelDestroyed, destroyed.CauseTxt)
+	}
+	var start StasisStart
+	if err = ev.Decode(&start); err != nil {
+		return nil, err
+	}
+	return &start.Channel, nil
+}
+
+// BridgeParams are the parameters of POST /bridges/{bridgeId}
+type BridgeParams struct {
+	BridgeID   string // generated if empty
+	Type       string // comma separated list of bridge type attributes (mixing, holding, dtmf_events, proxy_media, video_sfu, video_single)
+	Name       string
+	DTMFEvents bool // adds dtmf_events to Type, reporting the DTMF of the bridged channels while still passing it through
+}
+
+// bridgeType returns Type including the attributes enabled by the flags
+func (p *BridgeParams) bridgeType() string {
+	if !p.DTMFEvents {
+		return p.Type
+	}
+	for _, attr := range strings.Split(p.Type, ",") {
+		if attr == bridgeDTMFEvents {
+			return p.Type
+		}
+	}
+	if p.Type == "" {
+		return bridgeDTMFEvents
+	}
+	return p.Type + "," + bridgeDTMFEvents
+}
+
+// CreateBridge creates a new bridge
+func (ari *ARInGO) CreateBridge(p BridgeParams) (br *Bridge, err error) {
+	if p.BridgeID == "" {
+		p.BridgeID = ari.NewID()
+	}
+	params := url.Values{}
+	if bridgeType := p.bridgeType(); bridgeType != "" {
+		params.Set("type", bridgeType)
+	}
+	if p.Name != "" {
+		params.Set("name", p.Name)
+	}
+	br = new(Bridge)
+	bridgeURL := ari.resourceURL("bridges", p.BridgeID)
+	if err = ari.createWithID(context.Background(), HTTP_POST, bridgeURL, params, bridgeURL, br); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// Play starts playing the media URIs on the channel
+func (ari *ARInGO) Play(channelID string, media ...string) (*Playback, error) {
+	return ari.play("channels", channelID, ari.NewID(), media)
+}
+
+// PlayWithID starts playing the media URIs to the channel using the given playback id
+// Generate the id with NewID and subscribe with PlaybackFilter before playing so no event of the playback is missed
+func (ari *ARInGO) PlayWithID(channelID, playbackID string, media ...string) (*Playback, error) {
+	return ari.play("channels", channelID, playbackID, media)
+}
+
+// PlayOnBridge starts playing the media URIs to all channels of the bridge
+func (ari *ARInGO) PlayOnBridge(bridgeID string, media ...string) (*Playback, error) {
+	return ari.play("bridges", bridgeID, ari.NewID(), media)
+}
+
+// play starts the playback with the given id on a channel or bridge
+func (ari *ARInGO) play(resource, resourceID, playbackID string, media []string) (pb *Playback, err error) {
+	pb = new(Playback)
+	if err = ari.createWithID(context.Background(), HTTP_POST, ari.resourceURL(resource, resourceID, "play", playbackID),
+		url.Values{"media": media}, ari.resourceURL("playbacks", playbackID), pb); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// PlayAndWait plays the media URIs on the channel and waits for the playback to finish
+// The returned playback reflects the final state (done or failed)
+func (ari *ARInGO) PlayAndWait(ctx context.Context, channelID string, media ...string) (pb *Playback, err error) {
+	playbackID := ari.NewID()
+	sub := ari.Subscribe(AllFilters(TypeFilter("PlaybackFinished"), PlaybackFilter(playbackID)))
+	defer sub.Close()
+	if _, err = ari.play("channels", channelID, playbackID, media); err != nil {
+		return
+	}
+	var ev *Event
+	if ev, err = sub.Next(ctx); err != nil {
+		return
+	}
+	var pbEv PlaybackEvent
+	if err = ev.Decode(&pbEv); err != nil {
+		return nil, err
+	}
+	return &pbEv.Playback, nil
+}
+
+// RecordParams are the parameters of POST /channels/{channelId}/record and /bridges/{bridgeId}/record
+type RecordParams struct {
+	Name               string // generated if empty
+	Format             string // ie: wav
+	MaxDurationSeconds int
+	MaxSilenceSeconds  int
+	IfExists           string // fail, overwrite or append
+	Beep               bool
+	TerminateOn        string // DTMF input to terminate recording: none, any, * or #
+}
+
+func (p *RecordParams) values() url.Values {
+	params := url.Values{
+		"name":   {p.Name},
+		"format": {p.Format},
+	}
+	if p.MaxDurationSeconds != 0 {
+		params.Set("maxDurationSeconds", strconv.Itoa(p.MaxDurationSeconds))
+	}
+	if p.MaxSilenceSeconds != 0 {
+		params.Set("maxSilenceSeconds", strconv.Itoa(p.MaxSilenceSeconds))
+	}
+	if p.IfExists != "" {
+		params.Set("ifExists", p.IfExists)
+	}
+	if p.Beep {
+		params.Set("beep", "true")
+	}
+	if p.TerminateOn != "" {
+		params.Set("terminateOn", p.TerminateOn)
+	}
+	return params
+}
+
+// Record starts recording the channel
+func (ari *ARInGO) Record(channelID string, p RecordParams) (*LiveRecording, error) {
+	return ari.record("channels", channelID, p)
+}
+
+// RecordBridge starts recording the mix of the bridge
+func (ari *ARInGO) RecordBridge(bridgeID string, p RecordParams) (*LiveRecording, error) {
+	return ari.record("bridges", bridgeID, p)
+}
+
+func (ari *ARInGO) record(resource, resourceID string, p RecordParams) (rec *LiveRecording, err error) {
+	if p.Name == "" {
+		p.Name = ari.NewID()
+	}
+	rec = new(LiveRecording)
+	ctx := context.Background()
+	if err = ari.callDecode(ctx, HTTP_POST, ari.resourceURL(resource, resourceID, "record"), p.values(), rec,
+		withClientID()); err == errCreatedOnRetry {
+		// unlike the ids, names are often chosen by the users so the recording found is ours only if of the same target
+		if err = ari.callDecode(ctx, HTTP_GET, ari.resourceURL("recordings", "live", p.Name), nil, rec); err == nil &&
+			rec.TargetURI != strings.TrimSuffix(resource, "s")+":"+resourceID {
+			err = NewErrUnexpectedReplyCode(http.StatusConflict)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+// GetLiveRecording returns the details of a recording in progress
+func (ari *ARInGO) GetLiveRecording(name string) (rec *LiveRecording, err error) {
+	rec = new(LiveRecording)
+	if err = ari.callDecode(context.Background(), HTTP_GET, ari.resourceURL("recordings", "live", name), nil, rec); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// StopRecording stops the live recording and stores it
+func (ari *ARInGO) StopRecording(name string) error {
+	return ari.callDecode(context.Background(), HTTP_POST, ari.resourceURL("recordings", "live", name, "stop"), nil, nil)
+}
+
+// CancelRecording stops the live recording and discards it
+func (ari *ARInGO) CancelRecording(name string) error {
+	return ari.callDecode(context.Background(), HTTP_DELETE, ari.resourceURL("recordings", "live", name), nil, nil)
+}
+
+// PauseRecording pauses the live recording, the pause is not part of the stored file
+func (ari *ARInGO) PauseRecording(name string) error {
+	return ari.callDecode(context.Background(), HTTP_POST, ari.resourceURL("recordings", "live", name, "pause"), nil, nil)
+}
+
+// UnpauseRecording resumes a paused live recording
+func (ari *ARInGO) UnpauseRecording(name string) error {
+	return ari.callDecode(context.Background(), HTTP_DELETE, ari.resourceURL("recordings", "live", name, "pause"), nil, nil)
+}
+
+// ListStoredRecordings returns the finished recordings kept by Asterisk
+func (ari *ARInGO) ListStoredRecordings() (recs []*StoredRecording, err error) {
+	err = ari.callDecode(context.Background(), HTTP_GET, ari.resourceURL("recordings", "stored"), nil, &recs)
+	return
+}
+
+// GetStoredRecording returns the details of one stored recording
+func (ari *ARInGO) GetStoredRecording(name string) (rec *StoredRecording, err error) {
+	rec = new(StoredRecording)
+	if err = ari.callDecode(context.Background(), HTTP_GET, ari.resourceURL("recordings", "stored", name), nil, rec); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// DeleteStoredRecording removes the stored recording from Asterisk
+func (ari *ARInGO) DeleteStoredRecording(name string) error {
+	return ari.callDecode(context.Background(), HTTP_DELETE, ari.resourceURL("recordings", "stored", name), nil, nil)
+}
+
+// DownloadRecording streams the file of the stored recording into w, returning the number of bytes written
+// The file is always fetched over HTTP, without buffering it in memory
+func (ari *ARInGO) DownloadRecording(ctx context.Context, name string, w io.Writer) (n int64, err error) {
+	var u *url.URL
+	if u, err = url.Parse(ari.resourceURL("recordings", "stored", name, "file")); err != nil {
+		return
+	}
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(ctx, HTTP_GET, u.String(), nil); err != nil {
+		return
+	}
+	req.Header.Set("User-Agent", ari.userAgent)
+	req.SetBasicAuth(ari.username, ari.password)
+	ari.logWire("ARI request", "method", HTTP_GET, "url", redactURL(u.String()))
+	start := time.Now()
+	var resp *http.Response
+	resp, err = ari.httpClient.Do(req)
+	var statusCode int
+	if resp != nil {
+		statusCode = resp.StatusCode
+	}
+	if ari.metrics != nil {
+		ari.metrics.RESTCall(HTTP_GET, resourceTemplate(ari.restURL, u), statusCode, time.Since(start))
+	}
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	ari.logWire("ARI reply", "method", HTTP_GET, "url", redactURL(u.String()), "status", statusCode)
+	if statusCode != http.StatusOK {
+		reply, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorReply))
+		return 0, replyCodeError(statusCode, reply)
+	}
+	return io.Copy(w, resp.Body)
+}
+
+// ListChannels returns the active channels
+func (ari *ARInGO) ListChannels() (chs []*Channel, err error) {
+	err = ari.callDecode(context.Background(), HTTP_GET, ari.resourceURL("channels"), nil, &chs)
+	return
+}
+
+// GetChannel returns the details of one channel
+func (ari *ARInGO) GetChannel(channelID string) (ch *Channel, err error) {
+	ch = new(Channel)
+	if err = ari.callDecode(context.Background(), HTTP_GET, ari.resourceURL("channels", channelID), nil, ch); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// Hangup hangs up the channel, reason is optional (ie: normal, busy, congestion, no_answer)
+func (ari *ARInGO) Hangup(channelID, reason string) error {
+	params := url.Values{}
+	if reason != "" {
+		params.Set("reason", reason)
+	}
+	return ari.callDecode(context.Background(), HTTP_DELETE, ari.resourceURL("channels", channelID), params, nil)
+}
+
+// ListBridges returns the active bridges
+func (ari *ARInGO) ListBridges() (brs []*Bridge, err error) {
+	err = ari.callDecode(context.Background(), HTTP_GET, ari.resourceURL("bridges"), nil, &brs)
+	return
+}
+
+// DestroyBridge shuts down the bridge, the channels inside are not hung up
+func (ari *ARInGO) DestroyBridge(bridgeID string) error {
+	return ari.callDecode(context.Background(), HTTP_DELETE, ari.resourceURL("bridges", bridgeID), nil, nil)
+}
+
+// AsteriskInfo returns the Asterisk system information
+// only can restrict the reply to some of the sections: build, system, config and status
+func (ari *ARInGO) AsteriskInfo(only ...string) (info *AsteriskInfo, err error) {
+	params := url.Values{}
+	if len(only) != 0 {
+		params.Set("only", strings.Join(only, ","))
+	}
+	info = new(AsteriskInfo)
+	if err = ari.callDecode(context.Background(), HTTP_GET, ari.resourceURL("asterisk", "info"), params, info); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// ReloadModule reloads one Asterisk module (ie: res_pjsip.so)
+func (ari *ARInGO) ReloadModule(moduleName string) error {
+	return ari.callDecode(context.Background(), HTTP_PUT, ari.resourceURL("asterisk", "modules", moduleName), nil, nil)
+}
+
+// UpdateDynamicConfig creates or updates one sorcery object (ie: res_pjsip, endpoint, 1001), returning all its attributes
+func (ari *ARInGO) UpdateDynamicConfig(configClass, objectType, id string, fields []ConfigTuple) (tuples []ConfigTuple, err error) {
+	err = ari.callDecode(context.Background(), HTTP_PUT,
+		ari.resourceURL("asterisk", "config", "dynamic", configClass, objectType, id), nil, &tuples,
+		WithJSONBody(map[string][]ConfigTuple{"fields": fields}))
+	return
+}
+
+// GetDynamicConfig returns the attributes of one sorcery object
+func (ari *ARInGO) GetDynamicConfig(configClass, objectType, id string) (tuples []ConfigTuple, err error) {
+	err = ari.callDecode(context.Background(), HTTP_GET,
+		ari.resourceURL("asterisk", "config", "dynamic", configClass, objectType, id), nil, &tuples)
+	return
+}
+
+// DeleteDynamicConfig removes one sorcery object
+func (ari *ARInGO) DeleteDynamicConfig(configClass, objectType, id string) error {
+	return ari.callDecode(context.Background(), HTTP_DELETE,
+		ari.resourceURL("asterisk", "config", "dynamic", configClass, objectType, id), nil, nil)
+}
+
+// ExternalMediaParams are the parameters of POST /channels/externalMedia
+type ExternalMediaParams struct {
+	ChannelID      string // generated if empty
+	App            string
+	ExternalHost   string // host:port receiving the media
+	Encapsulation  string // rtp (default) or audiosocket
+	Transport      string // udp (default) or tcp
+	ConnectionType string // client (default)
+	Format         string // ie: ulaw, alaw, slin16
+	Direction      string // both (default)
+	Data           string // encapsulation specific, ie: the AudioSocket UUID
+}
+
+func (p *ExternalMediaParams) values() url.Values {
+	params := url.Values{
+		"channelId":     {p.ChannelID},
+		"app":           {p.App},
+		"external_host": {p.ExternalHost},
+		"format":        {p.Format},
+	}
+	for key, val := range map[string]string{
+		"encapsulation":   p.Encapsulation,
+		"transport":       p.Transport,
+		"connection_type": p.ConnectionType,
+		"direction":       p.Direction,
+		"data":            p.Data,
+	} {
+		if val != "" {
+			params.Set(key, val)
+		}
+	}
+	return params
+}
+
+// ExternalMedia creates a channel exchanging the media with an external host
+// For RTP the address Asterisk sends from is inside the UNICASTRTP_LOCAL_ADDRESS/UNICASTRTP_LOCAL_PORT channel variables
+func (ari *ARInGO) ExternalMedia(p ExternalMediaParams) (ch *Channel, err error) {
+	if p.ChannelID == "" {
+		p.ChannelID = ari.NewID()
+	}
+	ch = new(Channel)
+	if err = ari.createWithID(context.Background(), HTTP_POST, ari.resourceURL("channels", "externalMedia"), p.values(),
+		ari.resourceURL("channels", p.ChannelID), ch); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// Redirect redirects the channel to a different endpoint of the same channel technology (ie: PJSIP/1001)
+func (ari *ARInGO) Redirect(channelID, endpoint string) error {
+	return ari.callDecode(context.Background(), HTTP_POST, ari.resourceURL("channels", channelID, "redirect"),
+		url.Values{"endpoint": {endpoint}}, nil)
+}
+
+// StopPlayback stops the playback
+func (ari *ARInGO) StopPlayback(playbackID string) error {
+	return ari.callDecode(context.Background(), HTTP_DELETE, ari.resourceURL("playbacks", playbackID), nil, nil)
+}
+
+// GetBridge returns the details of one bridge
+func (ari *ARInGO) GetBridge(bridgeID string) (br *Bridge, err error) {
+	br = new(Bridge)
+	if err = ari.callDecode(context.Background(), HTTP_GET, ari.resourceURL("bridges", bridgeID), nil, br); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// AddChannel adds the channels to the bridge
+func (ari *ARInGO) AddChannel(bridgeID string, channelIDs ...string) error {
+	return ari.callDecode(context.Background(), HTTP_POST, ari.resourceURL("bridges", bridgeID, "addChannel"),
+		url.Values{"channel": {strings.Join(channelIDs, ",")}}, nil)
+}
+
+// RemoveChannel removes the channels from the bridge
+func (ari *ARInGO) RemoveChannel(bridgeID string, channelIDs ...string) error {
+	return ari.callDecode(context.Background(), HTTP_POST, ari.resourceURL("bridges", bridgeID, "removeChannel"),
+		url.Values{"channel": {strings.Join(channelIDs, ",")}}, nil)
+}
+
+// StartBridgeMOH plays music on hold to the bridge, mohClass is optional
+func (ari *ARInGO) StartBridgeMOH(bridgeID, mohClass string) error {
+	params := url.Values{}
+	if mohClass != "" {
+		params.Set("mohClass", mohClass)
+	}
+	return ari.callDecode(context.Background(), HTTP_POST, ari.resourceURL("bridges", bridgeID, "moh"), params, nil)
+}
+
+// StopBridgeMOH stops the music on hold of the bridge
+func (ari *ARInGO) StopBridgeMOH(bridgeID string) error {
+	return ari.callDecode(context.Background(), HTTP_DELETE, ari.resourceURL("bridges", bridgeID, "moh"), nil, nil)
+}
+
+// Mute mutes the channel in the given direction (in, out or both), both if empty
+func (ari *ARInGO) Mute(channelID, direction string) error {
+	return ari.callDecode(context.Background(), HTTP_POST, ari.resourceURL("channels", channelID, "mute"),
+		directionParams(direction), nil)
+}
+
+// Unmute unmutes the channel in the given direction (in, out or both), both if empty
+func (ari *ARInGO) Unmute(channelID, direction string) error {
+	return ari.callDecode(context.Background(), HTTP_DELETE, ari.resourceURL("channels", channelID, "mute"),
+		directionParams(direction), nil)
+}
+
+func directionParams(direction string) (params url.Values) {
+	params = url.Values{}
+	if direction != "" {
+		params.Set("direction", direction)
+	}
+	return
+}
+
+// SetChannelVar sets one variable, or dialplan function (ie: TALK_DETECT(set)), on the channel
+func (ari *ARInGO) SetChannelVar(channelID, variable, value string) error {
+	return ari.callDecode(context.Background(), HTTP_POST, ari.resourceURL("channels", channelID, "variable"),
+		url.Values{"variable": {variable}, "value": {value}}, nil)
+}
+
+// CreateChannel creates a channel into the Stasis application without dialing it, use DialChannel to dial
+// Only Endpoint, App, AppArgs, ChannelID, OtherChannelID, Originator, Formats and Variables of p are used
+func (ari *ARInGO) CreateChannel(p OriginateParams) (ch *Channel, err error) {
+	p.setIDs(ari)
+	params := url.Values{"endpoint": {p.Endpoint}, "app": {p.App}}
+	for key, val := range map[string]string{
+		"appArgs":        p.AppArgs,
+		"channelId":      p.ChannelID,
+		"otherChannelId": p.OtherChannelID,
+		"originator":     p.Originator,
+		"formats":        p.Formats,
+	} {
+		if val != "" {
+			params.Set(key, val)
+		}
+	}
+	ch = new(Channel)
+	if err = ari.createWithID(context.Background(), HTTP_POST, ari.resourceURL("channels", "create"), params,
+		ari.resourceURL("channels", p.ChannelID), ch, p.variablesOption()...); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// DialChannel dials a channel created with CreateChannel, caller is the optional channel dialing it
+// timeout is in seconds, 0 to ring until hung up
+func (ari *ARInGO) DialChannel(channelID, caller string, timeout int) error {
+	params := url.Values{}
+	if caller != "" {
+		params.Set("caller", caller)
+	}
+	if timeout != 0 {
+		params.Set("timeout", strconv.Itoa(timeout))
+	}
+	return ari.callDecode(context.Background(), HTTP_POST, ari.resourceURL("channels", channelID, "dial"), params, nil)
+}
+
+// Answer answers the channel
+func (ari *ARInGO) Answer(channelID string) error {
+	return ari.callDecode(context.Background(), HTTP_POST, ari.resourceURL("channels", channelID, "answer"), nil, nil)
+}
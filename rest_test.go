@@ -0,0 +1,575 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package aringo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// fakeARI is a minimal Asterisk ARI server used by the tests
+// REST handlers registered on mux can push events towards the client with send
+type fakeARI struct {
+	*httptest.Server
+	mux    *http.ServeMux
+	connMu sync.Mutex
+	conn   *websocket.Conn
+	reqMux sync.Mutex
+	reqs   []string // logged by the handlers registered with handleREST
+}
+
+func newFakeARI(t *testing.T, opts ...Option) (fake *fakeARI, ari *ARInGO) {
+	fake = &fakeARI{mux: http.NewServeMux()}
+	connected := make(chan struct{})
+	fake.mux.Handle("/ari/events", websocket.Handler(func(c *websocket.Conn) {
+		fake.connMu.Lock()
+		fake.conn = c
+		fake.connMu.Unlock()
+		close(connected)
+		c.Read(make([]byte, 1)) // keep the connection open until the client leaves
+	}))
+	fake.Server = httptest.NewServer(fake.mux)
+	stopChan := make(chan struct{})
+	t.Cleanup(func() {
+		close(stopChan)
+		fake.Close()
+	})
+	n := strings.LastIndexByte(fake.URL, ':')
+	var err error
+	if ari, err = NewARInGO("ws"+strings.TrimPrefix(fake.URL, "http")+"/ari/events?app=test", fake.URL[:n]+"/",
+		"", "", "", make(chan map[string]interface{}, 100), make(chan error, 1), stopChan, 1, 0, 0, fibDuration, opts...); err != nil {
+		t.Fatal(err)
+	}
+	<-connected
+	return
+}
+
+// send writes one frame towards the client
+func (fake *fakeARI) send(frame string) {
+	fake.connMu.Lock()
+	fake.conn.Write([]byte(frame))
+	fake.connMu.Unlock()
+}
+
+// handleREST registers the handler on mux, logging the requests it serves
+func (fake *fakeARI) handleREST(pattern string, handler http.HandlerFunc) {
+	fake.mux.HandleFunc(pattern, func(rw http.ResponseWriter, r *http.Request) {
+		fake.reqMux.Lock()
+		fake.reqs = append(fake.reqs, r.Method+" "+r.URL.RequestURI())
+		fake.reqMux.Unlock()
+		handler(rw, r)
+	})
+}
+
+// requests returns the requests logged so far
+func (fake *fakeARI) requests() []string {
+	fake.reqMux.Lock()
+	defer fake.reqMux.Unlock()
+	return append([]string(nil), fake.reqs...)
+}
+
+func (fake *fakeARI) checkRequests(t *testing.T, exp []string) {
+	t.Helper()
+	if rcv := fake.requests(); !reflect.DeepEqual(exp, rcv) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp, rcv)
+	}
+}
+
+func TestOriginateParamsValues(t *testing.T) {
+	p := OriginateParams{
+		Endpoint: "PJSIP/1001",
+		App:      "test",
+		AppArgs:  "dialed",
+		Priority: 1,
+		Timeout:  30,
+	}
+	exp := url.Values{
+		"endpoint": {"PJSIP/1001"},
+		"app":      {"test"},
+		"appArgs":  {"dialed"},
+		"priority": {"1"},
+		"timeout":  {"30"},
+	}
+	if rcv := p.values(); rcv.Encode() != exp.Encode() {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp, rcv)
+	}
+}
+
+func TestOriginateAndWait(t *testing.T) {
+	fake, ari := newFakeARI(t, WithIDGenerator(func() string { return "ch1" }))
+	fake.mux.HandleFunc("/ari/channels", func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != HTTP_POST {
+			t.Errorf("Unexpected method: %s", r.Method)
+		}
+		r.ParseForm()
+		if r.Form.Get("endpoint") != "PJSIP/1001" || r.Form.Get("app") != "test" || r.Form.Get("channelId") != "ch1" {
+			t.Errorf("Unexpected originate: %+v", r.Form)
+		}
+		if r.Form.Has("otherChannelId") {
+			t.Errorf("Unexpected otherChannelId: %+v", r.Form)
+		}
+		// events reach the client before the REST reply
+		fake.send(`{"type":"StasisStart","channel":{"id":"other","state":"Up"}}`)
+		fake.send(`{"type":"StasisStart","args":["dialed"],"channel":{"id":"ch1","state":"Up"}}`)
+		time.Sleep(10 * time.Millisecond)
+		rw.Write([]byte(`{"id":"ch1","state":"Down"}`))
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	ch, err := ari.OriginateAndWait(ctx, OriginateParams{Endpoint: "PJSIP/1001", App: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ch.ID != "ch1" || ch.State != "Up" {
+		t.Errorf("Unexpected channel: %+v", ch)
+	}
+}
+
+func TestOriginateAndWaitDestroyed(t *testing.T) {
+	fake, ari := newFakeARI(t, WithIDGenerator(func() string { return "ch1" }))
+	fake.mux.HandleFunc("/ari/channels", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte(`{"id":"ch1","state":"Down"}`))
+		fake.send(`{"type":"ChannelDestroyed","cause":17,"cause_txt":"User busy","channel":{"id":"ch1"}}`)
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := ari.OriginateAndWait(ctx, OriginateParams{Endpoint: "PJSIP/1001", App: "test"}); !errors.Is(err, ErrChannelDestroyed) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", ErrChannelDestroyed, err)
+	} else if err.Error() != "CHANNEL_DESTROYED: User busy" {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestPlayAndWait(t *testing.T) {
+	fake, ari := newFakeARI(t, WithIDGenerator(func() string { return "pb1" }))
+	fake.mux.HandleFunc("/ari/channels/ch1/play/pb1", func(rw http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if media := r.Form["media"]; len(media) != 2 || media[0] != "sound:hello" || media[1] != "sound:world" {
+			t.Errorf("Unexpected media: %+v", media)
+		}
+		fake.send(`{"type":"PlaybackFinished","playback":{"id":"pb0","state":"done"}}`)
+		fake.send(`{"type":"PlaybackFinished","playback":{"id":"pb1","state":"done"}}`)
+		time.Sleep(10 * time.Millisecond)
+		rw.Write([]byte(`{"id":"pb1","state":"queued"}`))
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	pb, err := ari.PlayAndWait(ctx, "ch1", "sound:hello", "sound:world")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pb.ID != "pb1" || pb.State != "done" {
+		t.Errorf("Unexpected playback: %+v", pb)
+	}
+}
+
+func TestPlayAndWaitTimeout(t *testing.T) {
+	fake, ari := newFakeARI(t)
+	fake.mux.HandleFunc("/ari/channels/ch1/play/", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte(`{"id":"pb1","state":"queued"}`))
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := ari.PlayAndWait(ctx, "ch1", "sound:hello"); err != context.DeadlineExceeded {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", context.DeadlineExceeded, err)
+	}
+	if _, err := ari.PlayAndWait(ctx, "unknown", "sound:hello"); !IsReplyCode(err, http.StatusNotFound) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestOriginateLocalIDs(t *testing.T) {
+	var ids int
+	ari := &ARInGO{idGenerator: func() string {
+		ids++
+		return "id" + strconv.Itoa(ids)
+	}}
+	p := OriginateParams{Endpoint: "Local/1001@internal"}
+	p.setIDs(ari)
+	if p.ChannelID != "id1" || p.OtherChannelID != "id2" {
+		t.Errorf("Unexpected ids: %+v", p)
+	}
+	p = OriginateParams{Endpoint: "PJSIP/1001", ChannelID: "mine"}
+	p.setIDs(ari)
+	if p.ChannelID != "mine" || p.OtherChannelID != "" {
+		t.Errorf("Unexpected ids: %+v", p)
+	}
+}
+
+func TestCreateWithIDRetry(t *testing.T) {
+	fake, ari := newFakeARI(t, WithIDGenerator(func() string { return "br1" }))
+	var posts int
+	fake.mux.HandleFunc("/ari/bridges/br1", func(rw http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case HTTP_GET:
+			rw.Write([]byte(`{"id":"br1","bridge_type":"mixing"}`))
+			return
+		}
+		if posts++; posts == 1 { // created but the reply is lost on the way
+			conn, _, _ := rw.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		rw.WriteHeader(http.StatusConflict)
+	})
+	br, err := ari.CreateBridge(BridgeParams{Type: "mixing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if posts != 2 {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", 2, posts)
+	}
+	if br.ID != "br1" || br.BridgeType != "mixing" {
+		t.Errorf("Unexpected bridge: %+v", br)
+	}
+}
+
+func TestCreateWithIDConflict(t *testing.T) {
+	fake, ari := newFakeARI(t)
+	fake.mux.HandleFunc("/ari/channels", func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusConflict) // id used by someone else
+	})
+	if _, err := ari.Originate(OriginateParams{Endpoint: "PJSIP/1001", ChannelID: "taken"}); !IsReplyCode(err, http.StatusConflict) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestRecordRetryConflict(t *testing.T) {
+	fake, ari := newFakeARI(t)
+	var posts int
+	fake.mux.HandleFunc("/ari/channels/ch1/record", func(rw http.ResponseWriter, r *http.Request) {
+		if posts++; posts == 1 { // created but the reply is lost on the way
+			conn, _, _ := rw.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		rw.WriteHeader(http.StatusConflict)
+	})
+	target := "channel:ch1"
+	fake.mux.HandleFunc("/ari/recordings/live/rec1", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte(`{"name":"rec1","format":"wav","target_uri":"` + target + `","state":"recording"}`))
+	})
+	rec, err := ari.Record("ch1", RecordParams{Name: "rec1", Format: "wav"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Name != "rec1" || rec.TargetURI != "channel:ch1" {
+		t.Errorf("Unexpected recording: %+v", rec)
+	}
+	posts, target = 0, "channel:ch2" // the name is used by the recording of another channel
+	if _, err = ari.Record("ch1", RecordParams{Name: "rec1", Format: "wav"}); !IsReplyCode(err, http.StatusConflict) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestRecord(t *testing.T) {
+	fake, ari := newFakeARI(t, WithIDGenerator(func() string { return "rec1" }))
+	fake.mux.HandleFunc("/ari/bridges/br1/record", func(rw http.ResponseWriter, r *http.Request) {
+		exp := url.Values{
+			"name":              {"rec1"},
+			"format":            {"wav"},
+			"maxSilenceSeconds": {"5"},
+			"beep":              {"true"},
+			"terminateOn":       {"#"},
+		}
+		if r.URL.RawQuery != exp.Encode() {
+			t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp.Encode(), r.URL.RawQuery)
+		}
+		rw.Write([]byte(`{"name":"rec1","format":"wav","state":"queued","target_uri":"bridge:br1"}`))
+	})
+	rec, err := ari.RecordBridge("br1", RecordParams{Format: "wav", MaxSilenceSeconds: 5, Beep: true, TerminateOn: "#"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := &LiveRecording{Name: "rec1", Format: "wav", State: "queued", TargetURI: "bridge:br1"}
+	if *rec != *exp {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp, rec)
+	}
+}
+
+func TestRESTARInGO(t *testing.T) {
+	var received []string
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if user, pass, _ := r.BasicAuth(); user != "cgrates" || pass != "CGRateS.org" {
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		received = append(received, r.Method+" "+r.URL.RequestURI())
+		switch r.URL.Path {
+		case "/ari/channels":
+			rw.Write([]byte(`[{"id":"ch1","state":"Up"},{"id":"ch2","state":"Ring"}]`))
+		case "/ari/channels/ch1":
+			if r.Method == HTTP_GET {
+				rw.Write([]byte(`{"id":"ch1","state":"Up"}`))
+				return
+			}
+			rw.WriteHeader(http.StatusNoContent)
+		case "/ari/bridges":
+			rw.Write([]byte(`[{"id":"br1","bridge_type":"mixing","channels":["ch1"]}]`))
+		case "/ari/recordings/live/rec1":
+			if r.Method == HTTP_GET {
+				rw.Write([]byte(`{"name":"rec1","format":"wav","state":"recording"}`))
+				return
+			}
+			rw.WriteHeader(http.StatusNoContent)
+		case "/ari/recordings/stored":
+			rw.Write([]byte(`[{"name":"rec1","format":"wav"}]`))
+		case "/ari/recordings/stored/rec1":
+			if r.Method == HTTP_GET {
+				rw.Write([]byte(`{"name":"rec1","format":"wav"}`))
+				return
+			}
+			rw.WriteHeader(http.StatusNoContent)
+		case "/ari/asterisk/info":
+			rw.Write([]byte(`{"system":{"version":"20.5.0","entity_id":"00:11:22:33:44:55"}}`))
+		default:
+			rw.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+	ari := NewRESTARInGO(srv.URL+"/ari/", "cgrates", "CGRateS.org", "aringo")
+
+	if chs, err := ari.ListChannels(); err != nil {
+		t.Error(err)
+	} else if len(chs) != 2 || chs[0].ID != "ch1" || chs[1].State != "Ring" {
+		t.Errorf("Unexpected channels: %+v", chs)
+	}
+	if ch, err := ari.GetChannel("ch1"); err != nil {
+		t.Error(err)
+	} else if ch.ID != "ch1" || ch.State != "Up" {
+		t.Errorf("Unexpected channel: %+v", ch)
+	}
+	if err := ari.Hangup("ch1", "busy"); err != nil {
+		t.Error(err)
+	}
+	if brs, err := ari.ListBridges(); err != nil {
+		t.Error(err)
+	} else if len(brs) != 1 || brs[0].ID != "br1" || brs[0].Channels[0] != "ch1" {
+		t.Errorf("Unexpected bridges: %+v", brs)
+	}
+	if err := ari.DestroyBridge("br1"); err != nil {
+		t.Error(err)
+	}
+	if info, err := ari.AsteriskInfo("system"); err != nil {
+		t.Error(err)
+	} else if info.System == nil || info.System.Version != "20.5.0" || info.Build != nil {
+		t.Errorf("Unexpected info: %+v", info)
+	}
+	if err := ari.ReloadModule("res_pjsip.so"); err != nil {
+		t.Error(err)
+	}
+	if err := ari.Redirect("ch1", "PJSIP/1001"); err != nil {
+		t.Error(err)
+	}
+	if err := ari.StopPlayback("pb1"); err != nil {
+		t.Error(err)
+	}
+	if err := ari.AddChannel("br1", "ch1", "ch2"); err != nil {
+		t.Error(err)
+	}
+	if err := ari.RemoveChannel("br1", "ch2"); err != nil {
+		t.Error(err)
+	}
+	if err := ari.StartBridgeMOH("br1", "default"); err != nil {
+		t.Error(err)
+	}
+	if err := ari.StopBridgeMOH("br1"); err != nil {
+		t.Error(err)
+	}
+	if err := ari.Mute("ch1", "in"); err != nil {
+		t.Error(err)
+	}
+	if err := ari.Unmute("ch1", ""); err != nil {
+		t.Error(err)
+	}
+	if err := ari.SetChannelVar("ch1", "TALK_DETECT(set)", ""); err != nil {
+		t.Error(err)
+	}
+	if err := ari.DialChannel("ch1", "ch2", 30); err != nil {
+		t.Error(err)
+	}
+	if err := ari.Answer("ch2"); err != nil {
+		t.Error(err)
+	}
+	if rec, err := ari.GetLiveRecording("rec1"); err != nil {
+		t.Error(err)
+	} else if rec.Name != "rec1" || rec.State != "recording" {
+		t.Errorf("Unexpected recording: %+v", rec)
+	}
+	if err := ari.PauseRecording("rec1"); err != nil {
+		t.Error(err)
+	}
+	if err := ari.UnpauseRecording("rec1"); err != nil {
+		t.Error(err)
+	}
+	if err := ari.StopRecording("rec1"); err != nil {
+		t.Error(err)
+	}
+	if err := ari.CancelRecording("rec1"); err != nil {
+		t.Error(err)
+	}
+	if recs, err := ari.ListStoredRecordings(); err != nil {
+		t.Error(err)
+	} else if len(recs) != 1 || recs[0].Name != "rec1" || recs[0].Format != "wav" {
+		t.Errorf("Unexpected recordings: %+v", recs)
+	}
+	if rec, err := ari.GetStoredRecording("rec1"); err != nil {
+		t.Error(err)
+	} else if rec.Name != "rec1" {
+		t.Errorf("Unexpected recording: %+v", rec)
+	}
+	if err := ari.DeleteStoredRecording("rec1"); err != nil {
+		t.Error(err)
+	}
+	exp := []string{
+		"GET /ari/channels",
+		"GET /ari/channels/ch1",
+		"DELETE /ari/channels/ch1?reason=busy",
+		"GET /ari/bridges",
+		"DELETE /ari/bridges/br1",
+		"GET /ari/asterisk/info?only=system",
+		"PUT /ari/asterisk/modules/res_pjsip.so",
+		"POST /ari/channels/ch1/redirect?endpoint=PJSIP%2F1001",
+		"DELETE /ari/playbacks/pb1",
+		"POST /ari/bridges/br1/addChannel?channel=ch1%2Cch2",
+		"POST /ari/bridges/br1/removeChannel?channel=ch2",
+		"POST /ari/bridges/br1/moh?mohClass=default",
+		"DELETE /ari/bridges/br1/moh",
+		"POST /ari/channels/ch1/mute?direction=in",
+		"DELETE /ari/channels/ch1/mute",
+		"POST /ari/channels/ch1/variable?value=&variable=TALK_DETECT%28set%29",
+		"POST /ari/channels/ch1/dial?caller=ch2&timeout=30",
+		"POST /ari/channels/ch2/answer",
+		"GET /ari/recordings/live/rec1",
+		"POST /ari/recordings/live/rec1/pause",
+		"DELETE /ari/recordings/live/rec1/pause",
+		"POST /ari/recordings/live/rec1/stop",
+		"DELETE /ari/recordings/live/rec1",
+		"GET /ari/recordings/stored",
+		"GET /ari/recordings/stored/rec1",
+		"DELETE /ari/recordings/stored/rec1",
+	}
+	if strings.Join(received, "\n") != strings.Join(exp, "\n") {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp, received)
+	}
+
+	ari = NewRESTARInGO(srv.URL+"/ari", "cgrates", "wrong", "aringo")
+	if _, err := ari.ListChannels(); !IsReplyCode(err, http.StatusUnauthorized) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestDynamicConfig(t *testing.T) {
+	var received []string
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = append(received, r.Method+" "+r.URL.RequestURI()+" "+string(body))
+		if r.Method == HTTP_DELETE {
+			rw.WriteHeader(http.StatusNoContent)
+			return
+		}
+		rw.Write([]byte(`[{"attribute":"max_contacts","value":"1"},{"attribute":"context","value":"default"}]`))
+	}))
+	defer srv.Close()
+	ari := NewRESTARInGO(srv.URL+"/ari", "", "", "aringo")
+	exp := []ConfigTuple{{Attribute: "max_contacts", Value: "1"}, {Attribute: "context", Value: "default"}}
+	if tuples, err := ari.UpdateDynamicConfig("res_pjsip", "aor", "1001",
+		[]ConfigTuple{{Attribute: "max_contacts", Value: "1"}}); err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(exp, tuples) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp, tuples)
+	}
+	if tuples, err := ari.GetDynamicConfig("res_pjsip", "aor", "1001"); err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(exp, tuples) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp, tuples)
+	}
+	if err := ari.DeleteDynamicConfig("res_pjsip", "aor", "1001"); err != nil {
+		t.Error(err)
+	}
+	expReqs := []string{
+		`PUT /ari/asterisk/config/dynamic/res_pjsip/aor/1001 {"fields":[{"attribute":"max_contacts","value":"1"}]}`,
+		"GET /ari/asterisk/config/dynamic/res_pjsip/aor/1001 ",
+		"DELETE /ari/asterisk/config/dynamic/res_pjsip/aor/1001 ",
+	}
+	if !reflect.DeepEqual(expReqs, received) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", expReqs, received)
+	}
+}
+
+func TestDownloadRecording(t *testing.T) {
+	audio := bytes.Repeat([]byte{0x52, 0x49, 0x46, 0x46}, 1024)
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ari/recordings/stored/rec%201/file", "/ari/recordings/stored/rec 1/file":
+			rw.Header().Set("Content-Type", "audio/wav")
+			rw.Write(audio)
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+			rw.Write([]byte(`{"message":"Recording not found"}`))
+		}
+	}))
+	defer srv.Close()
+	ari := NewRESTARInGO(srv.URL+"/ari", "", "", "aringo")
+	var buf bytes.Buffer
+	if n, err := ari.DownloadRecording(context.Background(), "rec 1", &buf); err != nil {
+		t.Fatal(err)
+	} else if n != int64(len(audio)) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", len(audio), n)
+	}
+	if !bytes.Equal(audio, buf.Bytes()) {
+		t.Error("Unexpected recording content")
+	}
+	buf.Reset()
+	_, err := ari.DownloadRecording(context.Background(), "rec2", &buf)
+	var rcErr *UnexpectedReplyCodeError
+	if !errors.As(err, &rcErr) || rcErr.StatusCode != http.StatusNotFound || rcErr.Message != "Recording not found" {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Unexpected content: %q", buf.String())
+	}
+}
+
+func TestExternalMedia(t *testing.T) {
+	fake, ari := newFakeARI(t, WithIDGenerator(func() string { return "em1" }))
+	fake.mux.HandleFunc("/ari/channels/externalMedia", func(rw http.ResponseWriter, r *http.Request) {
+		exp := url.Values{
+			"channelId":     {"em1"},
+			"app":           {"test"},
+			"external_host": {"127.0.0.1:4000"},
+			"format":        {"slin16"},
+			"direction":     {"in"},
+		}
+		if r.URL.RawQuery != exp.Encode() {
+			t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp.Encode(), r.URL.RawQuery)
+		}
+		rw.Write([]byte(`{"id":"em1","name":"UnicastRTP/127.0.0.1:4000-0x1","state":"Down",
+			"channelvars":{"UNICASTRTP_LOCAL_ADDRESS":"127.0.0.1","UNICASTRTP_LOCAL_PORT":"10000"}}`))
+	})
+	ch, err := ari.ExternalMedia(ExternalMediaParams{App: "test", ExternalHost: "127.0.0.1:4000", Format: "slin16", Direction: "in"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ch.ID != "em1" || ch.ChannelVars["UNICASTRTP_LOCAL_PORT"] != "10000" {
+		t.Errorf("Unexpected channel: %+v", ch)
+	}
+}
@@ -0,0 +1,215 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package aringo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	EventTypeReconnected = "Reconnected" // synthetic event posted after the websocket was reconnected
+	MissedField          = "missed"      // marks the synthetic end events generated on resync
+
+	timestampLayout = "2006-01-02T15:04:05.000-0700" // format of the timestamps sent by Asterisk
+)
+
+// restURLFromWS derives the REST base URL out of the events websocket URL
+// ie: ws://127.0.0.1:8088/ari/events?app=cgrates becomes http://127.0.0.1:8088/ari
+func restURLFromWS(wsURL string) string {
+	u, err := url.Parse(wsURL)
+	if err != nil {
+		return ""
+	}
+	switch u.Scheme {
+	case "ws":
+		u.Scheme = "http"
+	case "wss":
+		u.Scheme = "https"
+	}
+	u.Path = strings.TrimSuffix(strings.TrimSuffix(u.Path, "/"), "/events")
+	u.RawPath = ""
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String()
+}
+
+// trackedResource is the last known state of a resource together with its application
+type trackedResource struct {
+	application string
+	object      json.RawMessage
+}
+
+// resourceTracker keeps the channels, bridges and playbacks seen on the event stream
+// It is only accessed out of the listener goroutine so it needs no locking
+type resourceTracker struct {
+	channels  map[string]*trackedResource
+	bridges   map[string]*trackedResource
+	playbacks map[string]*trackedResource
+}
+
+func newResourceTracker() *resourceTracker {
+	return &resourceTracker{
+		channels:  make(map[string]*trackedResource),
+		bridges:   make(map[string]*trackedResource),
+		playbacks: make(map[string]*trackedResource),
+	}
+}
+
+// track updates the known resources based on one event
+func (rt *resourceTracker) track(ev *Event) {
+	var objs struct {
+		Channel  json.RawMessage `json:"channel"`
+		Bridge   json.RawMessage `json:"bridge"`
+		Playback json.RawMessage `json:"playback"`
+	}
+	if err := ev.Decode(&objs); err != nil {
+		return
+	}
+	switch ev.Type {
+	case "StasisStart":
+		rt.add(rt.channels, ev.Application, objs.Channel)
+	case "ChannelStateChange", "ChannelVarset", "ChannelDialplan", "ChannelCallerId":
+		rt.refresh(rt.channels, objs.Channel) // only channels inside Stasis are tracked
+	case "StasisEnd", "ChannelDestroyed":
+		delete(rt.channels, objectID(objs.Channel))
+	case "BridgeCreated", "ChannelEnteredBridge", "ChannelLeftBridge":
+		rt.add(rt.bridges, ev.Application, objs.Bridge)
+	case "BridgeDestroyed":
+		delete(rt.bridges, objectID(objs.Bridge))
+	case "PlaybackStarted", "PlaybackContinuing":
+		rt.add(rt.playbacks, ev.Application, objs.Playback)
+	case "PlaybackFinished":
+		delete(rt.playbacks, objectID(objs.Playback))
+	}
+}
+
+func (rt *resourceTracker) add(resources map[string]*trackedResource, app string, obj json.RawMessage) {
+	if id := objectID(obj); id != "" {
+		resources[id] = &trackedResource{application: app, object: obj}
+	}
+}
+
+func (rt *resourceTracker) refresh(resources map[string]*trackedResource, obj json.RawMessage) {
+	if res, known := resources[objectID(obj)]; known {
+		res.object = obj
+	}
+}
+
+// objectID returns the id field of a raw ARI object
+func objectID(obj json.RawMessage) string {
+	if len(obj) == 0 {
+		return ""
+	}
+	var o struct {
+		ID string `json:"id"`
+	}
+	json.Unmarshal(obj, &o)
+	return o.ID
+}
+
+// onReconnect announces the reconnect and resyncs the known resources if enabled
+func (ari *ARInGO) onReconnect() {
+	ari.emitEvent(EventTypeReconnected, "", nil)
+	if ari.tracker != nil {
+		ari.resync()
+	}
+}
+
+// resync queries Asterisk for the known resources and generates end events for the missing ones
+// Resources which cannot be queried are kept as they are
+func (ari *ARInGO) resync() {
+	ari.log().Info("ARI resync", "channels", len(ari.tracker.channels),
+		"bridges", len(ari.tracker.bridges), "playbacks", len(ari.tracker.playbacks))
+	if ids, err := ari.listIDs("/channels"); err != nil {
+		ari.log().Warn("ARI resync of channels failed", "error", err)
+	} else {
+		ari.emitMissed(ari.tracker.channels, ids, "StasisEnd", "channel")
+	}
+	if ids, err := ari.listIDs("/bridges"); err != nil {
+		ari.log().Warn("ARI resync of bridges failed", "error", err)
+	} else {
+		ari.emitMissed(ari.tracker.bridges, ids, "BridgeDestroyed", "bridge")
+	}
+	// there is no listing for playbacks, query them one by one
+	ids := make(map[string]bool)
+	for id := range ari.tracker.playbacks {
+		if _, err := ari.Call(HTTP_GET, ari.restURL+"/playbacks/"+url.PathEscape(id), nil); !IsReplyCode(err, http.StatusNotFound) {
+			ids[id] = true
+		}
+	}
+	ari.emitMissed(ari.tracker.playbacks, ids, "PlaybackFinished", "playback")
+}
+
+// listIDs returns the ids of the resources listed by Asterisk at the given path
+func (ari *ARInGO) listIDs(path string) (ids map[string]bool, err error) {
+	var reply []byte
+	if reply, err = ari.Call(HTTP_GET, ari.restURL+path, nil); err != nil {
+		return
+	}
+	var objs []json.RawMessage
+	if err = json.Unmarshal(reply, &objs); err != nil {
+		return
+	}
+	ids = make(map[string]bool)
+	for _, obj := range objs {
+		ids[objectID(obj)] = true
+	}
+	return
+}
+
+// emitMissed posts synthetic end events for the tracked resources not found in ids
+func (ari *ARInGO) emitMissed(resources map[string]*trackedResource, ids map[string]bool, evType, objKey string) {
+	missing := make([]string, 0, len(resources))
+	for id := range resources {
+		if !ids[id] {
+			missing = append(missing, id)
+		}
+	}
+	sort.Strings(missing)
+	if len(missing) != 0 {
+		ari.log().Info("ARI resources missed while disconnected", "event", evType, "ids", missing)
+	}
+	for _, id := range missing {
+		res := resources[id]
+		ari.emitEvent(evType, res.application, map[string]interface{}{
+			objKey:      res.object,
+			MissedField: true,
+		})
+	}
+}
+
+// emitEvent posts a synthetic event through the same path as the ones received from Asterisk
+func (ari *ARInGO) emitEvent(evType, application string, fields map[string]interface{}) {
+	ev := map[string]interface{}{
+		"type":      evType,
+		"timestamp": time.Now().Format(timestampLayout),
+	}
+	if application != "" {
+		ev["application"] = application
+	}
+	for k, v := range fields {
+		ev[k] = v
+	}
+	raw, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+	if ari.recorder != nil {
+		ari.recorder.recordFrame(raw)
+	}
+	var decoded *Event
+	if decoded, err = decodeEvent(raw); err != nil {
+		return
+	}
+	ari.handleEvent(decoded, raw) // never held, it describes what happened before the held events
+}
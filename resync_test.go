@@ -0,0 +1,160 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package aringo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+func TestRestURLFromWS(t *testing.T) {
+	for wsURL, exp := range map[string]string{
+		"ws://127.0.0.1:8088/ari/events?api_key=cgrates:CGRateS.org&app=cgrates_auth": "http://127.0.0.1:8088/ari",
+		"wss://asterisk.example.com/ari/events/?app=a":                                "https://asterisk.example.com/ari",
+		"ws://127.0.0.1:8088/": "http://127.0.0.1:8088",
+	} {
+		if rcv := restURLFromWS(wsURL); rcv != exp {
+			t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp, rcv)
+		}
+	}
+}
+
+func TestResyncAfterReconnect(t *testing.T) {
+	var conns int32
+	mux := http.NewServeMux()
+	mux.Handle("/ari/events", websocket.Handler(func(c *websocket.Conn) {
+		if atomic.AddInt32(&conns, 1) != 1 {
+			c.Read(make([]byte, 1)) // keep the second connection open until the client leaves
+			return
+		}
+		for _, frame := range []string{
+			`{"type":"StasisStart","application":"app1","channel":{"id":"ch1","state":"Up"}}`,
+			`{"type":"StasisStart","application":"app1","channel":{"id":"ch2","state":"Up"}}`,
+			`{"type":"ChannelStateChange","application":"app1","channel":{"id":"ch1","state":"Ringing"}}`,
+			`{"type":"BridgeCreated","application":"app1","bridge":{"id":"br1"}}`,
+			`{"type":"PlaybackStarted","application":"app1","playback":{"id":"pb1"}}`,
+			`{"type":"PlaybackStarted","application":"app1","playback":{"id":"pb2"}}`,
+			`{"type":"PlaybackFinished","application":"app1","playback":{"id":"pb2"}}`,
+		} {
+			c.Write([]byte(frame))
+		}
+		c.Close()
+	}))
+	mux.HandleFunc("/ari/channels", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte(`[{"id":"ch2"}]`))
+	})
+	mux.HandleFunc("/ari/bridges", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte(`[]`))
+	})
+	mux.HandleFunc("/ari/playbacks/pb1", func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusNotFound)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	n := strings.LastIndexByte(srv.URL, ':')
+	evChannel := make(chan map[string]interface{}, 20)
+	stopChan := make(chan struct{})
+	defer close(stopChan)
+	if _, err := NewARInGO("ws"+strings.TrimPrefix(srv.URL, "http")+"/ari/events?app=app1", srv.URL[:n]+"/",
+		"", "", "", evChannel, make(chan error, 1), stopChan, 1, 1, 0, fibDuration, WithResync()); err != nil {
+		t.Fatal(err)
+	}
+
+	expTypes := []string{"StasisStart", "StasisStart", "ChannelStateChange", "BridgeCreated",
+		"PlaybackStarted", "PlaybackStarted", "PlaybackFinished",
+		EventTypeReconnected, "StasisEnd", "BridgeDestroyed", "PlaybackFinished"}
+	evs := make([]map[string]interface{}, len(expTypes))
+	for i, expType := range expTypes {
+		select {
+		case evs[i] = <-evChannel:
+		case <-time.After(time.Second):
+			t.Fatalf("Timeout waiting for event %d: %s", i, expType)
+		}
+		if evs[i]["type"] != expType {
+			t.Errorf("Event %d, \nExpected: <%+v>, \nReceived: <%+v>", i, expType, evs[i]["type"])
+		}
+	}
+	if evs[7]["missed"] != nil {
+		t.Errorf("Unexpected Reconnected event: %+v", evs[7])
+	}
+	for i, expID := range map[int]string{8: "ch1", 9: "br1", 10: "pb1"} {
+		if evs[i][MissedField] != true || evs[i]["application"] != "app1" {
+			t.Errorf("Unexpected missed event: %+v", evs[i])
+		}
+		for _, key := range []string{"channel", "bridge", "playback"} {
+			if obj, has := evs[i][key].(map[string]interface{}); has && obj["id"] != expID {
+				t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", expID, obj["id"])
+			}
+		}
+	}
+	if state := evs[8]["channel"].(map[string]interface{})["state"]; state != "Ringing" {
+		t.Errorf("Expected last known channel state, received: <%+v>", state)
+	}
+	select {
+	case ev := <-evChannel:
+		t.Errorf("Unexpected event: %+v", ev)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestResyncOverWebsocket(t *testing.T) {
+	var conns, httpCalls int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ari/", func(rw http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&httpCalls, 1)
+		rw.Write([]byte(`[]`))
+	})
+	mux.Handle("/ari/events", websocket.Handler(func(c *websocket.Conn) {
+		if atomic.AddInt32(&conns, 1) == 1 {
+			c.Write([]byte(`{"type":"StasisStart","application":"app1","channel":{"id":"ch1"}}`))
+			c.Close()
+			return
+		}
+		c.Write([]byte(`{"type":"StasisStart","application":"app1","channel":{"id":"ch2"}}`)) // held until resynced
+		for {
+			var req RESTRequest
+			if err := websocket.JSON.Receive(c, &req); err != nil {
+				return
+			}
+			websocket.JSON.Send(c, RESTResponse{Type: EventTypeRESTResponse, RequestID: req.RequestID,
+				StatusCode: 200, MessageBody: `[]`})
+		}
+	}))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	n := strings.LastIndexByte(srv.URL, ':')
+	evChannel := make(chan map[string]interface{}, 20)
+	stopChan := make(chan struct{})
+	defer close(stopChan)
+	if _, err := NewARInGO("ws"+strings.TrimPrefix(srv.URL, "http")+"/ari/events?app=app1", srv.URL[:n]+"/",
+		"", "", "", evChannel, make(chan error, 1), stopChan, 1, 1, 0, fibDuration,
+		WithResync(), WithRESTOverWebsocket(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	for i, expType := range []string{"StasisStart", EventTypeReconnected, "StasisEnd", "StasisStart"} {
+		select {
+		case ev := <-evChannel:
+			if ev["type"] != expType {
+				t.Errorf("Event %d, \nExpected: <%+v>, \nReceived: <%+v>", i, expType, ev["type"])
+			}
+		case <-time.After(500 * time.Millisecond): // replies waiting for the probe timeout arrive too late
+			t.Fatalf("Timeout waiting for event %d: %s", i, expType)
+		}
+	}
+	if calls := atomic.LoadInt32(&httpCalls); calls != 0 {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", 0, calls)
+	}
+}
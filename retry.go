@@ -0,0 +1,191 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package aringo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	defaultClientIDAttempts = 3                      // attempts for requests with client supplied ids when no policy is configured
+	defaultRetryMinDelay    = 100 * time.Millisecond // delay unit when the policy does not specify one
+)
+
+var errCreatedOnRetry = errors.New("CREATED_ON_RETRY") // a retried request with client supplied id found its resource already created
+
+// RetryPolicy controls the retries of the REST calls
+// Only idempotent methods (GET, PUT, DELETE) and requests carrying client supplied ids are retried,
+// on network errors and server side (5xx) reply codes
+type RetryPolicy struct {
+	MaxAttempts int                                                     // including the first attempt, 1 or less disables retries
+	MinDelay    time.Duration                                           // delay unit passed to DelayFunc, 100ms if 0
+	MaxDelay    time.Duration                                           // maximum delay between attempts, 0 for the reconnect interval
+	DelayFunc   func(time.Duration, time.Duration) func() time.Duration // the reconnect delay function if nil
+}
+
+// WithRetryPolicy sets the default retry policy of the REST calls
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(ari *ARInGO) {
+		ari.retryPolicy = &policy
+	}
+}
+
+// CallOption customizes one REST call
+type CallOption func(*callOptions)
+
+type callOptions struct {
+	retryPolicy *RetryPolicy
+	idempotent  bool // safe to retry independent of method
+	clientID    bool // creates a resource with client supplied id
+	jsonBody    interface{}
+}
+
+// WithCallRetryPolicy overwrites the retry policy for one call
+func WithCallRetryPolicy(policy RetryPolicy) CallOption {
+	return func(opts *callOptions) {
+		opts.retryPolicy = &policy
+	}
+}
+
+// WithIdempotent marks the call as safe to retry, ie: a POST with client supplied ids
+func WithIdempotent() CallOption {
+	return func(opts *callOptions) {
+		opts.idempotent = true
+	}
+}
+
+// WithJSONBody sends v marshaled as JSON in the request body, the data of the call is moved into the query string
+// Needed by the parameters which cannot be passed as form values, ie: the variables of an originate
+func WithJSONBody(v interface{}) CallOption {
+	return func(opts *callOptions) {
+		opts.jsonBody = v
+	}
+}
+
+// withClientID marks the calls creating resources with client supplied ids
+func withClientID() CallOption {
+	return func(opts *callOptions) {
+		opts.idempotent = true
+		opts.clientID = true
+	}
+}
+
+// CallContext executes one REST call to Asterisk, retrying it according to the retry policy
+// Unlike Call, the reply is returned independent of method
+func (ari *ARInGO) CallContext(ctx context.Context, method, reqURL string, data url.Values,
+	opts ...CallOption) (reply []byte, err error) {
+	var cOpts callOptions
+	for _, opt := range opts {
+		opt(&cOpts)
+	}
+	if ari.tracer != nil {
+		if u, errParse := url.Parse(reqURL); errParse == nil {
+			var span trace.Span
+			ctx, span = ari.startCallSpan(ctx, method, u, data)
+			defer func() { ari.endCallSpan(span, method, u, data, reply, err) }()
+		}
+	}
+	var jsonBody []byte
+	if cOpts.jsonBody != nil {
+		if jsonBody, err = json.Marshal(cOpts.jsonBody); err != nil {
+			return
+		}
+	}
+	idempotent := cOpts.idempotent || method == HTTP_GET || method == HTTP_PUT || method == HTTP_DELETE
+	policy := ari.callRetryPolicy(&cOpts)
+	if !idempotent {
+		policy.MaxAttempts = 1
+	}
+	var delay func() time.Duration
+	for attempt := 0; ; attempt++ {
+		if attempt != 0 {
+			if delay == nil {
+				delay = ari.retryDelay(policy)
+			}
+			select {
+			case <-time.After(delay()):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		if reply, err = ari.request(ctx, method, reqURL, data, jsonBody, idempotent); err == nil {
+			return
+		}
+		if attempt != 0 && cOpts.clientID && IsReplyCode(err, http.StatusConflict) {
+			return nil, errCreatedOnRetry
+		}
+		if attempt+1 >= policy.MaxAttempts || !isRetriable(err) {
+			ari.log().Warn("ARI request failed", "method", method, "url", redactURL(reqURL), "attempts", attempt+1, "error", err)
+			return
+		}
+		ari.log().Info("ARI request retrying", "method", method, "url", redactURL(reqURL), "attempt", attempt+1, "error", err)
+	}
+}
+
+// callRetryPolicy returns the retry policy applying to one call
+func (ari *ARInGO) callRetryPolicy(cOpts *callOptions) (policy RetryPolicy) {
+	switch {
+	case cOpts.retryPolicy != nil:
+		policy = *cOpts.retryPolicy
+	case ari.retryPolicy != nil:
+		policy = *ari.retryPolicy
+	case cOpts.clientID:
+		policy.MaxAttempts = defaultClientIDAttempts
+	}
+	return
+}
+
+// retryDelay returns the delay function used between the attempts of one call
+func (ari *ARInGO) retryDelay(policy RetryPolicy) func() time.Duration {
+	delayFunc := policy.DelayFunc
+	if delayFunc == nil {
+		delayFunc = ari.delayFunc
+	}
+	minDelay := policy.MinDelay
+	if minDelay == 0 {
+		minDelay = defaultRetryMinDelay
+	}
+	if delayFunc == nil {
+		return func() time.Duration { return minDelay }
+	}
+	maxDelay := policy.MaxDelay
+	if maxDelay == 0 {
+		maxDelay = ari.maxReconnectInterval
+	}
+	return delayFunc(minDelay, maxDelay)
+}
+
+// isRetriable checks if a failed call can succeed when repeated
+func isRetriable(err error) bool {
+	var rcErr *UnexpectedReplyCodeError
+	if errors.As(err, &rcErr) {
+		return rcErr.StatusCode >= 500
+	}
+	return isNetworkError(err)
+}
+
+// isNetworkError checks if the request failed on its way to/from Asterisk (ie: connection refused or reset)
+func isNetworkError(err error) bool {
+	var uErr *url.Error
+	if !errors.As(err, &uErr) {
+		return false
+	}
+	var nErr net.Error
+	return errors.As(uErr.Err, &nErr) ||
+		errors.Is(uErr.Err, io.EOF) ||
+		errors.Is(uErr.Err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,144 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package aringo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func fixedDelay(durationUnit, maxDuration time.Duration) func() time.Duration {
+	return func() time.Duration { return time.Millisecond }
+}
+
+// newFlakyServer replies with 503 for the first failures requests
+func newFlakyServer(failures int) (srv *httptest.Server, attempts *int) {
+	attempts = new(int)
+	srv = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if *attempts++; *attempts <= failures {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		rw.Write([]byte("OK"))
+	}))
+	return
+}
+
+func TestCallContextRetry(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		method   string
+		opts     []CallOption
+		expErr   bool
+		expTries int
+	}{
+		{name: "GET", method: HTTP_GET, expTries: 3},
+		{name: "DELETE", method: HTTP_DELETE, expTries: 3},
+		{name: "POST", method: HTTP_POST, expErr: true, expTries: 1},
+		{name: "IdempotentPOST", method: HTTP_POST, opts: []CallOption{WithIdempotent()}, expTries: 3},
+		{name: "CallOverride", method: HTTP_GET, opts: []CallOption{WithCallRetryPolicy(RetryPolicy{MaxAttempts: 2})},
+			expErr: true, expTries: 2},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv, attempts := newFlakyServer(2)
+			defer srv.Close()
+			ari := &ARInGO{
+				httpClient:  srv.Client(),
+				delayFunc:   fixedDelay,
+				retryPolicy: &RetryPolicy{MaxAttempts: 5},
+			}
+			reply, err := ari.CallContext(context.Background(), tc.method, srv.URL, nil, tc.opts...)
+			if tc.expErr {
+				if !IsReplyCode(err, http.StatusServiceUnavailable) {
+					t.Errorf("Unexpected error: %v", err)
+				}
+			} else if err != nil {
+				t.Error(err)
+			} else if string(reply) != "OK" {
+				t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", "OK", string(reply))
+			}
+			if *attempts != tc.expTries {
+				t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", tc.expTries, *attempts)
+			}
+		})
+	}
+}
+
+func TestCallContextNoPolicy(t *testing.T) {
+	srv, attempts := newFlakyServer(1)
+	defer srv.Close()
+	ari := &ARInGO{httpClient: srv.Client(), delayFunc: fixedDelay}
+	if _, err := ari.Call(HTTP_GET, srv.URL, nil); !IsReplyCode(err, http.StatusServiceUnavailable) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if *attempts != 1 {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", 1, *attempts)
+	}
+}
+
+func TestCallContextRetryCanceled(t *testing.T) {
+	srv, attempts := newFlakyServer(10)
+	defer srv.Close()
+	ari := &ARInGO{
+		httpClient:  srv.Client(),
+		delayFunc:   fibDuration,
+		retryPolicy: &RetryPolicy{MaxAttempts: 10, MinDelay: time.Second},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := ari.CallContext(ctx, HTTP_GET, srv.URL, nil); err != context.DeadlineExceeded {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", context.DeadlineExceeded, err)
+	}
+	if *attempts != 1 {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", 1, *attempts)
+	}
+}
+
+func TestCallContextConnectionRefused(t *testing.T) {
+	srv, attempts := newFlakyServer(0)
+	srvURL := srv.URL
+	srv.Close() // Asterisk restarting
+	ari := &ARInGO{
+		httpClient:  http.DefaultClient,
+		delayFunc:   fixedDelay,
+		retryPolicy: &RetryPolicy{MaxAttempts: 3},
+	}
+	if _, err := ari.CallContext(context.Background(), HTTP_GET, srvURL, nil); !isNetworkError(err) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if *attempts != 0 {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", 0, *attempts)
+	}
+}
+
+func TestIsRetriable(t *testing.T) {
+	for i, tc := range []struct {
+		err error
+		exp bool
+	}{
+		{NewErrUnexpectedReplyCode(http.StatusServiceUnavailable), true},
+		{NewErrUnexpectedReplyCode(http.StatusInternalServerError), true},
+		{NewErrUnexpectedReplyCode(http.StatusNotFound), false},
+		{NewErrUnexpectedReplyCode(http.StatusConflict), false},
+		{&url.Error{Op: "Get", URL: "http://127.0.0.1", Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}}, true},
+		{&url.Error{Op: "Post", URL: "http://127.0.0.1", Err: io.EOF}, true},
+		{&url.Error{Op: "parse", URL: ":foo", Err: errors.New("missing protocol scheme")}, false},
+		{errors.New("Unrecognized method: invalid"), false},
+	} {
+		if rcv := isRetriable(tc.err); rcv != tc.exp {
+			t.Errorf("Case %d, \nExpected: <%+v>, \nReceived: <%+v>", i, tc.exp, rcv)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package aringo
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"golang.org/x/net/websocket"
+)
+
+// Authenticator decides if an incoming ARI websocket connection is accepted
+type Authenticator func(r *http.Request) bool
+
+// BasicAuth accepts the connections authenticated with one of the username/password pairs
+// configured on the Asterisk side of the outbound websocket
+func BasicAuth(credentials map[string]string) Authenticator {
+	return func(r *http.Request) bool {
+		username, password, has := r.BasicAuth()
+		if !has {
+			return false
+		}
+		expPassword, has := credentials[username]
+		return has && subtle.ConstantTimeCompare([]byte(password), []byte(expPassword)) == 1
+	}
+}
+
+// Server accepts the ARI websocket connections opened by Asterisk towards the application (outbound websockets)
+// Each accepted connection is served by its own ARInGO, exposing the same events and REST helpers as a dialing one
+// Reconnecting is up to Asterisk, a connection which is lost is dropped
+type Server struct {
+	auth      Authenticator
+	onConnect func(*ARInGO)
+	opts      []Option
+	stopChan  chan struct{}
+	stopOnce  sync.Once
+	connsMux  sync.Mutex
+	conns     map[*ARInGO]struct{}
+}
+
+// NewServer creates a server accepting the connections allowed by auth
+// onConnect is called for each accepted connection before its events are dispatched, so subscriptions miss none of them
+// The websocket is already read meanwhile so REST requests sent over it get their replies
+// The options apply to each connection, without WithRESTURL the REST requests are sent over the websocket
+func NewServer(auth Authenticator, onConnect func(*ARInGO), opts ...Option) *Server {
+	return &Server{
+		auth:      auth,
+		onConnect: onConnect,
+		opts:      opts,
+		stopChan:  make(chan struct{}),
+		conns:     make(map[*ARInGO]struct{}),
+	}
+}
+
+// ServeHTTP authenticates the request and serves the ARI websocket for as long as Asterisk keeps it open
+func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if srv.auth == nil || !srv.auth(r) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="aringo"`)
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	select {
+	case <-srv.stopChan:
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	default:
+	}
+	websocket.Server{ // no Handshake so the Origin header, not sent by Asterisk, is not required
+		Handler: func(ws *websocket.Conn) {
+			ari := srv.newConnection(ws, r)
+			srv.connsMux.Lock()
+			srv.conns[ari] = struct{}{}
+			srv.connsMux.Unlock()
+			defer func() {
+				srv.connsMux.Lock()
+				delete(srv.conns, ari)
+				srv.connsMux.Unlock()
+			}()
+			listenerDone := make(chan struct{})
+			go func() {
+				ari.wsEventListener()
+				close(listenerDone)
+			}()
+			if srv.onConnect != nil {
+				srv.onConnect(ari)
+			}
+			ari.hold.release(ari)
+			<-listenerDone
+		},
+	}.ServeHTTP(w, r)
+}
+
+// newConnection creates the ARInGO serving one accepted websocket
+func (srv *Server) newConnection(ws *websocket.Conn, r *http.Request) (ari *ARInGO) {
+	wsURL := url.URL{Scheme: "ws", Host: r.RemoteAddr, Path: r.URL.Path, RawQuery: r.URL.RawQuery}
+	if r.TLS != nil {
+		wsURL.Scheme = "wss"
+	}
+	ari = &ARInGO{
+		httpClient:     new(http.Client),
+		wsURL:          wsURL.String(), // identifies the remote Asterisk inside the logs
+		ws:             ws,
+		inbound:        true,
+		hold:           new(eventHold), // released once onConnect returns
+		wsListenerExit: srv.stopChan,
+	}
+	for _, opt := range srv.opts {
+		opt(ari)
+	}
+	if ari.restURL == "" && ari.wsREST == nil { // no HTTP towards Asterisk, REST goes over the websocket
+		ari.wsREST = newWSRESTTransport(0)
+		ari.wsREST.state.Store(wsRESTSupported)
+	}
+	if ari.metrics != nil {
+		ari.metrics.ConnectionUp()
+	}
+	ari.log().Info("ARI connection accepted", "url", redactURL(ari.wsURL))
+	return
+}
+
+// Connections returns the currently connected Asterisk servers
+func (srv *Server) Connections() (conns []*ARInGO) {
+	srv.connsMux.Lock()
+	defer srv.connsMux.Unlock()
+	for ari := range srv.conns {
+		conns = append(conns, ari)
+	}
+	return
+}
+
+// Close refuses new connections and closes the existing ones
+func (srv *Server) Close() {
+	srv.stopOnce.Do(func() { close(srv.stopChan) })
+	for _, ari := range srv.Connections() {
+		ari.ws.Close()
+	}
+}
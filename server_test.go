@@ -0,0 +1,150 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package aringo
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// dialServer connects to the server as Asterisk would
+func dialServer(srvURL, username, password string) (*websocket.Conn, error) {
+	cfg, err := websocket.NewConfig("ws"+strings.TrimPrefix(srvURL, "http")+"/ari/outbound", srvURL)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(username+":"+password)))
+	return websocket.DialConfig(cfg)
+}
+
+func TestServer(t *testing.T) {
+	restSrv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte(`[{"id":"ch1"}]`))
+	}))
+	defer restSrv.Close()
+	subs := make(chan *Subscription, 2)
+	ariSrv := NewServer(BasicAuth(map[string]string{"asterisk1": "secret1", "asterisk2": "secret2"}),
+		func(ari *ARInGO) {
+			subs <- ari.Subscribe(TypeFilter("StasisStart"))
+		}, WithRESTURL(restSrv.URL+"/ari"))
+	srv := httptest.NewServer(ariSrv)
+	defer srv.Close()
+
+	if _, err := dialServer(srv.URL, "asterisk1", "wrong"); err == nil {
+		t.Error("Expected authentication error")
+	}
+	if resp, err := http.Get(srv.URL + "/ari/outbound"); err != nil {
+		t.Fatal(err)
+	} else if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", http.StatusUnauthorized, resp.StatusCode)
+	}
+
+	ast1, err := dialServer(srv.URL, "asterisk1", "secret1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ast1.Close()
+	ast2, err := dialServer(srv.URL, "asterisk2", "secret2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ast2.Close()
+	sub1, sub2 := <-subs, <-subs
+	if len(ariSrv.Connections()) != 2 {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", 2, len(ariSrv.Connections()))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for _, ast := range []*websocket.Conn{ast1, ast2} {
+		ast.Write([]byte(`{"type":"StasisStart","application":"test","channel":{"id":"ch1"}}`))
+	}
+	for _, sub := range []*Subscription{sub1, sub2} {
+		ev, err := sub.Next(ctx)
+		if err != nil {
+			t.Fatal(err)
+		} else if ev.ChannelID() != "ch1" {
+			t.Errorf("Unexpected event: %+v", ev)
+		}
+		if chs, err := sub.ari.ListChannels(); err != nil {
+			t.Error(err)
+		} else if len(chs) != 1 || chs[0].ID != "ch1" {
+			t.Errorf("Unexpected channels: %+v", chs)
+		}
+	}
+
+	ast1.Close() // lost connections are dropped
+	for deadline := time.Now().Add(time.Second); len(ariSrv.Connections()) != 1; time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("\nExpected: <%+v>, \nReceived: <%+v>", 1, len(ariSrv.Connections()))
+		}
+	}
+
+	ariSrv.Close()
+	ast2.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := ast2.Read(make([]byte, 1)); err == nil || strings.Contains(err.Error(), "timeout") {
+		t.Errorf("Expected connection closed by server, received: %v", err)
+	}
+	if _, err := dialServer(srv.URL, "asterisk1", "secret1"); err == nil {
+		t.Error("Expected connection refused after Close")
+	}
+}
+
+func TestServerOnConnectREST(t *testing.T) {
+	type setup struct {
+		info *AsteriskInfo
+		err  error
+		sub  *Subscription
+	}
+	setups := make(chan *setup, 1)
+	srv := httptest.NewServer(NewServer(func(*http.Request) bool { return true },
+		func(ari *ARInGO) { // REST over the websocket, answered while the events are held
+			s := new(setup)
+			s.info, s.err = ari.AsteriskInfo()
+			s.sub = ari.Subscribe(TypeFilter("StasisStart"))
+			setups <- s
+		}))
+	defer srv.Close()
+	ast, err := dialServer(srv.URL, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ast.Close()
+	var req RESTRequest
+	if err = websocket.JSON.Receive(ast, &req); err != nil {
+		t.Fatal(err)
+	}
+	ast.Write([]byte(`{"type":"StasisStart","application":"test","channel":{"id":"ch1"}}`)) // before the reply
+	websocket.JSON.Send(ast, RESTResponse{Type: EventTypeRESTResponse, RequestID: req.RequestID,
+		StatusCode: 200, MessageBody: `{"system":{"version":"22.0.0"}}`})
+	var s *setup
+	select {
+	case s = <-setups:
+	case <-time.After(time.Second):
+		t.Fatal("onConnect blocked")
+	}
+	if s.err != nil {
+		t.Fatal(s.err)
+	} else if s.info.System == nil || s.info.System.Version != "22.0.0" {
+		t.Errorf("Unexpected info: %+v", s.info)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if ev, err := s.sub.Next(ctx); err != nil { // held until onConnect returned
+		t.Fatal(err)
+	} else if ev.ChannelID() != "ch1" {
+		t.Errorf("Unexpected event: %+v", ev)
+	}
+}
@@ -0,0 +1,95 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package aringo
+
+import (
+	"fmt"
+	"strings"
+)
+
+const sipTag = "sip" // SIP header of the field, ie: `sip:"X-Account"`
+
+// ChannelVariables are the variables set on a channel when it is created
+// The SIP header methods add the PJSIP_HEADER functions changing the outgoing INVITE of PJSIP channels
+type ChannelVariables map[string]string
+
+// AddSIPHeader adds the header to the outgoing INVITE, returns vars for chaining, allocated if nil
+func (vars ChannelVariables) AddSIPHeader(name, value string) ChannelVariables {
+	if vars == nil {
+		vars = make(ChannelVariables)
+	}
+	vars["PJSIP_HEADER(add,"+name+")"] = value
+	return vars
+}
+
+// UpdateSIPHeader replaces the value of a header already part of the outgoing INVITE
+func (vars ChannelVariables) UpdateSIPHeader(name, value string) ChannelVariables {
+	if vars == nil {
+		vars = make(ChannelVariables)
+	}
+	vars["PJSIP_HEADER(update,"+name+")"] = value
+	return vars
+}
+
+// RemoveSIPHeader removes the header out of the outgoing INVITE, a trailing * removes all headers with the prefix
+func (vars ChannelVariables) RemoveSIPHeader(name string) ChannelVariables {
+	if vars == nil {
+		vars = make(ChannelVariables)
+	}
+	vars["PJSIP_HEADER(remove,"+name+")"] = ""
+	return vars
+}
+
+// SIPHeaders are the SIP headers of a channel, by name
+type SIPHeaders map[string]string
+
+// Decode populates the fields of the struct pointed by v out of the headers named by their sip tags
+// Fields without matching header are left unchanged
+func (hdrs SIPHeaders) Decode(v interface{}) (err error) {
+	var fields []taggedField
+	if fields, err = structFields(v, sipTag); err != nil {
+		return
+	}
+	for _, fld := range fields {
+		value, has := hdrs[fld.name]
+		if !has {
+			continue
+		}
+		if err = setField(fld.value, value); err != nil {
+			return fmt.Errorf("header %s: %w", fld.name, err)
+		}
+	}
+	return
+}
+
+// GetSIPHeader returns the value of one header out of the INVITE received on an inbound PJSIP channel
+func (ari *ARInGO) GetSIPHeader(channelID, name string) (string, error) {
+	return ari.GetChannelVar(channelID, "PJSIP_HEADER(read,"+name+")")
+}
+
+// GetSIPHeaders returns the headers of the INVITE received on an inbound PJSIP channel whose names start with prefix
+// (ie: X-), the first value is returned for the repeated headers
+func (ari *ARInGO) GetSIPHeaders(channelID, prefix string) (hdrs SIPHeaders, err error) {
+	var names string
+	if names, err = ari.GetChannelVar(channelID, "PJSIP_HEADERS("+prefix+")"); err != nil {
+		return
+	}
+	hdrs = make(SIPHeaders)
+	for _, name := range strings.Split(names, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		if _, has := hdrs[name]; has {
+			continue
+		}
+		if hdrs[name], err = ari.GetSIPHeader(channelID, name); err != nil {
+			return nil, err
+		}
+	}
+	return
+}
@@ -0,0 +1,122 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package aringo
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestChannelVariablesSIPHeaders(t *testing.T) {
+	vars := ChannelVariables{"CDR(account)": "1001"}.
+		AddSIPHeader("X-Route", "carrier1").
+		UpdateSIPHeader("P-Asserted-Identity", "<sip:1001@example.com>").
+		RemoveSIPHeader("X-Internal-*")
+	exp := ChannelVariables{
+		"CDR(account)":                             "1001",
+		"PJSIP_HEADER(add,X-Route)":                "carrier1",
+		"PJSIP_HEADER(update,P-Asserted-Identity)": "<sip:1001@example.com>",
+		"PJSIP_HEADER(remove,X-Internal-*)":        "",
+	}
+	if !reflect.DeepEqual(exp, vars) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp, vars)
+	}
+}
+
+func TestChannelVariablesSIPHeadersNil(t *testing.T) {
+	var p OriginateParams
+	p.Variables = p.Variables.AddSIPHeader("X-Account", "1001")
+	exp := ChannelVariables{"PJSIP_HEADER(add,X-Account)": "1001"}
+	if !reflect.DeepEqual(exp, p.Variables) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp, p.Variables)
+	}
+	var vars ChannelVariables
+	if vars = vars.UpdateSIPHeader("X-Route", "carrier1"); len(vars) != 1 {
+		t.Errorf("Unexpected variables: %+v", vars)
+	}
+	vars = nil
+	if vars = vars.RemoveSIPHeader("X-Internal-*"); len(vars) != 1 {
+		t.Errorf("Unexpected variables: %+v", vars)
+	}
+}
+
+func TestGetSIPHeaders(t *testing.T) {
+	ari, _ := newVarsServer(t, map[string]string{
+		"PJSIP_HEADERS(X-)":            "X-Account,X-Credit,X-Account",
+		"PJSIP_HEADER(read,X-Account)": "1001",
+		"PJSIP_HEADER(read,X-Credit)":  "12.5",
+	})
+	if value, err := ari.GetSIPHeader("ch1", "X-Account"); err != nil {
+		t.Error(err)
+	} else if value != "1001" {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", "1001", value)
+	}
+	hdrs, err := ari.GetSIPHeaders("ch1", "X-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := SIPHeaders{"X-Account": "1001", "X-Credit": "12.5"}
+	if !reflect.DeepEqual(exp, hdrs) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp, hdrs)
+	}
+	var typed struct {
+		Account string  `sip:"X-Account"`
+		Credit  float64 `sip:"X-Credit"`
+		Route   string  `sip:"X-Route"`
+	}
+	typed.Route = "unchanged"
+	if err := hdrs.Decode(&typed); err != nil {
+		t.Fatal(err)
+	}
+	if typed.Account != "1001" || typed.Credit != 12.5 || typed.Route != "unchanged" {
+		t.Errorf("Unexpected headers: %+v", typed)
+	}
+	var invalid struct {
+		Account bool `sip:"X-Account"`
+	}
+	if err := hdrs.Decode(&invalid); err == nil || err.Error() != `header X-Account: strconv.ParseBool: parsing "1001": invalid syntax` {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if _, err := ari.GetSIPHeaders("ch1", "P-"); !IsReplyCode(err, http.StatusNotFound) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestOriginateVariables(t *testing.T) {
+	var received []string
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = append(received, r.Method+" "+r.URL.RequestURI()+" "+r.Header.Get("Content-Type")+" "+string(body))
+		rw.Write([]byte(`{"id":"ch1"}`))
+	}))
+	defer srv.Close()
+	ari := NewRESTARInGO(srv.URL+"/ari", "", "", "aringo")
+	p := OriginateParams{Endpoint: "PJSIP/1001", App: "test", ChannelID: "ch1",
+		Variables: ChannelVariables{}.AddSIPHeader("X-Account", "1001")}
+	if _, err := ari.Originate(p); err != nil {
+		t.Error(err)
+	}
+	if _, err := ari.CreateChannel(p); err != nil {
+		t.Error(err)
+	}
+	p.Variables = nil
+	if _, err := ari.Originate(p); err != nil {
+		t.Error(err)
+	}
+	exp := []string{
+		`POST /ari/channels?app=test&channelId=ch1&endpoint=PJSIP%2F1001 application/json {"variables":{"PJSIP_HEADER(add,X-Account)":"1001"}}`,
+		`POST /ari/channels/create?app=test&channelId=ch1&endpoint=PJSIP%2F1001 application/json {"variables":{"PJSIP_HEADER(add,X-Account)":"1001"}}`,
+		`POST /ari/channels?app=test&channelId=ch1&endpoint=PJSIP%2F1001  `,
+	}
+	if !reflect.DeepEqual(exp, received) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp, received)
+	}
+}
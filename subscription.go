@@ -0,0 +1,188 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package aringo
+
+import (
+	"context"
+	"errors"
+)
+
+const subscriptionBuffer = 64 // events queued per subscription before closing it
+
+var (
+	ErrSubscriptionClosed   = errors.New("SUBSCRIPTION_CLOSED")
+	ErrSubscriptionOverflow = errors.New("SUBSCRIPTION_OVERFLOW")
+)
+
+// EventFilter selects the events of interest for a subscription
+type EventFilter func(*Event) bool
+
+// TypeFilter matches the events of the given types
+func TypeFilter(evTypes ...string) EventFilter {
+	return func(ev *Event) bool {
+		for _, evType := range evTypes {
+			if ev.Type == evType {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// ChannelFilter matches the events related to the given channel
+func ChannelFilter(channelID string) EventFilter {
+	return func(ev *Event) bool {
+		return ev.ChannelID() == channelID
+	}
+}
+
+// BridgeFilter matches the events related to the given bridge
+func BridgeFilter(bridgeID string) EventFilter {
+	return func(ev *Event) bool {
+		return ev.BridgeID() == bridgeID
+	}
+}
+
+// PlaybackFilter matches the events related to the given playback
+func PlaybackFilter(playbackID string) EventFilter {
+	return func(ev *Event) bool {
+		return ev.PlaybackID() == playbackID
+	}
+}
+
+// RecordingFilter matches the events related to the given live recording
+func RecordingFilter(name string) EventFilter {
+	return func(ev *Event) bool {
+		return ev.RecordingName() == name
+	}
+}
+
+// AllFilters matches the events matched by all of the filters
+func AllFilters(filters ...EventFilter) EventFilter {
+	return func(ev *Event) bool {
+		for _, filter := range filters {
+			if !filter(ev) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// AnyFilter matches the events matched by any of the filters
+func AnyFilter(filters ...EventFilter) EventFilter {
+	return func(ev *Event) bool {
+		for _, filter := range filters {
+			if filter(ev) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Subscription receives the events matching its filter, in parallel with the evChannel
+// The subscription is closed with ErrSubscriptionOverflow if the subscriber does not keep up with the events
+type Subscription struct {
+	ari    *ARInGO
+	id     uint64
+	filter EventFilter
+	events chan *Event
+	err    error // reason of closing, set before events is closed
+}
+
+// Events returns the channel where the matching events are posted, closed once the subscription ends
+func (sub *Subscription) Events() <-chan *Event {
+	return sub.events
+}
+
+// Err returns why the events channel was closed, ErrSubscriptionOverflow if events were lost
+func (sub *Subscription) Err() (err error) {
+	sub.ari.subsMux.RLock()
+	err = sub.err
+	sub.ari.subsMux.RUnlock()
+	return
+}
+
+// Close stops posting events into the subscription and closes its events channel
+func (sub *Subscription) Close() {
+	sub.close(ErrSubscriptionClosed)
+}
+
+func (sub *Subscription) close(err error) {
+	sub.ari.subsMux.Lock()
+	defer sub.ari.subsMux.Unlock()
+	if _, has := sub.ari.subs[sub.id]; !has { // closed already
+		return
+	}
+	delete(sub.ari.subs, sub.id)
+	sub.err = err
+	close(sub.events)
+}
+
+// Subscribe registers interest in the events matching filter
+// Subscribe before issuing the REST call which triggers the events so none is missed
+func (ari *ARInGO) Subscribe(filter EventFilter) (sub *Subscription) {
+	ari.subsMux.Lock()
+	defer ari.subsMux.Unlock()
+	if ari.subs == nil {
+		ari.subs = make(map[uint64]*Subscription)
+	}
+	ari.lastSubID++
+	sub = &Subscription{
+		ari:    ari,
+		id:     ari.lastSubID,
+		filter: filter,
+		events: make(chan *Event, subscriptionBuffer),
+	}
+	ari.subs[sub.id] = sub
+	return
+}
+
+// Await blocks until an event matching filter is received or the context is done
+func (ari *ARInGO) Await(ctx context.Context, filter EventFilter) (*Event, error) {
+	sub := ari.Subscribe(filter)
+	defer sub.Close()
+	return sub.Next(ctx)
+}
+
+// Next returns the next event of the subscription or the context error
+// Returns ErrSubscriptionClosed once the subscription is closed, ErrSubscriptionOverflow if events were lost
+func (sub *Subscription) Next(ctx context.Context) (*Event, error) {
+	select {
+	case ev, ok := <-sub.events:
+		if !ok {
+			return nil, sub.Err()
+		}
+		return ev, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// dispatch posts the event into the matching subscriptions
+// The subscriptions not keeping up are closed with ErrSubscriptionOverflow instead of silently missing the event
+func (ari *ARInGO) dispatch(ev *Event) {
+	var overflown []*Subscription
+	ari.subsMux.RLock()
+	for _, sub := range ari.subs {
+		if !sub.filter(ev) {
+			continue
+		}
+		select {
+		case sub.events <- ev:
+		default: // subscriber not keeping up
+			overflown = append(overflown, sub)
+		}
+	}
+	ari.subsMux.RUnlock()
+	for _, sub := range overflown {
+		ari.log().Warn("ARI subscription closed, events not consumed in time", "type", ev.Type, "subscription", sub.id)
+		sub.close(ErrSubscriptionOverflow)
+	}
+}
@@ -0,0 +1,149 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package aringo
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSubscriptionFilters(t *testing.T) {
+	ev, err := decodeEvent([]byte(`{"type":"PlaybackStarted","playback":{"id":"pb1","target_uri":"channel:ch1"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, tc := range []struct {
+		filter EventFilter
+		exp    bool
+	}{
+		{TypeFilter("PlaybackStarted", "PlaybackFinished"), true},
+		{TypeFilter("StasisStart"), false},
+		{PlaybackFilter("pb1"), true},
+		{PlaybackFilter("pb2"), false},
+		{ChannelFilter("ch1"), false},
+		{AllFilters(TypeFilter("PlaybackStarted"), PlaybackFilter("pb1")), true},
+		{AllFilters(TypeFilter("PlaybackStarted"), PlaybackFilter("pb2")), false},
+		{AllFilters(), true},
+		{AnyFilter(TypeFilter("StasisStart"), PlaybackFilter("pb1")), true},
+		{AnyFilter(TypeFilter("StasisStart"), PlaybackFilter("pb2")), false},
+		{AnyFilter(), false},
+		{BridgeFilter("br1"), false},
+		{RecordingFilter("pb1"), false},
+	} {
+		if rcv := tc.filter(ev); rcv != tc.exp {
+			t.Errorf("Filter %d, \nExpected: <%+v>, \nReceived: <%+v>", i, tc.exp, rcv)
+		}
+	}
+}
+
+func TestSubscriptionRecordingFilter(t *testing.T) {
+	ev, err := decodeEvent([]byte(`{"type":"RecordingStarted","recording":{"name":"rec1","target_uri":"channel:ch1"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !RecordingFilter("rec1")(ev) {
+		t.Error("Expected rec1 to match")
+	}
+	if RecordingFilter("rec2")(ev) {
+		t.Error("Expected rec2 not to match")
+	}
+}
+
+func TestSubscriptionDispatch(t *testing.T) {
+	ari := &ARInGO{evChannel: make(chan map[string]interface{}, 10)}
+	sub := ari.Subscribe(TypeFilter("StasisStart"))
+	ari.handleFrame([]byte(`{"type":"StasisEnd"}`))
+	ari.handleFrame([]byte(`{"type":"StasisStart","channel":{"id":"ch1"}}`))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	ev, err := sub.Next(ctx)
+	if err != nil {
+		t.Fatal(err)
+	} else if ev.Type != "StasisStart" || ev.ChannelID() != "ch1" {
+		t.Errorf("Unexpected event: %+v", ev)
+	}
+	if len(ari.evChannel) != 2 { // subscriptions do not consume events
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", 2, len(ari.evChannel))
+	}
+
+	sub.Close()
+	sub.Close() // closing twice is harmless
+	if len(ari.subs) != 0 {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", 0, len(ari.subs))
+	}
+	if _, err = sub.Next(ctx); err != ErrSubscriptionClosed {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", ErrSubscriptionClosed, err)
+	}
+}
+
+func TestSubscriptionOverflow(t *testing.T) {
+	ari := new(ARInGO)
+	sub := ari.Subscribe(TypeFilter("StasisStart"))
+	other := ari.Subscribe(TypeFilter("StasisEnd"))
+	defer other.Close()
+	ev := &Event{Type: "StasisStart"}
+	for i := 0; i < subscriptionBuffer+1; i++ { // overflow closes the subscription without blocking the listener
+		ari.dispatch(ev)
+	}
+	if len(ari.subs) != 1 {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", 1, len(ari.subs))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for i := 0; i < subscriptionBuffer; i++ { // queued events are still delivered
+		if _, err := sub.Next(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := sub.Next(ctx); err != ErrSubscriptionOverflow {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", ErrSubscriptionOverflow, err)
+	}
+	sub.Close() // the reason is kept
+	if err := sub.Err(); err != ErrSubscriptionOverflow {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", ErrSubscriptionOverflow, err)
+	}
+	ari.dispatch(ev) // not posted anymore
+}
+
+func TestAwaitTimeout(t *testing.T) {
+	ari := new(ARInGO)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if ev, err := ari.Await(ctx, TypeFilter("StasisStart")); err != context.DeadlineExceeded {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", context.DeadlineExceeded, err)
+	} else if ev != nil {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", nil, ev)
+	}
+	if len(ari.subs) != 0 {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", 0, len(ari.subs))
+	}
+}
+
+func TestAwait(t *testing.T) {
+	ari := &ARInGO{evChannel: make(chan map[string]interface{}, 10)}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		ari.handleFrame([]byte(`{"type":"ChannelDtmfReceived","digit":"1","channel":{"id":"ch2"}}`))
+		ari.handleFrame([]byte(`{"type":"ChannelDtmfReceived","digit":"2","channel":{"id":"ch1"}}`))
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	ev, err := ari.Await(ctx, AllFilters(TypeFilter("ChannelDtmfReceived"), ChannelFilter("ch1")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dtmf struct {
+		Digit string `json:"digit"`
+	}
+	if err = ev.Decode(&dtmf); err != nil {
+		t.Fatal(err)
+	} else if dtmf.Digit != "2" {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", "2", dtmf.Digit)
+	}
+}
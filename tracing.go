@@ -0,0 +1,222 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package aringo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	tracerName = "github.com/cgrates/aringo"
+
+	channelSpanTTL = 6 * time.Hour // traced channels without events or calls for this long are forgotten
+)
+
+// WithTracerProvider enables tracing of the REST calls and of the events received for the traced channels
+// Each channel gets attached to the span of the call which created it (or of its StasisStart for incoming channels)
+// so its whole lifecycle inside the application shows up as one trace
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(ari *ARInGO) {
+		ari.tracer = tp.Tracer(tracerName)
+	}
+}
+
+// channelCreatingResources are the REST resources creating channels when POSTed
+var channelCreatingResources = map[string]bool{
+	"/channels":                 true,
+	"/channels/{id}":            true,
+	"/channels/create":          true,
+	"/channels/externalMedia":   true,
+	"/channels/{id}/snoop":      true,
+	"/channels/{id}/snoop/{id}": true,
+}
+
+// startCallSpan starts the span of one REST call
+// Calls on a traced channel are attached to its trace unless ctx carries a span already
+func (ari *ARInGO) startCallSpan(ctx context.Context, method string, u *url.URL, data url.Values) (context.Context, trace.Span) {
+	resource := resourceTemplate(ari.restURL, u)
+	segments := resourceSegments(ari.restURL, u)
+	if !trace.SpanContextFromContext(ctx).IsValid() &&
+		len(segments) > 1 && segments[0] == "channels" {
+		if sc, has := ari.channelSpan(segments[1]); has {
+			ctx = trace.ContextWithSpanContext(ctx, sc)
+		}
+	}
+	ctx, span := ari.tracer.Start(ctx, "ARI "+method+" "+resource,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", method),
+			attribute.String("ari.resource", resource),
+		))
+	if method == HTTP_POST && channelCreatingResources[resource] {
+		if chID := createdChannelID(u, data, segments); chID != "" { // before the request since events can come before the reply
+			ari.setChannelSpan(chID, span.SpanContext())
+		}
+	}
+	return ctx, span
+}
+
+// createdChannelID returns the client supplied id of the channel created by a request
+func createdChannelID(u *url.URL, data url.Values, segments []string) string {
+	query := u.Query()
+	for _, key := range []string{"channelId", "snoopId"} {
+		if id := query.Get(key); id != "" {
+			return id
+		}
+		if id := data.Get(key); id != "" {
+			return id
+		}
+	}
+	switch {
+	case len(segments) == 2 && segments[1] != "create" && segments[1] != "externalMedia": // /channels/{id}
+		return segments[1]
+	case len(segments) == 4: // /channels/{id}/snoop/{id}
+		return segments[3]
+	}
+	return ""
+}
+
+// endCallSpan records the outcome of one REST call
+func (ari *ARInGO) endCallSpan(span trace.Span, method string, u *url.URL, data url.Values, reply []byte, err error) {
+	defer span.End()
+	createsChannel := method == HTTP_POST && channelCreatingResources[resourceTemplate(ari.restURL, u)]
+	if err != nil {
+		var rcErr *UnexpectedReplyCodeError
+		if errors.As(err, &rcErr) && rcErr.Message != "" {
+			span.SetAttributes(attribute.String("ari.message", rcErr.Message))
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		if createsChannel {
+			if chID := createdChannelID(u, data, resourceSegments(ari.restURL, u)); chID != "" {
+				ari.removeChannelSpan(chID)
+			}
+		}
+		return
+	}
+	if method == HTTP_DELETE && resourceTemplate(ari.restURL, u) == "/channels/{id}" { // hung up, maybe without Stasis events
+		ari.removeChannelSpan(resourceSegments(ari.restURL, u)[1])
+		return
+	}
+	if !createsChannel {
+		return
+	}
+	var ch struct {
+		ID string `json:"id"`
+	}
+	if json.Unmarshal(reply, &ch) == nil && ch.ID != "" {
+		ari.setChannelSpan(ch.ID, span.SpanContext())
+	}
+}
+
+// channelSpan is the span context of one traced channel
+type channelSpan struct {
+	sc   trace.SpanContext
+	seen time.Time // last event or call of the channel
+}
+
+// channelSpan returns the span context of the traced channel, marking it as seen
+func (ari *ARInGO) channelSpan(channelID string) (sc trace.SpanContext, has bool) {
+	ari.chanSpansMux.Lock()
+	defer ari.chanSpansMux.Unlock()
+	var cs *channelSpan
+	if cs, has = ari.chanSpans[channelID]; has {
+		cs.seen = time.Now()
+		sc = cs.sc
+	}
+	return
+}
+
+// setChannelSpan traces the channel, removing the stale ones whose hangup was never seen (ie: channels sent into the dialplan)
+func (ari *ARInGO) setChannelSpan(channelID string, sc trace.SpanContext) {
+	ari.chanSpansMux.Lock()
+	defer ari.chanSpansMux.Unlock()
+	now := time.Now()
+	if ari.chanSpans == nil {
+		ari.chanSpans = make(map[string]*channelSpan)
+		ari.chanSpansPruned = now
+	}
+	if now.Sub(ari.chanSpansPruned) >= channelSpanTTL {
+		for chID, cs := range ari.chanSpans {
+			if now.Sub(cs.seen) >= channelSpanTTL {
+				delete(ari.chanSpans, chID)
+			}
+		}
+		ari.chanSpansPruned = now
+	}
+	ari.chanSpans[channelID] = &channelSpan{sc: sc, seen: now}
+}
+
+func (ari *ARInGO) removeChannelSpan(channelID string) {
+	ari.chanSpansMux.Lock()
+	delete(ari.chanSpans, channelID)
+	ari.chanSpansMux.Unlock()
+}
+
+// eventChannelID returns the channel an event refers to, including the playbacks and recordings targeting channels
+func eventChannelID(ev *Event) string {
+	if chID := ev.ChannelID(); chID != "" {
+		return chID
+	}
+	var targets struct {
+		Playback struct {
+			TargetURI string `json:"target_uri"`
+		} `json:"playback"`
+		Recording struct {
+			TargetURI string `json:"target_uri"`
+		} `json:"recording"`
+	}
+	if ev.Decode(&targets) != nil {
+		return ""
+	}
+	for _, target := range []string{targets.Playback.TargetURI, targets.Recording.TargetURI} {
+		if strings.HasPrefix(target, "channel:") {
+			return strings.TrimPrefix(target, "channel:")
+		}
+	}
+	return ""
+}
+
+// traceEvent records the event as part of its channel trace and attaches the span to the event context
+// Incoming channels start their trace with the StasisStart event
+func (ari *ARInGO) traceEvent(ev *Event) {
+	chID := eventChannelID(ev)
+	if chID == "" {
+		return
+	}
+	ctx := context.Background()
+	sc, has := ari.channelSpan(chID)
+	if has {
+		ctx = trace.ContextWithSpanContext(ctx, sc)
+	} else if ev.Type != "StasisStart" {
+		return
+	}
+	var span trace.Span
+	ev.ctx, span = ari.tracer.Start(ctx, "ARI event "+ev.Type,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("ari.event.type", ev.Type),
+			attribute.String("ari.channel.id", chID),
+		))
+	span.End()
+	switch {
+	case ev.Type == "StasisEnd" || ev.Type == "ChannelDestroyed": // the application is not following the channel anymore
+		ari.removeChannelSpan(chID)
+	case !has:
+		ari.setChannelSpan(chID, span.SpanContext())
+	}
+}
@@ -0,0 +1,164 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package aringo
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func spanAttr(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestCreatedChannelID(t *testing.T) {
+	for reqURL, exp := range map[string]string{
+		"http://127.0.0.1/ari/channels?endpoint=PJSIP/1001&channelId=ch1": "ch1",
+		"http://127.0.0.1/ari/channels/ch2?endpoint=PJSIP/1001":           "ch2",
+		"http://127.0.0.1/ari/channels/create?channelId=ch3":              "ch3",
+		"http://127.0.0.1/ari/channels/externalMedia":                     "",
+		"http://127.0.0.1/ari/channels/ch1/snoop/ch4":                     "ch4",
+		"http://127.0.0.1/ari/channels/ch1/snoop?snoopId=ch5":             "ch5",
+	} {
+		u, _ := url.Parse(reqURL)
+		if rcv := createdChannelID(u, nil, resourceSegments("http://127.0.0.1/ari", u)); rcv != exp {
+			t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp, rcv)
+		}
+	}
+}
+
+func TestTracingChannelSpansRemoved(t *testing.T) {
+	tp := sdktrace.NewTracerProvider()
+	fake, ari := newFakeARI(t, WithTracerProvider(tp))
+	fake.mux.HandleFunc("/ari/channels", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte(`{"id":"ch1"}`))
+	})
+	fake.mux.HandleFunc("/ari/channels/ch1", func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusNoContent)
+	})
+	if _, err := ari.Originate(OriginateParams{Endpoint: "PJSIP/1001", Extension: "1002", Context: "default", ChannelID: "ch1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, has := ari.channelSpan("ch1"); !has {
+		t.Fatal("Expected ch1 traced")
+	}
+	if err := ari.Hangup("ch1", ""); err != nil { // no Stasis event for the channels inside the dialplan
+		t.Fatal(err)
+	}
+	if _, has := ari.channelSpan("ch1"); has {
+		t.Error("Expected ch1 span removed after the hangup")
+	}
+
+	// the stale channels are removed once the pruning is due
+	ari.setChannelSpan("ch2", trace.SpanContext{})
+	ari.setChannelSpan("ch3", trace.SpanContext{})
+	ari.chanSpansMux.Lock()
+	ari.chanSpans["ch2"].seen = time.Now().Add(-channelSpanTTL)
+	ari.chanSpansPruned = time.Now().Add(-channelSpanTTL)
+	ari.chanSpansMux.Unlock()
+	ari.setChannelSpan("ch4", trace.SpanContext{})
+	for chID, exp := range map[string]bool{"ch2": false, "ch3": true, "ch4": true} {
+		if _, has := ari.channelSpan(chID); has != exp {
+			t.Errorf("%s, \nExpected: <%+v>, \nReceived: <%+v>", chID, exp, has)
+		}
+	}
+}
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	fake, ari := newFakeARI(t, WithTracerProvider(tp), WithIDGenerator(func() string { return "ch1" }))
+	fake.mux.HandleFunc("/ari/channels", func(rw http.ResponseWriter, r *http.Request) {
+		fake.send(`{"type":"StasisStart","channel":{"id":"ch1"}}`) // before the reply
+		time.Sleep(10 * time.Millisecond)
+		rw.Write([]byte(`{"id":"ch1"}`))
+	})
+	fake.mux.HandleFunc("/ari/channels/ch1/play/", func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusNotFound)
+		rw.Write([]byte(`{"message":"Channel not found"}`))
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := ari.OriginateAndWait(ctx, OriginateParams{Endpoint: "PJSIP/1001", App: "test"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ari.Play("ch1", "sound:hello"); !IsReplyCode(err, http.StatusNotFound) {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sub := ari.Subscribe(TypeFilter("StasisStart", "ChannelDestroyed"))
+	defer sub.Close()
+	fake.send(`{"type":"StasisStart","channel":{"id":"ch2"}}`) // incoming channel
+	fake.send(`{"type":"ChannelDestroyed","channel":{"id":"ch1"}}`)
+	ev2, err := sub.Next(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = sub.Next(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 5 {
+		t.Fatalf("\nExpected: <%+v>, \nReceived: <%+v>", 5, len(spans))
+	}
+	byName := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range spans {
+		if spanAttr(span, "ari.channel.id").AsString() == "ch2" {
+			continue // incoming channel checked out of the event context
+		}
+		byName[span.Name()] = span
+	}
+	originate := byName["ARI POST /channels"]
+	if originate == nil {
+		t.Fatalf("Originate span missing out of: %+v", byName)
+	}
+	if status := spanAttr(originate, "http.response.status_code").AsInt64(); status != http.StatusOK {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", http.StatusOK, status)
+	}
+	traceID := originate.SpanContext().TraceID()
+	for _, name := range []string{"ARI event StasisStart", "ARI POST /channels/{id}/play/{id}", "ARI event ChannelDestroyed"} {
+		span := byName[name]
+		if span == nil {
+			t.Fatalf("Span %s missing out of: %+v", name, byName)
+		}
+		if span.Parent().SpanID() != originate.SpanContext().SpanID() || span.SpanContext().TraceID() != traceID {
+			t.Errorf("Span %s not part of the originate trace", name)
+		}
+	}
+	play := byName["ARI POST /channels/{id}/play/{id}"]
+	if play.Status().Code != codes.Error {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", codes.Error, play.Status().Code)
+	}
+	if msg := spanAttr(play, "ari.message").AsString(); msg != "Channel not found" {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", "Channel not found", msg)
+	}
+
+	// the incoming channel starts its own trace, exposed by the event context
+	evSC := trace.SpanContextFromContext(ev2.Context())
+	if !evSC.IsValid() || evSC.TraceID() == traceID {
+		t.Errorf("Unexpected span context for incoming channel: %+v", evSC)
+	}
+	if _, has := ari.channelSpan("ch1"); has {
+		t.Error("Expected ch1 span removed after ChannelDestroyed")
+	}
+}
@@ -0,0 +1,268 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package aringo
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var (
+	ErrTransfereeHangup     = errors.New("TRANSFEREE_HANGUP")
+	ErrTransfererHangup     = errors.New("TRANSFERER_HANGUP")
+	ErrTransferTargetHangup = errors.New("TRANSFER_TARGET_HANGUP")
+	ErrTransferCancelled    = errors.New("TRANSFER_CANCELLED")
+	ErrTransferFinished     = errors.New("TRANSFER_FINISHED")
+)
+
+// transferParams prepares the originate of a transfer target, which needs to enter Stasis so it can be bridged
+// The application defaults to the first one of the connection
+func (ari *ARInGO) transferParams(p *OriginateParams) (err error) {
+	if p.App, err = ari.defaultApp(p.App); err != nil {
+		return
+	}
+	p.setIDs(ari)
+	return
+}
+
+// watchTransfer returns a context canceled once the bridge is left empty, with ErrTransfereeHangup as cause,
+// or once one of the hangups channels leaves Stasis, with the error mapped to the channel as cause
+func (ari *ARInGO) watchTransfer(ctx context.Context, bridgeID string,
+	hangups map[string]error) (wCtx context.Context, stop context.CancelFunc) {
+	filters := []EventFilter{AllFilters(TypeFilter("ChannelLeftBridge", "BridgeDestroyed"), BridgeFilter(bridgeID))}
+	for channelID := range hangups {
+		filters = append(filters, AllFilters(TypeFilter("StasisEnd", "ChannelDestroyed"), ChannelFilter(channelID)))
+	}
+	sub := ari.Subscribe(AnyFilter(filters...))
+	var cancel context.CancelCauseFunc
+	wCtx, cancel = context.WithCancelCause(ctx)
+	go func() {
+		defer sub.Close()
+		for {
+			ev, err := sub.Next(wCtx)
+			if err != nil {
+				return
+			}
+			if cause := transferHangup(ev, bridgeID, hangups); cause != nil {
+				cancel(cause)
+				return
+			}
+		}
+	}()
+	return wCtx, func() { cancel(context.Canceled) }
+}
+
+// transferHangup returns the error of an event ending the transfer, nil for the other events
+func transferHangup(ev *Event, bridgeID string, hangups map[string]error) error {
+	switch ev.Type {
+	case "BridgeDestroyed":
+		return ErrTransfereeHangup
+	case "ChannelLeftBridge":
+		var left BridgeChannelEvent
+		if ev.Decode(&left) == nil && len(left.Bridge.Channels) == 0 {
+			return ErrTransfereeHangup
+		}
+	case "StasisEnd", "ChannelDestroyed":
+		return hangups[ev.ChannelID()]
+	}
+	return nil
+}
+
+// BlindTransfer hangs up the transferer and dials the endpoint of p into the bridge in its place
+// The channels left in the bridge hear music on hold until the target answers
+// On failure the music on hold is stopped and the channels are left in the bridge, ErrTransfereeHangup
+// is returned if they hang up meanwhile. To send a channel to another endpoint without dialing use Redirect
+func (ari *ARInGO) BlindTransfer(ctx context.Context, bridgeID, transfererID string,
+	p OriginateParams) (target *Channel, err error) {
+	if err = ari.transferParams(&p); err != nil {
+		return
+	}
+	wCtx, stop := ari.watchTransfer(ctx, bridgeID, nil) // before touching the bridge so no hangup is missed
+	defer stop()
+	if err = ari.RemoveChannel(bridgeID, transfererID); err != nil {
+		return
+	}
+	ari.Hangup(transfererID, "") // the transferer could have hung up already
+	if err = ari.StartBridgeMOH(bridgeID, ""); err != nil {
+		return
+	}
+	if target, err = ari.OriginateAndWait(wCtx, p); err != nil {
+		if wCtx.Err() != nil {
+			err = context.Cause(wCtx)
+			ari.Hangup(p.ChannelID, "") // still ringing
+		}
+		ari.StopBridgeMOH(bridgeID)
+		return nil, err
+	}
+	if err = ari.AddChannel(bridgeID, target.ID); err != nil {
+		ari.Hangup(target.ID, "")
+		ari.StopBridgeMOH(bridgeID)
+		return nil, err
+	}
+	if err = ari.StopBridgeMOH(bridgeID); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// AttendedTransfer is a consult call in progress
+// The transferee waits on hold in its bridge while the transferer talks to the target inside a consult bridge
+type AttendedTransfer struct {
+	ari             *ARInGO
+	BridgeID        string   // bridge of the transferee
+	TransfererID    string   // channel transferring the call
+	Target          *Channel // channel of the consulted party, once answered
+	ConsultBridgeID string   // bridge of the transferer and the target
+	targetID        string
+	stop            context.CancelFunc // stops watching the hangups
+
+	mux  sync.Mutex
+	done chan struct{}
+	err  error
+}
+
+// StartAttendedTransfer puts the bridge on hold, moves the transferer into a consult bridge and dials the endpoint of p there
+// Returns once the target answers, the initial state is restored on failure
+// Once started, the transfer is completed if the transferer hangs up and cancelled if the target hangs up
+func (ari *ARInGO) StartAttendedTransfer(ctx context.Context, bridgeID, transfererID string,
+	p OriginateParams) (at *AttendedTransfer, err error) {
+	if err = ari.transferParams(&p); err != nil {
+		return
+	}
+	at = &AttendedTransfer{
+		ari:          ari,
+		BridgeID:     bridgeID,
+		TransfererID: transfererID,
+		targetID:     p.ChannelID,
+		done:         make(chan struct{}),
+	}
+	var wCtx context.Context
+	wCtx, at.stop = ari.watchTransfer(context.Background(), bridgeID, map[string]error{
+		transfererID: ErrTransfererHangup,
+		p.ChannelID:  ErrTransferTargetHangup,
+	})
+	defer func() {
+		if err == nil {
+			return
+		}
+		if wCtx.Err() != nil { // a hangup caused the failure
+			err = context.Cause(wCtx)
+		}
+		at.stop()
+		at.rollback()
+		at = nil
+	}()
+	if err = ari.RemoveChannel(bridgeID, transfererID); err != nil {
+		return
+	}
+	if err = ari.StartBridgeMOH(bridgeID, ""); err != nil {
+		return
+	}
+	var consult *Bridge
+	if consult, err = ari.CreateBridge(BridgeParams{Type: "mixing"}); err != nil {
+		return
+	}
+	at.ConsultBridgeID = consult.ID
+	if err = ari.AddChannel(consult.ID, transfererID); err != nil {
+		return
+	}
+	origCtx, cancel := context.WithCancel(ctx) // stop waiting for the target on hangups
+	defer cancel()
+	defer context.AfterFunc(wCtx, cancel)()
+	if at.Target, err = ari.OriginateAndWait(origCtx, p); err != nil {
+		return
+	}
+	if err = ari.AddChannel(consult.ID, at.Target.ID); err != nil {
+		return
+	}
+	go at.watch(wCtx)
+	return
+}
+
+// watch ends the transfer on hangups
+func (at *AttendedTransfer) watch(wCtx context.Context) {
+	<-wCtx.Done()
+	switch cause := context.Cause(wCtx); cause {
+	case ErrTransfererHangup:
+		at.Complete()
+	case ErrTransferTargetHangup:
+		at.finish(cause, at.rollback)
+	case ErrTransfereeHangup: // nobody left to transfer, transferer and target stay in the consult bridge
+		at.finish(cause, nil)
+	}
+}
+
+// Complete bridges the target with the transferee and hangs up the transferer
+// The transferer is returned into its bridge if the target cannot be moved there
+func (at *AttendedTransfer) Complete() error {
+	return at.finish(nil, at.complete)
+}
+
+// Cancel hangs up the target and returns the transferer into its bridge
+func (at *AttendedTransfer) Cancel() error {
+	return at.finish(ErrTransferCancelled, at.rollback)
+}
+
+// Done is closed once the transfer was completed or cancelled
+func (at *AttendedTransfer) Done() <-chan struct{} {
+	return at.done
+}
+
+// Err returns the outcome of a finished transfer: nil if completed, ErrTransferCancelled or the hangup which ended it
+func (at *AttendedTransfer) Err() error {
+	at.mux.Lock()
+	defer at.mux.Unlock()
+	return at.err
+}
+
+// finish executes the action ending the transfer, only once
+func (at *AttendedTransfer) finish(result error, action func() error) (err error) {
+	at.mux.Lock()
+	defer at.mux.Unlock()
+	select {
+	case <-at.done:
+		return ErrTransferFinished
+	default:
+	}
+	at.stop()
+	if action != nil {
+		err = action()
+	}
+	if result == nil {
+		result = err
+	}
+	at.err = result
+	close(at.done)
+	return
+}
+
+func (at *AttendedTransfer) complete() (err error) {
+	at.ari.DestroyBridge(at.ConsultBridgeID)
+	if err = at.ari.AddChannel(at.BridgeID, at.Target.ID); err != nil {
+		at.rollback()
+		return
+	}
+	at.ari.StopBridgeMOH(at.BridgeID)
+	at.ari.Hangup(at.TransfererID, "") // the transferer could have hung up already
+	return
+}
+
+// rollback hangs up the target and returns the transferer into its bridge
+// Best effort since any of the parties could be gone, the error of returning the transferer is reported
+func (at *AttendedTransfer) rollback() (err error) {
+	at.ari.Hangup(at.targetID, "")
+	if at.ConsultBridgeID != "" {
+		at.ari.DestroyBridge(at.ConsultBridgeID)
+	}
+	err = at.ari.AddChannel(at.BridgeID, at.TransfererID)
+	if errMOH := at.ari.StopBridgeMOH(at.BridgeID); err == nil {
+		err = errMOH
+	}
+	return
+}
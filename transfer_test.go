@@ -0,0 +1,254 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package aringo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// transferFake answers the REST calls of the transfers, logging them
+// The originated target answers unless busy is set
+type transferFake struct {
+	*fakeARI
+	busy bool // guarded by reqMux
+}
+
+func newTransferFake(t *testing.T) (fake *transferFake, ari *ARInGO) {
+	var lastID int
+	fake = new(transferFake)
+	fake.fakeARI, ari = newFakeARI(t, WithIDGenerator(func() string {
+		lastID++
+		return "id" + strconv.Itoa(lastID)
+	}))
+	fake.handleREST("/ari/", func(rw http.ResponseWriter, r *http.Request) {
+		fake.reqMux.Lock()
+		busy := fake.busy
+		fake.reqMux.Unlock()
+		switch {
+		case r.URL.Path == "/ari/channels":
+			id := r.URL.Query().Get("channelId")
+			rw.Write([]byte(`{"id":"` + id + `"}`))
+			if busy {
+				fake.send(`{"type":"ChannelDestroyed","cause":17,"cause_txt":"User busy","channel":{"id":"` + id + `"}}`)
+			} else {
+				fake.send(`{"type":"StasisStart","application":"test","channel":{"id":"` + id + `","state":"Up"}}`)
+			}
+		case r.Method == HTTP_POST && r.URL.Path == "/ari/bridges/id2":
+			rw.Write([]byte(`{"id":"id2","bridge_type":"mixing"}`))
+		default:
+			rw.WriteHeader(http.StatusNoContent)
+		}
+	})
+	return
+}
+
+func waitTransfer(t *testing.T, at *AttendedTransfer) {
+	t.Helper()
+	select {
+	case <-at.Done():
+	case <-time.After(time.Second):
+		t.Fatal("transfer not finished")
+	}
+}
+
+func TestBlindTransfer(t *testing.T) {
+	fake, ari := newTransferFake(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	target, err := ari.BlindTransfer(ctx, "br1", "ch1", OriginateParams{Endpoint: "PJSIP/1002"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target.ID != "id1" {
+		t.Errorf("Unexpected target: %+v", target)
+	}
+	fake.checkRequests(t, []string{
+		"POST /ari/bridges/br1/removeChannel?channel=ch1",
+		"DELETE /ari/channels/ch1",
+		"POST /ari/bridges/br1/moh",
+		"POST /ari/channels?app=test&channelId=id1&endpoint=PJSIP%2F1002",
+		"POST /ari/bridges/br1/addChannel?channel=id1",
+		"DELETE /ari/bridges/br1/moh",
+	})
+}
+
+func TestBlindTransferBusy(t *testing.T) {
+	fake, ari := newTransferFake(t)
+	fake.busy = true
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := ari.BlindTransfer(ctx, "br1", "ch1", OriginateParams{Endpoint: "PJSIP/1002"}); !errors.Is(err, ErrChannelDestroyed) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", ErrChannelDestroyed, err)
+	}
+	fake.checkRequests(t, []string{
+		"POST /ari/bridges/br1/removeChannel?channel=ch1",
+		"DELETE /ari/channels/ch1",
+		"POST /ari/bridges/br1/moh",
+		"POST /ari/channels?app=test&channelId=id1&endpoint=PJSIP%2F1002",
+		"DELETE /ari/bridges/br1/moh",
+	})
+}
+
+func TestBlindTransferTransfereeHangup(t *testing.T) {
+	fake, ari := newTransferFake(t)
+	fake.handleREST("/ari/channels", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte(`{"id":"id1"}`)) // target keeps ringing while the transferee hangs up
+		fake.send(`{"type":"ChannelLeftBridge","bridge":{"id":"br1","channels":[]},"channel":{"id":"ch2"}}`)
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := ari.BlindTransfer(ctx, "br1", "ch1", OriginateParams{Endpoint: "PJSIP/1002"}); err != ErrTransfereeHangup {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", ErrTransfereeHangup, err)
+	}
+	fake.checkRequests(t, []string{
+		"POST /ari/bridges/br1/removeChannel?channel=ch1",
+		"DELETE /ari/channels/ch1",
+		"POST /ari/bridges/br1/moh",
+		"POST /ari/channels?app=test&channelId=id1&endpoint=PJSIP%2F1002",
+		"DELETE /ari/channels/id1",
+		"DELETE /ari/bridges/br1/moh",
+	})
+}
+
+func TestBlindTransferMissingApplication(t *testing.T) {
+	ari := NewRESTARInGO("http://127.0.0.1:8088/ari", "", "", "")
+	if _, err := ari.BlindTransfer(context.Background(), "br1", "ch1", OriginateParams{Endpoint: "PJSIP/1002"}); err != ErrMissingApplication {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", ErrMissingApplication, err)
+	}
+}
+
+// attendedSetup are the requests starting an attended transfer of ch1 out of br1 towards id1
+var attendedSetup = []string{
+	"POST /ari/bridges/br1/removeChannel?channel=ch1",
+	"POST /ari/bridges/br1/moh",
+	"POST /ari/bridges/id2?type=mixing",
+	"POST /ari/bridges/id2/addChannel?channel=ch1",
+	"POST /ari/channels?app=test&channelId=id1&endpoint=PJSIP%2F1002",
+	"POST /ari/bridges/id2/addChannel?channel=id1",
+}
+
+func startAttended(t *testing.T, ari *ARInGO) (at *AttendedTransfer) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	var err error
+	if at, err = ari.StartAttendedTransfer(ctx, "br1", "ch1", OriginateParams{Endpoint: "PJSIP/1002"}); err != nil {
+		t.Fatal(err)
+	}
+	if at.Target.ID != "id1" || at.ConsultBridgeID != "id2" {
+		t.Errorf("Unexpected transfer: %+v", at)
+	}
+	return
+}
+
+func TestAttendedTransferComplete(t *testing.T) {
+	fake, ari := newTransferFake(t)
+	at := startAttended(t, ari)
+	if err := at.Complete(); err != nil {
+		t.Error(err)
+	}
+	waitTransfer(t, at)
+	if err := at.Err(); err != nil {
+		t.Error(err)
+	}
+	if err := at.Cancel(); err != ErrTransferFinished {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", ErrTransferFinished, err)
+	}
+	fake.checkRequests(t, append(attendedSetup,
+		"DELETE /ari/bridges/id2",
+		"POST /ari/bridges/br1/addChannel?channel=id1",
+		"DELETE /ari/bridges/br1/moh",
+		"DELETE /ari/channels/ch1",
+	))
+}
+
+func TestAttendedTransferCancel(t *testing.T) {
+	fake, ari := newTransferFake(t)
+	at := startAttended(t, ari)
+	if err := at.Cancel(); err != nil {
+		t.Error(err)
+	}
+	if err := at.Err(); err != ErrTransferCancelled {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", ErrTransferCancelled, err)
+	}
+	fake.checkRequests(t, append(attendedSetup,
+		"DELETE /ari/channels/id1",
+		"DELETE /ari/bridges/id2",
+		"POST /ari/bridges/br1/addChannel?channel=ch1",
+		"DELETE /ari/bridges/br1/moh",
+	))
+}
+
+func TestAttendedTransferHangups(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		event string
+		err   error
+		exp   []string
+	}{
+		{
+			name:  "transferer",
+			event: `{"type":"StasisEnd","channel":{"id":"ch1"}}`,
+			exp: []string{
+				"DELETE /ari/bridges/id2",
+				"POST /ari/bridges/br1/addChannel?channel=id1",
+				"DELETE /ari/bridges/br1/moh",
+				"DELETE /ari/channels/ch1",
+			},
+		},
+		{
+			name:  "target",
+			event: `{"type":"ChannelDestroyed","channel":{"id":"id1"}}`,
+			err:   ErrTransferTargetHangup,
+			exp: []string{
+				"DELETE /ari/channels/id1",
+				"DELETE /ari/bridges/id2",
+				"POST /ari/bridges/br1/addChannel?channel=ch1",
+				"DELETE /ari/bridges/br1/moh",
+			},
+		},
+		{
+			name:  "transferee",
+			event: `{"type":"ChannelLeftBridge","bridge":{"id":"br1","channels":[]},"channel":{"id":"ch2"}}`,
+			err:   ErrTransfereeHangup,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fake, ari := newTransferFake(t)
+			at := startAttended(t, ari)
+			fake.send(tc.event)
+			waitTransfer(t, at)
+			if err := at.Err(); err != tc.err {
+				t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", tc.err, err)
+			}
+			fake.checkRequests(t, append(append([]string(nil), attendedSetup...), tc.exp...))
+		})
+	}
+}
+
+func TestAttendedTransferBusy(t *testing.T) {
+	fake, ari := newTransferFake(t)
+	fake.busy = true
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := ari.StartAttendedTransfer(ctx, "br1", "ch1", OriginateParams{Endpoint: "PJSIP/1002"}); err != ErrTransferTargetHangup &&
+		!errors.Is(err, ErrChannelDestroyed) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	fake.checkRequests(t, append(append([]string(nil), attendedSetup[:5]...),
+		"DELETE /ari/channels/id1",
+		"DELETE /ari/bridges/id2",
+		"POST /ari/bridges/br1/addChannel?channel=ch1",
+		"DELETE /ari/bridges/br1/moh",
+	))
+}
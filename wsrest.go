@@ -0,0 +1,226 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package aringo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	EventTypeRESTRequest  = "RESTRequest"  // REST request sent over the websocket
+	EventTypeRESTResponse = "RESTResponse" // reply of Asterisk to a RESTRequest
+
+	defaultWSRESTProbeTimeout = 2 * time.Second
+)
+
+// states of the REST over websocket support
+const (
+	wsRESTUnknown int32 = iota // no reply received yet
+	wsRESTSupported
+	wsRESTUnsupported // Asterisk did not reply, HTTP is used instead
+)
+
+// errRESTOverWSUnavailable signals the request should be sent over HTTP instead
+var errRESTOverWSUnavailable = errors.New("REST_OVER_WS_UNAVAILABLE")
+
+// ErrRESTOverWSNoReply is returned for the requests not safe to be sent twice, which got no reply within the probe timeout
+// Asterisk may still execute them, the following requests are sent over HTTP
+var ErrRESTOverWSNoReply = errors.New("REST_OVER_WS_NO_REPLY")
+
+// QueryString is one parameter of a REST request sent over the websocket
+type QueryString struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// RESTRequest is one REST request sent over the ARI websocket
+type RESTRequest struct {
+	Type          string        `json:"type"`
+	TransactionID string        `json:"transaction_id"`
+	RequestID     string        `json:"request_id"`
+	Method        string        `json:"method"`
+	URI           string        `json:"uri"` // relative to the REST base URL, ie: channels/1234.5/answer
+	QueryStrings  []QueryString `json:"query_strings,omitempty"`
+	ContentType   string        `json:"content_type,omitempty"`
+	MessageBody   string        `json:"message_body,omitempty"`
+}
+
+// RESTResponse is the reply of Asterisk to a RESTRequest, correlated via RequestID
+type RESTResponse struct {
+	Type          string `json:"type"`
+	TransactionID string `json:"transaction_id"`
+	RequestID     string `json:"request_id"`
+	StatusCode    int    `json:"status_code"`
+	ReasonPhrase  string `json:"reason_phrase"`
+	URI           string `json:"uri"`
+	ContentType   string `json:"content_type,omitempty"`
+	MessageBody   string `json:"message_body,omitempty"`
+}
+
+// WithRESTOverWebsocket sends the REST requests over the ARI websocket instead of HTTP, keeping them ordered with the events
+// If Asterisk does not reply to the first request within probeTimeout (0 for the default of 2s) it is considered
+// as not supporting the transport and HTTP is used from there on, until the next reconnect
+func WithRESTOverWebsocket(probeTimeout time.Duration) Option {
+	return func(ari *ARInGO) {
+		ari.wsREST = newWSRESTTransport(probeTimeout)
+	}
+}
+
+// wsRESTTransport correlates the REST requests sent over the websocket with their replies
+type wsRESTTransport struct {
+	probeTimeout time.Duration
+	state        atomic.Int32
+	mux          sync.Mutex // protects pending and lastID
+	pending      map[string]chan *RESTResponse
+	lastID       uint64
+}
+
+func newWSRESTTransport(probeTimeout time.Duration) *wsRESTTransport {
+	if probeTimeout == 0 {
+		probeTimeout = defaultWSRESTProbeTimeout
+	}
+	return &wsRESTTransport{
+		probeTimeout: probeTimeout,
+		pending:      make(map[string]chan *RESTResponse),
+	}
+}
+
+// register returns a new request id together with the channel receiving its reply
+func (t *wsRESTTransport) register() (id string, respChan chan *RESTResponse) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	t.lastID++
+	id = strconv.FormatUint(t.lastID, 10)
+	respChan = make(chan *RESTResponse, 1)
+	t.pending[id] = respChan
+	return
+}
+
+func (t *wsRESTTransport) unregister(id string) {
+	t.mux.Lock()
+	delete(t.pending, id)
+	t.mux.Unlock()
+}
+
+// deliver passes the reply to the request waiting for it
+func (t *wsRESTTransport) deliver(ev *Event) {
+	var resp RESTResponse
+	if ev.Decode(&resp) != nil {
+		return
+	}
+	t.state.Store(wsRESTSupported)
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	if respChan, has := t.pending[resp.RequestID]; has { // replies for requests given up on are dropped
+		respChan <- &resp
+		delete(t.pending, resp.RequestID)
+	}
+}
+
+// reset fails the pending requests once the websocket is lost, the next connection gets probed again
+func (t *wsRESTTransport) reset() {
+	t.mux.Lock()
+	for id, respChan := range t.pending {
+		close(respChan)
+		delete(t.pending, id)
+	}
+	t.mux.Unlock()
+	t.state.Store(wsRESTUnknown)
+}
+
+// wsRoundTrip sends the request over the websocket and waits for its reply
+// Returns errRESTOverWSUnavailable if the request should be sent over HTTP instead
+// Once sent, only the idempotent requests fall back to HTTP since Asterisk may only be slow to reply
+func (ari *ARInGO) wsRoundTrip(ctx context.Context, method string, u *url.URL, data url.Values,
+	jsonBody []byte, idempotent bool) (statusCode int, reply []byte, err error) {
+	t := ari.wsREST
+	if t.state.Load() == wsRESTUnsupported {
+		return 0, nil, errRESTOverWSUnavailable
+	}
+	id, respChan := t.register()
+	defer t.unregister(id)
+	req := &RESTRequest{
+		Type:          EventTypeRESTRequest,
+		TransactionID: id,
+		RequestID:     id,
+		Method:        method,
+		URI:           restURI(ari.restURL, u),
+		QueryStrings:  queryStrings(u.Query(), data),
+	}
+	if jsonBody != nil {
+		req.ContentType = contentTypeJSON
+		req.MessageBody = string(jsonBody)
+	}
+	if err = ari.sendFrame(req); err != nil {
+		ari.log().Warn("ARI REST over websocket failed, using HTTP", "error", err)
+		return 0, nil, errRESTOverWSUnavailable
+	}
+	var probe <-chan time.Time
+	if t.state.Load() == wsRESTUnknown {
+		timer := time.NewTimer(t.probeTimeout)
+		defer timer.Stop()
+		probe = timer.C
+	}
+	for {
+		select {
+		case resp, ok := <-respChan:
+			if !ok {
+				return 0, nil, &url.Error{Op: method, URL: u.String(), Err: io.ErrUnexpectedEOF}
+			}
+			return resp.StatusCode, []byte(resp.MessageBody), nil
+		case <-probe:
+			if t.state.CompareAndSwap(wsRESTUnknown, wsRESTUnsupported) {
+				ari.log().Warn("ARI REST over websocket not supported, falling back to HTTP", "url", redactURL(ari.wsURL))
+			}
+			if t.state.Load() == wsRESTUnsupported {
+				if !idempotent {
+					return 0, nil, &url.Error{Op: method, URL: u.String(), Err: ErrRESTOverWSNoReply}
+				}
+				return 0, nil, errRESTOverWSUnavailable
+			}
+			probe = nil // supported after all, wait for the reply
+		case <-ctx.Done():
+			return 0, nil, ctx.Err()
+		}
+	}
+}
+
+// restURI returns the path of the request relative to the REST base URL
+func restURI(restURL string, u *url.URL) string {
+	path := u.EscapedPath()
+	if base, err := url.Parse(restURL); err == nil {
+		path = strings.TrimPrefix(path, strings.TrimSuffix(base.EscapedPath(), "/"))
+	}
+	return strings.Trim(path, "/")
+}
+
+// queryStrings lists the parameters of the request out of the URL query and the form data
+func queryStrings(sets ...url.Values) (qs []QueryString) {
+	for _, vals := range sets {
+		keys := make([]string, 0, len(vals))
+		for key := range vals {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			for _, val := range vals[key] {
+				qs = append(qs, QueryString{Name: key, Value: val})
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,225 @@
+/*
+Released under MIT License <http://www.opensource.org/licenses/mit-license.php
+Copyright (C) ITsysCOM GmbH. All Rights Reserved.
+
+Provides Asterisk ARI connector from Go programming language.
+*/
+
+package aringo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// newWSRESTServer starts an ARI server passing the requests received over the websocket to onRequest
+// httpCalls counts the requests received over HTTP
+func newWSRESTServer(t *testing.T, onRequest func(c *websocket.Conn, req *RESTRequest), opts ...Option) (ari *ARInGO, httpCalls *atomic.Int32) {
+	httpCalls = new(atomic.Int32)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ari/", func(rw http.ResponseWriter, r *http.Request) {
+		httpCalls.Add(1)
+		rw.Write([]byte(`[{"id":"http"}]`))
+	})
+	mux.Handle("/ari/events", websocket.Handler(func(c *websocket.Conn) {
+		for {
+			var req RESTRequest
+			if err := websocket.JSON.Receive(c, &req); err != nil {
+				return
+			}
+			onRequest(c, &req)
+		}
+	}))
+	srv := httptest.NewServer(mux)
+	stopChan := make(chan struct{})
+	t.Cleanup(func() {
+		close(stopChan)
+		srv.Close()
+	})
+	var err error
+	if ari, err = NewARInGO("ws"+strings.TrimPrefix(srv.URL, "http")+"/ari/events?app=test", srv.URL,
+		"", "", "", nil, make(chan error, 1), stopChan, 1, 0, 0, fibDuration, opts...); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func TestRESTOverWebsocket(t *testing.T) {
+	out := new(syncBuffer)
+	rec := NewRecorder(out, true)
+	received := make(chan *RESTRequest, 10)
+	ari, httpCalls := newWSRESTServer(t, func(c *websocket.Conn, req *RESTRequest) {
+		received <- req
+		resp := RESTResponse{
+			Type:          EventTypeRESTResponse,
+			TransactionID: req.TransactionID,
+			RequestID:     req.RequestID,
+			URI:           req.URI,
+			StatusCode:    200,
+			ReasonPhrase:  "OK",
+			ContentType:   "application/json",
+		}
+		switch req.URI {
+		case "channels":
+			c.Write([]byte(`{"type":"StasisStart","channel":{"id":"ch1"}}`)) // events keep their order with the replies
+			resp.MessageBody = `[{"id":"ch1"}]`
+		case "channels/ch1":
+			resp.StatusCode = 204
+			resp.ReasonPhrase = "No Content"
+		default:
+			resp.StatusCode = 404
+			resp.ReasonPhrase = "Not Found"
+			resp.MessageBody = `{"message":"Channel not found"}`
+		}
+		websocket.JSON.Send(c, resp)
+	}, WithRESTOverWebsocket(time.Second), WithRecorder(rec))
+	sub := ari.Subscribe(TypeFilter("StasisStart"))
+	defer sub.Close()
+
+	chs, err := ari.ListChannels()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(chs) != 1 || chs[0].ID != "ch1" {
+		t.Errorf("Unexpected channels: %+v", chs)
+	}
+	select {
+	case <-sub.Events(): // received before the reply
+	default:
+		t.Error("Event not received before the reply")
+	}
+	if err = ari.Hangup("ch1", "busy"); err != nil {
+		t.Error(err)
+	}
+	var rcErr *UnexpectedReplyCodeError
+	if _, err = ari.GetChannel("missing"); !errors.As(err, &rcErr) {
+		t.Errorf("Unexpected error: %v", err)
+	} else if rcErr.StatusCode != 404 || rcErr.Message != "Channel not found" {
+		t.Errorf("Unexpected error: %+v", rcErr)
+	}
+	if httpCalls.Load() != 0 {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", 0, httpCalls.Load())
+	}
+	// the replies are recorded once, by the requests
+	if strings.Contains(out.String(), EventTypeRESTResponse) {
+		t.Errorf("Replies recorded as frames: %s", out.String())
+	}
+	if n := strings.Count(out.String(), `"kind":"`+RecordKindREST+`"`); n != 3 {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", 3, n)
+	}
+
+	exp := []*RESTRequest{
+		{Type: EventTypeRESTRequest, TransactionID: "1", RequestID: "1", Method: HTTP_GET, URI: "channels"},
+		{Type: EventTypeRESTRequest, TransactionID: "2", RequestID: "2", Method: HTTP_DELETE, URI: "channels/ch1",
+			QueryStrings: []QueryString{{Name: "reason", Value: "busy"}}},
+		{Type: EventTypeRESTRequest, TransactionID: "3", RequestID: "3", Method: HTTP_GET, URI: "channels/missing"},
+	}
+	for _, expReq := range exp {
+		if req := <-received; !reflect.DeepEqual(expReq, req) {
+			t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", expReq, req)
+		}
+	}
+}
+
+func TestRESTOverWebsocketFallback(t *testing.T) {
+	var wsCalls atomic.Int32
+	ari, httpCalls := newWSRESTServer(t, func(c *websocket.Conn, req *RESTRequest) {
+		wsCalls.Add(1) // older Asterisk ignores the requests
+	}, WithRESTOverWebsocket(20*time.Millisecond))
+	for i := 0; i < 2; i++ {
+		if chs, err := ari.ListChannels(); err != nil {
+			t.Fatal(err)
+		} else if len(chs) != 1 || chs[0].ID != "http" {
+			t.Errorf("Unexpected channels: %+v", chs)
+		}
+	}
+	if wsCalls.Load() != 1 || httpCalls.Load() != 2 {
+		t.Errorf("Unexpected calls, websocket: %d, http: %d", wsCalls.Load(), httpCalls.Load())
+	}
+}
+
+func TestRESTOverWebsocketNoReply(t *testing.T) {
+	var wsCalls atomic.Int32
+	ari, httpCalls := newWSRESTServer(t, func(c *websocket.Conn, req *RESTRequest) {
+		wsCalls.Add(1) // slow Asterisk, the request may still run
+	}, WithRESTOverWebsocket(20*time.Millisecond))
+	if err := ari.Answer("ch1"); !errors.Is(err, ErrRESTOverWSNoReply) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", ErrRESTOverWSNoReply, err)
+	}
+	if wsCalls.Load() != 1 || httpCalls.Load() != 0 {
+		t.Errorf("Unexpected calls, websocket: %d, http: %d", wsCalls.Load(), httpCalls.Load())
+	}
+	if err := ari.Answer("ch1"); err != nil { // over HTTP from now on
+		t.Error(err)
+	}
+	if wsCalls.Load() != 1 || httpCalls.Load() != 1 {
+		t.Errorf("Unexpected calls, websocket: %d, http: %d", wsCalls.Load(), httpCalls.Load())
+	}
+}
+
+func TestRESTOverWebsocketConnectionLost(t *testing.T) {
+	ari, _ := newWSRESTServer(t, func(c *websocket.Conn, req *RESTRequest) {
+		c.Close()
+	}, WithRESTOverWebsocket(time.Second))
+	if _, err := ari.ListChannels(); !errors.Is(err, io.ErrUnexpectedEOF) || !isRetriable(err) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestServerRESTOverWebsocket(t *testing.T) {
+	conns := make(chan *ARInGO, 1)
+	srv := httptest.NewServer(NewServer(func(*http.Request) bool { return true },
+		func(ari *ARInGO) { conns <- ari }))
+	defer srv.Close()
+	ast, err := dialServer(srv.URL, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ast.Close()
+	go func() {
+		var req RESTRequest
+		if websocket.JSON.Receive(ast, &req) == nil {
+			websocket.JSON.Send(ast, RESTResponse{Type: EventTypeRESTResponse, RequestID: req.RequestID,
+				StatusCode: 200, MessageBody: `{"system":{"version":"22.0.0"}}`})
+		}
+	}()
+	info, err := (<-conns).AsteriskInfo()
+	if err != nil {
+		t.Fatal(err)
+	} else if info.System == nil || info.System.Version != "22.0.0" {
+		t.Errorf("Unexpected info: %+v", info)
+	}
+}
+
+func TestRESTOverWebsocketJSONBody(t *testing.T) {
+	received := make(chan *RESTRequest, 1)
+	ari, _ := newWSRESTServer(t, func(c *websocket.Conn, req *RESTRequest) {
+		received <- req
+		websocket.JSON.Send(c, RESTResponse{Type: EventTypeRESTResponse, TransactionID: req.TransactionID,
+			RequestID: req.RequestID, URI: req.URI, StatusCode: 204, ReasonPhrase: "No Content"})
+	}, WithRESTOverWebsocket(time.Second))
+	if _, err := ari.CallContext(context.Background(), HTTP_POST, ari.resourceURL("channels"),
+		url.Values{"endpoint": {"PJSIP/1001"}}, WithJSONBody(map[string]ChannelVariables{
+			"variables": ChannelVariables{}.AddSIPHeader("X-Account", "1001"),
+		})); err != nil {
+		t.Fatal(err)
+	}
+	exp := &RESTRequest{Type: EventTypeRESTRequest, TransactionID: "1", RequestID: "1", Method: HTTP_POST, URI: "channels",
+		QueryStrings: []QueryString{{Name: "endpoint", Value: "PJSIP/1001"}},
+		ContentType:  "application/json",
+		MessageBody:  `{"variables":{"PJSIP_HEADER(add,X-Account)":"1001"}}`,
+	}
+	if req := <-received; !reflect.DeepEqual(exp, req) {
+		t.Errorf("\nExpected: <%+v>, \nReceived: <%+v>", exp, req)
+	}
+}